@@ -1,46 +1,119 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"embed"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
 
 	"github.com/dmehra2102/order-management-platform/internal/config"
+	"github.com/dmehra2102/order-management-platform/internal/migrate"
+	"github.com/dmehra2102/order-management-platform/migrations"
+	_ "github.com/lib/pq"
 )
 
-var migrationFiles embed.FS
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: migrations [--config FILE] <command> [--dry-run] [N]
+
+Commands:
+  status    List migrations and whether they are applied
+  up [N]    Apply the next N pending migrations, all when N is omitted
+  down [N]  Revert the last N applied migrations, 1 when N is omitted
+
+--dry-run prints the migrations up or down would run without running them.
+It may come before or after the command, but before N.
+`)
+}
 
 func main() {
-	cfg := config.Load()
+	dryRun := flag.Bool("dry-run", false, "print the migrations that would run without running them")
+	flag.Usage = usage
+	cfg := config.MustLoad()
+
+	command, args := "up", []string(nil)
+	if flag.NArg() > 0 {
+		command, args = flag.Arg(0), flag.Args()[1:]
+	}
+
+	// Flags after the command name are parsed on their own, so that
+	// "up --dry-run" is not read as a migration count
+	commandFlags := flag.NewFlagSet(command, flag.ExitOnError)
+	commandFlags.BoolVar(dryRun, "dry-run", *dryRun, "print the migrations that would run without running them")
+	commandFlags.Usage = usage
+	_ = commandFlags.Parse(args)
+
+	n := 0
+	if command == "down" {
+		n = 1
+	}
+	if commandFlags.NArg() > 0 {
+		var err error
+		if n, err = strconv.Atoi(commandFlags.Arg(0)); err != nil || n <= 0 {
+			log.Fatalf("Invalid migration count %q", commandFlags.Arg(0))
+		}
+	}
+	if commandFlags.NArg() > 1 || (command != "status" && command != "up" && command != "down") {
+		usage()
+		os.Exit(2)
+	}
+
+	all, err := migrate.Load(migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
 
 	db, err := sql.Open("postgres", cfg.DatabaseURL())
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
 	defer db.Close()
+	cfg.Database.ConfigurePool(db)
 
 	if err := db.Ping(); err != nil {
 		log.Fatalf("Failed to ping: %v", err)
 	}
 
-	files, err := migrationFiles.ReadDir("../migrations")
-	if err != nil {
-		log.Fatalf("Unable to read migration folder: %v", err)
+	migrator := migrate.NewMigrator(db, all)
+	migrator.DryRun = *dryRun
+	migrator.Logf = func(format string, args ...any) {
+		fmt.Printf(format+"\n", args...)
+	}
+
+	ctx := context.Background()
+	suffix := ""
+	if *dryRun {
+		suffix = " (dry run)"
 	}
 
-	for _, f := range files {
-		sqlBytes, err := migrationFiles.ReadFile("../migrations/" + f.Name())
+	switch command {
+	case "status":
+		statuses, err := migrator.Status(ctx)
 		if err != nil {
-			log.Fatalf("Failed reading migration file %s: %v", f.Name(), err)
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d_%-32s %s\n", s.Version, s.Name, applied)
 		}
 
-		fmt.Printf("-> Running migration: %s\n", f.Name())
+	case "up":
+		done, err := migrator.Up(ctx, n)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		fmt.Printf("✓ %d migration(s) applied%s\n", len(done), suffix)
 
-		if _, err := db.Exec(string(sqlBytes)); err != nil {
-			log.Fatalf("Migration %s failed: %v", f.Name(), err)
+	case "down":
+		done, err := migrator.Down(ctx, n)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
 		}
+		fmt.Printf("✓ %d migration(s) reverted%s\n", len(done), suffix)
 	}
-
-	fmt.Println("✓ All migrations executed")
 }
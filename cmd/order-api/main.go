@@ -3,71 +3,50 @@ package main
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
-	"time"
 
+	"github.com/dmehra2102/order-management-platform/internal/api"
 	"github.com/dmehra2102/order-management-platform/internal/config"
-	"github.com/dmehra2102/order-management-platform/internal/domain"
 	"github.com/dmehra2102/order-management-platform/internal/kafka"
 	"github.com/dmehra2102/order-management-platform/internal/logger"
 	"github.com/dmehra2102/order-management-platform/internal/metrics"
+	"github.com/dmehra2102/order-management-platform/internal/outbox"
 	"github.com/dmehra2102/order-management-platform/internal/repository"
 	"github.com/dmehra2102/order-management-platform/internal/service"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+	_ "github.com/lib/pq"
 )
 
-type CreateOrderRequest struct {
-	UserID       string                   `json:"user_id"`
-	RestaurantID string                   `json:"restaurant_id"`
-	Items        []CreateOrderItemRequest `json:"items"`
-}
-
-type CreateOrderItemRequest struct {
-	ItemID   string  `json:"item_id"`
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Quantity int     `json:"quantity"`
-}
-
-type OrderResponse struct {
-	ID           string             `json:"id"`
-	UserID       string             `json:"user_id"`
-	RestaurantID string             `json:"restaurant_id"`
-	Items        []domain.OrderItem `json:"items"`
-	TotalAmount  float64            `json:"total_amount"`
-	Status       string             `json:"status"`
-	CreatedAt    time.Time          `json:"created_at"`
-	UpdatedAt    time.Time          `json:"updated_at"`
-}
-
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-}
-
-type Server struct {
-	mux     *http.ServeMux
-	db      *sql.DB
-	service *service.OrderService
-	logger  *logger.Logger
-	metrics *metrics.Metrics
-}
-
 func main() {
-	cfg := config.Load()
+	cfg := config.MustLoad()
 	l := logger.New(cfg.LogLevel)
 
 	l.Info("Starting Order API Service", map[string]any{
 		"environment": cfg.Environment,
-		"port":        cfg.HTTPPort,
+		"port":        cfg.HTTP.Port,
 	})
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, "order-api")
+	if err != nil {
+		l.Error("Failed to set up tracing", map[string]any{
+			"error": err,
+		})
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			l.Warn("Failed to flush traces", map[string]any{
+				"error": err,
+			})
+		}
+	}()
+
 	// Connect to Database
 	db, err := sql.Open("postgres", cfg.DatabaseURL())
 	if err != nil {
@@ -77,9 +56,10 @@ func main() {
 		os.Exit(1)
 	}
 	defer db.Close()
+	cfg.Database.ConfigurePool(db)
 
 	// Verify DB Connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Database.ConnectTimeout)
 	defer cancel()
 	if err := db.PingContext(ctx); err != nil {
 		l.Error("Failed to pind database", map[string]any{
@@ -90,11 +70,36 @@ func main() {
 
 	l.Info("Database connection successful", nil)
 
-	producer := kafka.NewProducer(cfg.KafkaBrokers, l)
+	serializer, _, err := cfg.Kafka.Serializers()
+	if err != nil {
+		l.Error("Failed to open schema registry", map[string]any{
+			"error": err,
+		})
+		os.Exit(1)
+	}
+
+	producer := kafka.NewProducer(cfg.Kafka.Broker(), l)
+	producer.Topics = cfg.Kafka.Topics
+	producer.Source = "order-api"
+	producer.Encoding = cfg.Kafka.Encoding
+	producer.Serializer = serializer
 	defer producer.Close()
 
 	orderRepo := repository.NewOrderRepository(db)
-	orderService := service.NewOrderService(orderRepo, producer, l)
+	orderService := service.NewOrderService(orderRepo, l)
+
+	// Outbox relay publishes events committed together with their orders
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+
+	relay := outbox.NewRelay(orderRepo, producer, l, cfg.Outbox.PollInterval, cfg.Outbox.BatchSize)
+	relayDone := make(chan error, 1)
+	go func() {
+		relayDone <- relay.Start(relayCtx)
+	}()
+
+	// Expired idempotency keys are purged by every replica; the DELETE is idempotent
+	go api.PurgeIdempotencyKeys(relayCtx, orderService, l, cfg.Idempotency.PurgeInterval)
 
 	// Prometheus Metrics
 	m := metrics.New()
@@ -104,24 +109,21 @@ func main() {
 		})
 	}
 
-	// Creating Server
-	server := &Server{
-		mux:     http.NewServeMux(),
-		db:      db,
-		service: orderService,
-		logger:  l,
-		metrics: m,
-	}
+	go api.TrackOpenOrders(relayCtx, orderService, m, l, cfg.Metrics.OpenOrdersInterval)
 
-	server.registerRoutes()
+	// Creating Server
+	webhookService := service.NewWebhookService(orderRepo, l)
+	webhookService.AllowHTTP = cfg.Development()
+	server := api.NewServer(orderService, webhookService, m, l, cfg.Idempotency.KeyTTL)
+	server.GatewayToken = cfg.HTTP.GatewayToken
 
 	// HTTP server
 	httpServer := &http.Server{
-		Addr:         fmt.Sprintf("%s:%s", cfg.HTTPHost, cfg.HTTPPort),
-		Handler:      loggingMiddleware(server.mux, l),
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:         fmt.Sprintf("%s:%s", cfg.HTTP.Host, cfg.HTTP.Port),
+		Handler:      server.Handler(),
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
 	}
 
 	// starting http server in background
@@ -143,7 +145,7 @@ func main() {
 	<-sigChan
 	l.Info("Shutting down HTTP Server", nil)
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
 	defer shutdownCancel()
 
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
@@ -152,150 +154,8 @@ func main() {
 		})
 	}
 
-	l.Info("HTTP Server shutdown complete", nil)
-}
-
-func (s *Server) registerRoutes() {
-	s.mux.HandleFunc("POST /api/v1/orders", s.createOrder)
-	s.mux.HandleFunc("GET /api/v1/orders/", s.handleGetOrder)
-	s.mux.HandleFunc("GET /api/v1/orders", s.listOrders)
-	s.mux.Handle("/metrics", promhttp.Handler())
-	s.mux.HandleFunc("GET /health", s.healthCheck)
-}
-
-func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
-	var req CreateOrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.respondError(w, http.StatusBadRequest, "Invalid request body", err.Error())
-		return
-	}
-
-	var items []domain.OrderItem
-	for _, item := range req.Items {
-		items = append(items, domain.OrderItem{
-			ID:       item.ItemID,
-			Name:     item.Name,
-			Price:    item.Price,
-			Quantity: item.Quantity,
-		})
-	}
-
-	order, err := s.service.CreateOrder(r.Context(), req.UserID, req.RestaurantID, items)
-	if err != nil {
-		s.metrics.OrdersFailed.Inc()
-		s.respondError(w, http.StatusBadRequest, "Failed to create order", err.Error())
-		return
-	}
-
-	s.metrics.OrdersCreated.Inc()
+	stopRelay()
+	<-relayDone
 
-	resp := OrderResponse{
-		ID:           order.ID,
-		UserID:       order.UserID,
-		RestaurantID: order.RestaurantID,
-		Items:        order.Items,
-		TotalAmount:  order.TotalAmount,
-		Status:       string(order.Status),
-		CreatedAt:    order.CreatedAt,
-		UpdatedAt:    order.UpdatedAt,
-	}
-
-	s.respondJSON(w, http.StatusCreated, resp)
-}
-
-func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
-	// Extract order ID from /api/v1/orders/{id}
-	path := strings.TrimPrefix(r.URL.Path, "/api/v1/orders/")
-	if path == "" {
-		s.respondError(w, http.StatusBadRequest, "Missing order ID", "")
-		return
-	}
-
-	order, err := s.service.GetOrder(r.Context(), path)
-	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			s.respondError(w, http.StatusNotFound, "Order not found", err.Error())
-		} else {
-			s.respondError(w, http.StatusInternalServerError, "Failed to fetch order", err.Error())
-		}
-		return
-	}
-
-	resp := OrderResponse{
-		ID:           order.ID,
-		UserID:       order.UserID,
-		RestaurantID: order.RestaurantID,
-		Items:        order.Items,
-		TotalAmount:  order.TotalAmount,
-		Status:       string(order.Status),
-		CreatedAt:    order.CreatedAt,
-		UpdatedAt:    order.UpdatedAt,
-	}
-
-	s.respondJSON(w, http.StatusOK, resp)
-}
-
-func (s *Server) listOrders(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		s.respondError(w, http.StatusBadRequest, "Missing user_id query parameter", "")
-		return
-	}
-
-	orders, err := s.service.ListOrders(r.Context(), userID, 50)
-	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "Failed to list orders", err.Error())
-		return
-	}
-
-	var responses []OrderResponse
-	for _, order := range orders {
-		responses = append(responses, OrderResponse{
-			ID:           order.ID,
-			UserID:       order.UserID,
-			RestaurantID: order.RestaurantID,
-			Items:        order.Items,
-			TotalAmount:  order.TotalAmount,
-			Status:       string(order.Status),
-			CreatedAt:    order.CreatedAt,
-			UpdatedAt:    order.UpdatedAt,
-		})
-	}
-
-	s.respondJSON(w, http.StatusOK, responses)
-}
-
-func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
-	s.respondJSON(w, http.StatusOK, map[string]any{
-		"status": "healthy",
-	})
-}
-
-func (s *Server) respondJSON(w http.ResponseWriter, code int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(data)
-}
-
-func (s *Server) respondError(w http.ResponseWriter, code int, message, detail string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	resp := ErrorResponse{
-		Error:   message,
-		Message: detail,
-	}
-	json.NewEncoder(w).Encode(resp)
-}
-
-func loggingMiddleware(next http.Handler, l *logger.Logger) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		duration := time.Since(start)
-		l.Debug("HTTP Request", map[string]any{
-			"method":   r.Method,
-			"path":     r.URL.Path,
-			"duration": duration.Milliseconds(),
-		})
-	})
+	l.Info("HTTP Server shutdown complete", nil)
 }
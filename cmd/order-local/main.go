@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dmehra2102/order-management-platform/internal/api"
+	"github.com/dmehra2102/order-management-platform/internal/config"
+	"github.com/dmehra2102/order-management-platform/internal/kafka"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/metrics"
+	"github.com/dmehra2102/order-management-platform/internal/outbox"
+	"github.com/dmehra2102/order-management-platform/internal/reactions"
+	"github.com/dmehra2102/order-management-platform/internal/repository/memstore"
+	"github.com/dmehra2102/order-management-platform/internal/service"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+	"github.com/dmehra2102/order-management-platform/internal/webhook"
+)
+
+// Partitions per topic of the in-memory broker
+const localPartitions = 3
+
+// pipeline is order-api and every order-processor mode wired to one
+// in-memory store and broker, so orders go from creation to confirmation
+// without Postgres or Kafka.
+type pipeline struct {
+	server   *api.Server
+	service  *service.OrderService
+	broker   *kafka.MemoryBroker
+	producer *kafka.Producer
+	consumer *kafka.Consumer
+	status   *kafka.Consumer
+	webhooks *kafka.Consumer
+	relay    *outbox.Relay
+	worker   *webhook.Worker
+}
+
+func newPipeline(cfg *config.Config, m *metrics.Metrics, l *logger.Logger) (*pipeline, error) {
+	store := memstore.New()
+	broker := kafka.NewMemoryBroker(localPartitions)
+
+	rulesConfig, err := cfg.Validation.LoadRules()
+	if err != nil {
+		return nil, fmt.Errorf("load validation rules: %w", err)
+	}
+	engine, err := rulesConfig.Build()
+	if err != nil {
+		return nil, fmt.Errorf("build validation rules: %w", err)
+	}
+
+	serializer, accepted, err := cfg.Kafka.Serializers()
+	if err != nil {
+		return nil, fmt.Errorf("open schema registry: %w", err)
+	}
+
+	producer := kafka.NewProducer(broker, l)
+	producer.Topics = cfg.Kafka.Topics
+	producer.Source = "order-local"
+	producer.Encoding = cfg.Kafka.Encoding
+	producer.Serializer = serializer
+
+	consumer := kafka.NewConsumer(broker, cfg.Kafka.ConsumerGroup, l, store, engine, producer)
+	consumer.Topics = cfg.Kafka.Topics
+	consumer.Retry = cfg.Kafka.Retry
+	consumer.Metrics = m
+
+	dispatcher := kafka.NewDispatcher()
+	reactions.Register(dispatcher, reactions.NewLogNotifier(l))
+	status := kafka.NewStatusConsumer(broker, cfg.Kafka.StatusConsumerGroup, l, store, producer, dispatcher)
+	status.Topics = cfg.Kafka.Topics
+	status.Retry = cfg.Kafka.Retry
+	status.Metrics = m
+
+	webhookDispatcher := kafka.NewDispatcher()
+	webhook.Register(webhookDispatcher, l)
+	webhooks := kafka.NewOrderEventsConsumer(broker, cfg.Kafka.WebhookConsumerGroup, l, store, producer, webhookDispatcher)
+	webhooks.Topics = cfg.Kafka.Topics
+	webhooks.Retry = cfg.Kafka.Retry
+	webhooks.Metrics = m
+	for _, s := range accepted {
+		consumer.Accept(s)
+		status.Accept(s)
+		webhooks.Accept(s)
+	}
+
+	worker := webhook.NewWorker(store, l, cfg.Webhooks.PollInterval, cfg.Webhooks.BatchSize, cfg.Webhooks.Delivery)
+	worker.Metrics = m
+	worker.AllowPrivateNetworks = cfg.Development()
+
+	orderService := service.NewOrderService(store, l)
+	webhookService := service.NewWebhookService(store, l)
+	webhookService.AllowHTTP = cfg.Development()
+	server := api.NewServer(orderService, webhookService, m, l, cfg.Idempotency.KeyTTL)
+	server.GatewayToken = cfg.HTTP.GatewayToken
+
+	return &pipeline{
+		server:   server,
+		service:  orderService,
+		broker:   broker,
+		producer: producer,
+		consumer: consumer,
+		status:   status,
+		webhooks: webhooks,
+		// One relay serves both halves, as they share the store
+		relay:  outbox.NewRelay(store, producer, l, cfg.Outbox.PollInterval, cfg.Outbox.BatchSize),
+		worker: worker,
+	}, nil
+}
+
+// run starts the relay, the consumers and the webhook worker and blocks until
+// ctx is cancelled and all have stopped.
+func (p *pipeline) run(ctx context.Context) {
+	relayDone := make(chan error, 1)
+	go func() {
+		relayDone <- p.relay.Start(ctx)
+	}()
+
+	consumerDone := make(chan error, 1)
+	go func() {
+		consumerDone <- p.consumer.Start(ctx)
+	}()
+
+	statusDone := make(chan error, 1)
+	go func() {
+		statusDone <- p.status.Start(ctx)
+	}()
+
+	webhooksDone := make(chan error, 1)
+	go func() {
+		webhooksDone <- p.webhooks.Start(ctx)
+	}()
+
+	workerDone := make(chan error, 1)
+	go func() {
+		workerDone <- p.worker.Start(ctx)
+	}()
+
+	<-workerDone
+	<-webhooksDone
+	<-statusDone
+	<-consumerDone
+	<-relayDone
+}
+
+func (p *pipeline) Close() error {
+	if err := p.webhooks.Close(); err != nil {
+		return err
+	}
+	if err := p.status.Close(); err != nil {
+		return err
+	}
+	if err := p.consumer.Close(); err != nil {
+		return err
+	}
+	return p.producer.Close()
+}
+
+// order-local runs the API and the order processor in one process on an
+// in-memory store and broker. Nothing survives a restart.
+func main() {
+	cfg := config.MustLoad()
+	l := logger.New(cfg.LogLevel)
+
+	l.Info("Starting Order Platform in local mode", map[string]any{
+		"environment": cfg.Environment,
+		"port":        cfg.HTTP.Port,
+	})
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, "order-local")
+	if err != nil {
+		l.Error("Failed to set up tracing", map[string]any{
+			"error": err,
+		})
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			l.Warn("Failed to flush traces", map[string]any{
+				"error": err,
+			})
+		}
+	}()
+
+	m := metrics.New()
+	if err := m.Register(); err != nil {
+		l.Warn("Failed to register metrics", map[string]any{
+			"error": err,
+		})
+	}
+
+	p, err := newPipeline(cfg, m, l)
+	if err != nil {
+		l.Error("Failed to set up local pipeline", map[string]any{
+			"error": err,
+		})
+		os.Exit(1)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pipelineDone := make(chan struct{})
+	go func() {
+		p.run(ctx)
+		close(pipelineDone)
+	}()
+
+	go api.TrackOpenOrders(ctx, p.service, m, l, cfg.Metrics.OpenOrdersInterval)
+
+	httpServer := &http.Server{
+		Addr:         fmt.Sprintf("%s:%s", cfg.HTTP.Host, cfg.HTTP.Port),
+		Handler:      p.server.Handler(),
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+	}
+
+	go func() {
+		l.Info("HTTP Server listening", map[string]any{
+			"addr": httpServer.Addr,
+		})
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			l.Error("HTTP Server error", map[string]any{
+				"error": err,
+			})
+		}
+	}()
+
+	// Shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	<-sigChan
+	l.Info("Shutting down", nil)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer shutdownCancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		l.Error("HTTP Server shutdown error", map[string]any{
+			"error": err,
+		})
+	}
+
+	cancel()
+	<-pipelineDone
+
+	l.Info("Shutdown complete", nil)
+}
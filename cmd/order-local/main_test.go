@@ -0,0 +1,532 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/api"
+	"github.com/dmehra2102/order-management-platform/internal/config"
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/kafka"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/metrics"
+	"github.com/dmehra2102/order-management-platform/internal/rules"
+	"github.com/dmehra2102/order-management-platform/internal/webhook"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func startPipeline(t *testing.T) (*pipeline, *httptest.Server) {
+	t.Helper()
+	return startPipelineWith(t, func(*config.Config) {})
+}
+
+// startPipelineWith starts a pipeline on the default configuration as changed
+// by configure.
+func startPipelineWith(t *testing.T, configure func(cfg *config.Config)) (*pipeline, *httptest.Server) {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Outbox.PollInterval = 20 * time.Millisecond
+	cfg.Webhooks.PollInterval = 20 * time.Millisecond
+	configure(cfg)
+
+	p, err := newPipeline(cfg, metrics.New(), logger.New("ERROR"))
+	if err != nil {
+		t.Fatalf("newPipeline: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.run(ctx)
+		close(done)
+	}()
+
+	srv := httptest.NewServer(p.server.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		cancel()
+		<-done
+		p.Close()
+	})
+	return p, srv
+}
+
+func createOrder(t *testing.T, srv *httptest.Server, amount string, header http.Header) api.OrderResponse {
+	t.Helper()
+
+	body := `{"user_id":"user-1","restaurant_id":"rest-1","items":[{"item_id":"item-1","name":"Thali","price":"` + amount + `","quantity":1}]}`
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/orders", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	maps.Copy(req.Header, header)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST order: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST order: status %d, want 201", resp.StatusCode)
+	}
+	var order api.OrderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		t.Fatalf("decode order: %v", err)
+	}
+	return order
+}
+
+// awaitStatus polls the order until it leaves PENDING and returns its status.
+func awaitStatus(t *testing.T, srv *httptest.Server, id string) string {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(srv.URL + "/api/v1/orders/" + id)
+		if err != nil {
+			t.Fatalf("GET order: %v", err)
+		}
+		var order api.OrderResponse
+		err = json.NewDecoder(resp.Body).Decode(&order)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("decode order: %v", err)
+		}
+
+		if order.Status != "PENDING" {
+			return order.Status
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("order %s still PENDING", id)
+	return ""
+}
+
+func TestPipelineConfirmsOrder(t *testing.T) {
+	_, srv := startPipeline(t)
+
+	order := createOrder(t, srv, "450.00", nil)
+	if order.Status != "PENDING" {
+		t.Fatalf("created order is %s, want PENDING", order.Status)
+	}
+
+	if status := awaitStatus(t, srv, order.ID); status != "CONFIRMED" {
+		t.Errorf("order is %s, want CONFIRMED", status)
+	}
+}
+
+func TestPipelineCountsRequestsByRoute(t *testing.T) {
+	p, srv := startPipeline(t)
+
+	order := createOrder(t, srv, "450.00", nil)
+	awaitStatus(t, srv, order.ID)
+
+	m := p.consumer.Metrics
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues("POST", "/api/v1/orders", "201")); got != 1 {
+		t.Errorf("order creations counted = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues("GET", "/api/v1/orders/", "200")); got < 1 {
+		t.Errorf("order reads counted = %v, want at least 1", got)
+	}
+	if got := testutil.ToFloat64(m.OrdersConfirmed); got != 1 {
+		t.Errorf("confirmed = %v, want 1", got)
+	}
+}
+
+func TestPipelineFailsOrderBreakingRules(t *testing.T) {
+	_, srv := startPipeline(t)
+
+	order := createOrder(t, srv, "50.00", nil)
+	if status := awaitStatus(t, srv, order.ID); status != "FAILED" {
+		t.Errorf("order is %s, want FAILED", status)
+	}
+}
+
+func TestPipelineAppliesDailyOrderCap(t *testing.T) {
+	// The shipped rules file turns the cap on
+	_, srv := startPipelineWith(t, func(cfg *config.Config) {
+		cfg.Validation.RulesFile = "../../config/rules.json"
+	})
+	for range 2 {
+		order := createOrder(t, srv, "450.00", nil)
+		if status := awaitStatus(t, srv, order.ID); status != "CONFIRMED" {
+			t.Fatalf("order under the cap is %s, want CONFIRMED", status)
+		}
+	}
+
+	_, srv = startPipelineWith(t, func(cfg *config.Config) {
+		cfg.Validation.Rules = &rules.Config{UserDailyOrderCap: 1}
+	})
+	first := createOrder(t, srv, "450.00", nil)
+	if status := awaitStatus(t, srv, first.ID); status != "CONFIRMED" {
+		t.Fatalf("first order is %s, want CONFIRMED", status)
+	}
+	second := createOrder(t, srv, "450.00", nil)
+	if status := awaitStatus(t, srv, second.ID); status != "FAILED" {
+		t.Errorf("order over the cap is %s, want FAILED", status)
+	}
+}
+
+func TestPipelineListsOrdersByStatus(t *testing.T) {
+	_, srv := startPipeline(t)
+
+	order := createOrder(t, srv, "450.00", nil)
+	awaitStatus(t, srv, order.ID)
+
+	resp, err := http.Get(srv.URL + "/api/v1/orders?user_id=user-1&status=confirmed,failed")
+	if err != nil {
+		t.Fatalf("list orders: %v", err)
+	}
+	var page api.ListOrdersResponse
+	err = json.NewDecoder(resp.Body).Decode(&page)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("decode page: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(page.Orders) != 1 || page.Orders[0].ID != order.ID {
+		t.Errorf("list = %d %+v, want the confirmed order", resp.StatusCode, page)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/v1/orders?status=CONFIRMED,SHIPPED")
+	if err != nil {
+		t.Fatalf("list orders: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("list by an unknown status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestPipelineFollowsRequestID(t *testing.T) {
+	p, srv := startPipeline(t)
+
+	order := createOrder(t, srv, "450.00", http.Header{api.RequestIDHeader: {"req-e2e-1"}})
+	if status := awaitStatus(t, srv, order.ID); status != "CONFIRMED" {
+		t.Fatalf("order is %s, want CONFIRMED", status)
+	}
+
+	// The confirmation is published by the relay after the consumer commits
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, msg := range p.broker.Messages(kafka.OrderStatusTopic) {
+			if string(msg.Key) != order.ID {
+				continue
+			}
+			for _, h := range msg.Headers {
+				if h.Key == kafka.RequestIDHeader && string(h.Value) == "req-e2e-1" {
+					return
+				}
+			}
+			t.Fatalf("status message headers %v lack the request ID", msg.Headers)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("confirmation was not published")
+}
+
+func TestPipelineContinuesCallerTrace(t *testing.T) {
+	p, srv := startPipeline(t)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	order := createOrder(t, srv, "450.00", http.Header{"Traceparent": {"00-" + traceID + "-00f067aa0ba902b7-01"}})
+	if status := awaitStatus(t, srv, order.ID); status != "CONFIRMED" {
+		t.Fatalf("order is %s, want CONFIRMED", status)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, msg := range p.broker.Messages(kafka.OrderStatusTopic) {
+			if string(msg.Key) != order.ID {
+				continue
+			}
+			for _, h := range msg.Headers {
+				if h.Key == kafka.TraceParentHeader && strings.Contains(string(h.Value), traceID) {
+					return
+				}
+			}
+			t.Fatalf("status message headers %v are not in the caller's trace", msg.Headers)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("confirmation was not published")
+}
+
+func TestPipelineGeneratesRequestID(t *testing.T) {
+	_, srv := startPipeline(t)
+
+	resp, err := http.Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatalf("GET /health: %v", err)
+	}
+	resp.Body.Close()
+
+	if id := resp.Header.Get(api.RequestIDHeader); id == "" {
+		t.Error("response has no request ID")
+	}
+}
+
+func TestPipelineCallsWebhooks(t *testing.T) {
+	_, srv := startPipeline(t)
+
+	received := make(chan webhook.Payload, 10)
+	var secret string
+	partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify(secret, r.Header, body, time.Now(), time.Minute); err != nil {
+			t.Errorf("delivery not signed: %v", err)
+		}
+		var payload webhook.Payload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("decode delivery: %v", err)
+		}
+		received <- payload
+	}))
+	defer partner.Close()
+
+	subscribe := func(header http.Header) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/restaurants/rest-1/webhooks",
+			strings.NewReader(`{"url":"`+partner.URL+`","event_types":["OrderCreated","OrderConfirmed"]}`))
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		maps.Copy(req.Header, header)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST webhook: %v", err)
+		}
+		return resp
+	}
+
+	// Only the restaurant itself may subscribe
+	for _, tc := range []struct {
+		header http.Header
+		want   int
+	}{
+		{nil, http.StatusUnauthorized},
+		{actorHeader("rest-2", domain.ActorRestaurant), http.StatusForbidden},
+		{actorHeader("user-1", domain.ActorCustomer), http.StatusForbidden},
+	} {
+		resp := subscribe(tc.header)
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("POST webhook as %v: status %d, want %d", tc.header, resp.StatusCode, tc.want)
+		}
+	}
+
+	resp := subscribe(actorHeader("rest-1", domain.ActorRestaurant))
+	var sub api.WebhookResponse
+	err := json.NewDecoder(resp.Body).Decode(&sub)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusCreated || sub.Secret == "" {
+		t.Fatalf("POST webhook: status %d, %+v, %v; want 201 with a secret", resp.StatusCode, sub, err)
+	}
+	secret = sub.Secret
+
+	order := createOrder(t, srv, "450.00", nil)
+
+	var types []domain.EventType
+	for len(types) < 2 {
+		select {
+		case payload := <-received:
+			if payload.OrderID != order.ID || payload.RestaurantID != "rest-1" {
+				t.Errorf("delivery = %+v, want an event of %s", payload, order.ID)
+			}
+			types = append(types, payload.Type)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("received %v, want the creation and confirmation", types)
+		}
+	}
+	slices.Sort(types)
+	if types[0] != domain.OrderConfirmedEventType || types[1] != domain.OrderCreatedEventType {
+		t.Errorf("received %v, want the creation and confirmation", types)
+	}
+}
+
+// cancelOrder asks to cancel the order with header identifying the caller and
+// returns the response status.
+func cancelOrder(t *testing.T, srv *httptest.Server, id string, header http.Header) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/orders/"+id+"/cancel", strings.NewReader(`{"reason":"changed my mind"}`))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	maps.Copy(req.Header, header)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST cancel: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func actorHeader(id string, role domain.ActorRole) http.Header {
+	return http.Header{api.ActorIDHeader: {id}, api.ActorRoleHeader: {string(role)}}
+}
+
+// postStep posts an empty body to path with header identifying the caller and
+// returns the response status.
+func postStep(t *testing.T, srv *httptest.Server, path string, header http.Header) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(`{"reason":"too busy"}`))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	maps.Copy(req.Header, header)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestPipelineGuardsFulfilmentSteps(t *testing.T) {
+	_, srv := startPipeline(t)
+
+	order := createOrder(t, srv, "450.00", nil)
+	awaitStatus(t, srv, order.ID)
+
+	restaurant := actorHeader("rest-1", domain.ActorRestaurant)
+	courier := actorHeader("courier-1", domain.ActorCourier)
+	steps := []struct {
+		path   string
+		header http.Header
+		want   int
+	}{
+		{"/api/v1/orders/" + order.ID + "/accept", restaurant, http.StatusMethodNotAllowed},
+		{"/api/v1/restaurants/rest-1/orders/" + order.ID + "/accept", restaurant, http.StatusOK},
+		{"/api/v1/orders/" + order.ID + "/prepare", nil, http.StatusUnauthorized},
+		{"/api/v1/orders/" + order.ID + "/prepare", actorHeader("user-1", domain.ActorCustomer), http.StatusForbidden},
+		{"/api/v1/orders/" + order.ID + "/prepare", actorHeader("rest-2", domain.ActorRestaurant), http.StatusForbidden},
+		{"/api/v1/orders/" + order.ID + "/prepare", restaurant, http.StatusOK},
+		{"/api/v1/orders/" + order.ID + "/ready", restaurant, http.StatusOK},
+		{"/api/v1/orders/" + order.ID + "/pickup", restaurant, http.StatusForbidden},
+		{"/api/v1/orders/" + order.ID + "/pickup", courier, http.StatusOK},
+		{"/api/v1/orders/" + order.ID + "/out-for-delivery", courier, http.StatusOK},
+		{"/api/v1/orders/" + order.ID + "/deliver", courier, http.StatusOK},
+	}
+	for _, step := range steps {
+		if got := postStep(t, srv, step.path, step.header); got != step.want {
+			t.Errorf("POST %s as %v: status %d, want %d", step.path, step.header, got, step.want)
+		}
+	}
+}
+
+func TestPipelineCancelsForAuthenticatedOwnerOnly(t *testing.T) {
+	_, srv := startPipeline(t)
+
+	order := createOrder(t, srv, "450.00", nil)
+	awaitStatus(t, srv, order.ID)
+
+	cases := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized},
+		{"self-declared support", actorHeader("agent-1", domain.ActorSupport), http.StatusForbidden},
+		{"another customer", actorHeader("user-2", domain.ActorCustomer), http.StatusForbidden},
+		{"owner", actorHeader("user-1", domain.ActorCustomer), http.StatusOK},
+	}
+	for _, tc := range cases {
+		if got := cancelOrder(t, srv, order.ID, tc.header); got != tc.want {
+			t.Errorf("%s: cancel answered %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPipelineTrustsOnlyTheGateway(t *testing.T) {
+	_, srv := startPipelineWith(t, func(cfg *config.Config) {
+		cfg.HTTP.GatewayToken = "gateway-secret"
+	})
+
+	order := createOrder(t, srv, "450.00", nil)
+	awaitStatus(t, srv, order.ID)
+
+	forged := actorHeader("agent-1", domain.ActorSupport)
+	forged.Set(api.GatewayTokenHeader, "guess")
+	if got := cancelOrder(t, srv, order.ID, forged); got != http.StatusUnauthorized {
+		t.Errorf("cancel with a wrong gateway token answered %d, want 401", got)
+	}
+
+	support := actorHeader("agent-1", domain.ActorSupport)
+	support.Set(api.GatewayTokenHeader, "gateway-secret")
+	if got := cancelOrder(t, srv, order.ID, support); got != http.StatusOK {
+		t.Errorf("cancel by support through the gateway answered %d, want 200", got)
+	}
+}
+
+func TestPipelineGuardsRestaurantRoutes(t *testing.T) {
+	_, srv := startPipeline(t)
+
+	order := createOrder(t, srv, "450.00", nil)
+	awaitStatus(t, srv, order.ID)
+
+	queue := func(restaurantID string, header http.Header) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/restaurants/"+restaurantID+"/orders", nil)
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		maps.Copy(req.Header, header)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET queue: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	restaurant := actorHeader("rest-1", domain.ActorRestaurant)
+	other := actorHeader("rest-2", domain.ActorRestaurant)
+	cases := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized},
+		{"customer", actorHeader("user-1", domain.ActorCustomer), http.StatusForbidden},
+		{"another restaurant", other, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		if got := queue("rest-1", tc.header); got != tc.want {
+			t.Errorf("%s: queue answered %d, want %d", tc.name, got, tc.want)
+		}
+		for _, step := range []string{"accept", "reject"} {
+			path := "/api/v1/restaurants/rest-1/orders/" + order.ID + "/" + step
+			if got := postStep(t, srv, path, tc.header); got != tc.want {
+				t.Errorf("%s: %s answered %d, want %d", tc.name, step, got, tc.want)
+			}
+		}
+	}
+
+	// Acting under its own id, another restaurant still cannot reach the order
+	if got := postStep(t, srv, "/api/v1/restaurants/rest-2/orders/"+order.ID+"/reject", other); got != http.StatusNotFound {
+		t.Errorf("reject by another restaurant under its own id answered %d, want 404", got)
+	}
+	if got := queue("rest-1", restaurant); got != http.StatusOK {
+		t.Errorf("queue of the restaurant answered %d, want 200", got)
+	}
+	if got := postStep(t, srv, "/api/v1/restaurants/rest-1/orders/"+order.ID+"/reject", restaurant); got != http.StatusOK {
+		t.Errorf("reject by the restaurant answered %d, want 200", got)
+	}
+}
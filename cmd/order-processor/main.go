@@ -3,26 +3,67 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/dmehra2102/order-management-platform/internal/config"
 	"github.com/dmehra2102/order-management-platform/internal/kafka"
 	"github.com/dmehra2102/order-management-platform/internal/logger"
 	"github.com/dmehra2102/order-management-platform/internal/metrics"
+	"github.com/dmehra2102/order-management-platform/internal/outbox"
+	"github.com/dmehra2102/order-management-platform/internal/reactions"
 	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+	"github.com/dmehra2102/order-management-platform/internal/webhook"
+	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Consumer modes
+const (
+	modeValidation = "validation"
+	modeStatus     = "status"
+	modeWebhooks   = "webhooks"
 )
 
 func main() {
-	cfg := config.Load()
+	mode := flag.String("mode", modeValidation, "consumer to run: validation of new orders, status to react to order status events, or webhooks to call partner endpoints")
+	cfg := config.MustLoad()
 	l := logger.New(cfg.LogLevel)
 
+	if *mode != modeValidation && *mode != modeStatus && *mode != modeWebhooks {
+		l.Error("Unknown processor mode", map[string]any{
+			"mode": *mode,
+		})
+		os.Exit(1)
+	}
+
 	l.Info("Starting Order Processor Service", map[string]any{
 		"environment": cfg.Environment,
+		"mode":        *mode,
 	})
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, "order-processor")
+	if err != nil {
+		l.Error("Failed to set up tracing", map[string]any{
+			"error": err,
+		})
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			l.Warn("Failed to flush traces", map[string]any{
+				"error": err,
+			})
+		}
+	}()
+
 	// Connect to DB
 	db, err := sql.Open("postgres", cfg.DatabaseURL())
 	if err != nil {
@@ -32,9 +73,10 @@ func main() {
 		os.Exit(1)
 	}
 	defer db.Close()
+	cfg.Database.ConfigurePool(db)
 
 	// Verify connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Database.ConnectTimeout)
 	defer cancel()
 	if err := db.PingContext(ctx); err != nil {
 		l.Error("Failed to ping database", map[string]any{
@@ -54,7 +96,58 @@ func main() {
 		})
 	}
 
-	consumer := kafka.NewConsumer(cfg.KafkaBrokers, "order-processor-group", l, orderRepo, db)
+	broker := cfg.Kafka.Broker()
+
+	serializer, accepted, err := cfg.Kafka.Serializers()
+	if err != nil {
+		l.Error("Failed to open schema registry", map[string]any{
+			"error": err,
+		})
+		os.Exit(1)
+	}
+
+	producer := kafka.NewProducer(broker, l)
+	producer.Topics = cfg.Kafka.Topics
+	producer.Source = "order-processor"
+	producer.Encoding = cfg.Kafka.Encoding
+	producer.Serializer = serializer
+	defer producer.Close()
+
+	var consumer *kafka.Consumer
+	switch *mode {
+	case modeValidation:
+		rulesConfig, err := cfg.Validation.LoadRules()
+		if err != nil {
+			l.Error("Failed to load validation rules", map[string]any{
+				"error": err,
+			})
+			os.Exit(1)
+		}
+
+		engine, err := rulesConfig.Build()
+		if err != nil {
+			l.Error("Invalid validation rules", map[string]any{
+				"error": err,
+			})
+			os.Exit(1)
+		}
+
+		consumer = kafka.NewConsumer(broker, cfg.Kafka.ConsumerGroup, l, orderRepo, engine, producer)
+	case modeStatus:
+		dispatcher := kafka.NewDispatcher()
+		reactions.Register(dispatcher, reactions.NewLogNotifier(l))
+		consumer = kafka.NewStatusConsumer(broker, cfg.Kafka.StatusConsumerGroup, l, orderRepo, producer, dispatcher)
+	case modeWebhooks:
+		dispatcher := kafka.NewDispatcher()
+		webhook.Register(dispatcher, l)
+		consumer = kafka.NewOrderEventsConsumer(broker, cfg.Kafka.WebhookConsumerGroup, l, orderRepo, producer, dispatcher)
+	}
+	consumer.Topics = cfg.Kafka.Topics
+	consumer.Retry = cfg.Kafka.Retry
+	consumer.Metrics = m
+	for _, s := range accepted {
+		consumer.Accept(s)
+	}
 	defer consumer.Close()
 
 	ctx, cancel = context.WithCancel(context.Background())
@@ -65,6 +158,50 @@ func main() {
 		consumerErrors <- consumer.Start(ctx)
 	}()
 
+	// Outbox relay publishes the status events written by validation; the
+	// status consumer writes no events
+	relayDone := make(chan error, 1)
+	if *mode == modeValidation {
+		relay := outbox.NewRelay(orderRepo, producer, l, cfg.Outbox.PollInterval, cfg.Outbox.BatchSize)
+		go func() {
+			relayDone <- relay.Start(ctx)
+		}()
+	} else {
+		close(relayDone)
+	}
+
+	// The webhook mode sends the deliveries its consumer queues
+	workerDone := make(chan error, 1)
+	if *mode == modeWebhooks {
+		worker := webhook.NewWorker(orderRepo, l, cfg.Webhooks.PollInterval, cfg.Webhooks.BatchSize, cfg.Webhooks.Delivery)
+		worker.Metrics = m
+		worker.AllowPrivateNetworks = cfg.Development()
+		go func() {
+			workerDone <- worker.Start(ctx)
+		}()
+	} else {
+		close(workerDone)
+	}
+
+	// Prometheus scrapes the processor on its own port
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", promhttp.Handler())
+	metricsServer := &http.Server{
+		Addr:              fmt.Sprintf("%s:%s", cfg.HTTP.Host, cfg.Metrics.Port),
+		Handler:           metricsMux,
+		ReadHeaderTimeout: cfg.HTTP.ReadTimeout,
+	}
+	go func() {
+		l.Info("Metrics server listening", map[string]any{
+			"addr": metricsServer.Addr,
+		})
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			l.Error("Metrics server error", map[string]any{
+				"error": err,
+			})
+		}
+	}()
+
 	// Shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -82,5 +219,17 @@ func main() {
 		}
 	}
 
+	cancel()
+	<-relayDone
+	<-workerDone
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer shutdownCancel()
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		l.Error("Metrics server shutdown error", map[string]any{
+			"error": err,
+		})
+	}
+
 	l.Info("Order Processor Service shutdown complete", nil)
 }
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+// Headers the API gateway sets on every request it forwards, naming the caller
+// it authenticated. They are trusted only on requests that also carry
+// GatewayTokenHeader with the server's GatewayToken.
+const (
+	ActorIDHeader      = "X-Actor-ID"
+	ActorRoleHeader    = "X-Actor-Role"
+	GatewayTokenHeader = "X-Gateway-Token"
+)
+
+var (
+	errUnauthenticated = errors.New("unauthenticated")
+	errForbidden       = errors.New("forbidden")
+)
+
+// actor returns the caller of r as authenticated by the gateway. Without a
+// GatewayToken, as in development, callers name themselves but may not claim
+// the support role.
+func (s *Server) actor(r *http.Request) (domain.Actor, error) {
+	if s.GatewayToken != "" {
+		token := r.Header.Get(GatewayTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.GatewayToken)) != 1 {
+			return domain.Actor{}, fmt.Errorf("%w: request did not come through the gateway", errUnauthenticated)
+		}
+	}
+
+	actor := domain.Actor{ID: r.Header.Get(ActorIDHeader), Role: domain.ActorRole(r.Header.Get(ActorRoleHeader))}
+	if actor.ID == "" {
+		return domain.Actor{}, fmt.Errorf("%w: %s is not set", errUnauthenticated, ActorIDHeader)
+	}
+	switch actor.Role {
+	case domain.ActorCustomer, domain.ActorRestaurant, domain.ActorCourier:
+	case domain.ActorSupport:
+		if s.GatewayToken == "" {
+			return domain.Actor{}, fmt.Errorf("%w: the support role is only accepted from the gateway", errForbidden)
+		}
+	default:
+		return domain.Actor{}, fmt.Errorf("%w: %s %q is not customer, restaurant, courier or support", errUnauthenticated, ActorRoleHeader, actor.Role)
+	}
+	return actor, nil
+}
+
+// restaurant returns the authenticated restaurant named by the route's id.
+// Other callers, support included, are refused.
+func (s *Server) restaurant(r *http.Request) (string, error) {
+	actor, err := s.actor(r)
+	if err != nil {
+		return "", err
+	}
+	if actor.Role != domain.ActorRestaurant || actor.ID != r.PathValue("id") {
+		return "", fmt.Errorf("%w: %s %q may not act for restaurant %s", errForbidden, actor.Role, actor.ID, r.PathValue("id"))
+	}
+	return actor.ID, nil
+}
+
+// respondAuthError answers a request whose caller could not be established
+// or may not make it.
+func (s *Server) respondAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errForbidden) {
+		s.respondError(w, http.StatusForbidden, "Forbidden", err.Error())
+		return
+	}
+	s.respondError(w, http.StatusUnauthorized, "Unauthenticated", err.Error())
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/metrics"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/service"
+)
+
+type RejectOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+// listRestaurantOrders is the restaurant's order queue: its orders oldest
+// first, by default only those still waiting on the restaurant.
+func (s *Server) listRestaurantOrders(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := s.restaurant(r)
+	if err != nil {
+		s.respondAuthError(w, err)
+		return
+	}
+
+	filter := repository.OrderFilter{
+		RestaurantID: restaurantID,
+		OldestFirst:  true,
+	}
+	if !r.URL.Query().Has("status") {
+		filter.Statuses = domain.OpenStatuses
+	}
+
+	s.listOrderPage(w, r, filter)
+}
+
+func (s *Server) acceptRestaurantOrder(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := s.restaurant(r)
+	if err != nil {
+		s.respondAuthError(w, err)
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(r)
+	if !ok {
+		s.respondError(w, http.StatusPreconditionFailed, "Precondition failed", "If-Match does not name an order version")
+		return
+	}
+
+	order, err := s.service.AcceptRestaurantOrder(r.Context(), restaurantID, r.PathValue("orderID"), expectedVersion)
+	if err != nil {
+		s.respondAdvanceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(order.Version))
+	s.respondJSON(w, http.StatusOK, newOrderResponse(order))
+}
+
+func (s *Server) rejectRestaurantOrder(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := s.restaurant(r)
+	if err != nil {
+		s.respondAuthError(w, err)
+		return
+	}
+
+	var req RejectOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Reason == "" {
+		s.respondError(w, http.StatusBadRequest, "Missing rejection reason", "")
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(r)
+	if !ok {
+		s.respondError(w, http.StatusPreconditionFailed, "Precondition failed", "If-Match does not name an order version")
+		return
+	}
+
+	order, err := s.service.RejectOrder(r.Context(), restaurantID, r.PathValue("orderID"), expectedVersion, req.Reason)
+	if err != nil {
+		s.respondCancelError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(order.Version))
+	s.respondJSON(w, http.StatusOK, newOrderResponse(order))
+}
+
+// TrackOpenOrders refreshes the open order gauge every interval until ctx is
+// cancelled. Restaurants whose queue emptied are dropped from the gauge.
+// Every order-api replica reports the same counts, so queries must take
+// max by (restaurant_id) rather than sum across instances.
+func TrackOpenOrders(ctx context.Context, svc *service.OrderService, m *metrics.Metrics, l *logger.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	seen := make(map[string]bool)
+	for {
+		counts, err := svc.OpenOrdersByRestaurant(ctx)
+		if err != nil {
+			l.Warn("Failed to count open orders", map[string]any{
+				"error": err,
+			})
+		} else {
+			for restaurantID := range seen {
+				if _, ok := counts[restaurantID]; !ok {
+					m.RestaurantOpenOrders.DeleteLabelValues(restaurantID)
+					delete(seen, restaurantID)
+				}
+			}
+			for restaurantID, count := range counts {
+				m.RestaurantOpenOrders.WithLabelValues(restaurantID).Set(float64(count))
+				seen[restaurantID] = true
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,622 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/metrics"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/requestid"
+	"github.com/dmehra2102/order-management-platform/internal/service"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type CreateOrderRequest struct {
+	UserID       string                   `json:"user_id"`
+	RestaurantID string                   `json:"restaurant_id"`
+	Currency     string                   `json:"currency"`
+	Items        []CreateOrderItemRequest `json:"items"`
+}
+
+// CreateOrderItemRequest.Price accepts {"amount":"249.50","currency":"INR"} or
+// a bare decimal in the order currency.
+type CreateOrderItemRequest struct {
+	ItemID   string       `json:"item_id"`
+	Name     string       `json:"name"`
+	Price    domain.Money `json:"price"`
+	Quantity int          `json:"quantity"`
+}
+
+type OrderResponse struct {
+	ID           string             `json:"id"`
+	UserID       string             `json:"user_id"`
+	RestaurantID string             `json:"restaurant_id"`
+	Items        []domain.OrderItem `json:"items"`
+	TotalAmount  domain.Money       `json:"total_amount"`
+	Status       string             `json:"status"`
+	Version      int                `json:"version"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+
+	AcceptedAt       *time.Time `json:"accepted_at,omitempty"`
+	PreparingAt      *time.Time `json:"preparing_at,omitempty"`
+	ReadyAt          *time.Time `json:"ready_at,omitempty"`
+	PickedUpAt       *time.Time `json:"picked_up_at,omitempty"`
+	OutForDeliveryAt *time.Time `json:"out_for_delivery_at,omitempty"`
+	DeliveredAt      *time.Time `json:"delivered_at,omitempty"`
+}
+
+// ListOrdersResponse is one page of orders. The cursors are passed back as
+// the cursor query parameter and are empty at either end. It replaced the bare
+// array the list endpoints used to return, which breaks clients that decode
+// the body as a list.
+type ListOrdersResponse struct {
+	Orders     []OrderResponse `json:"orders"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	PrevCursor string          `json:"prev_cursor,omitempty"`
+}
+
+// CancelOrderRequest gives the reason for a cancellation. The canceller is
+// the caller the gateway authenticated.
+type CancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// Page sizes for listing orders
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header to the column size.
+const maxIdempotencyKeyLength = 255
+
+// Server is the HTTP API. GatewayToken, when set, is the token the API
+// gateway presents with the caller identities it forwards; it must be set
+// before Handler is served.
+type Server struct {
+	GatewayToken string
+
+	mux      *http.ServeMux
+	service  *service.OrderService
+	webhooks *service.WebhookService
+	logger   *logger.Logger
+	metrics  *metrics.Metrics
+
+	idempotencyKeyTTL time.Duration
+}
+
+func NewServer(svc *service.OrderService, webhooks *service.WebhookService, m *metrics.Metrics, l *logger.Logger, idempotencyKeyTTL time.Duration) *Server {
+	s := &Server{
+		mux:      http.NewServeMux(),
+		service:  svc,
+		webhooks: webhooks,
+		logger:   l,
+		metrics:  m,
+
+		idempotencyKeyTTL: idempotencyKeyTTL,
+	}
+	s.registerRoutes()
+	return s
+}
+
+// Handler returns the API routes wrapped in request logging, tracing and
+// metrics.
+func (s *Server) Handler() http.Handler {
+	return loggingMiddleware(instrumentMiddleware(s.mux, s.metrics), s.logger)
+}
+
+func (s *Server) registerRoutes() {
+	s.mux.HandleFunc("POST /api/v1/orders", s.createOrder)
+	s.mux.HandleFunc("GET /api/v1/orders/", s.handleGetOrder)
+	s.mux.HandleFunc("GET /api/v1/orders", s.listOrders)
+	s.mux.HandleFunc("POST /api/v1/orders/{id}/cancel", s.cancelOrder)
+	s.mux.HandleFunc("POST /api/v1/orders/{id}/prepare", s.advanceOrder(s.service.StartPreparingOrder))
+	s.mux.HandleFunc("POST /api/v1/orders/{id}/ready", s.advanceOrder(s.service.MarkOrderReady))
+	s.mux.HandleFunc("POST /api/v1/orders/{id}/pickup", s.advanceOrder(s.service.PickUpOrder))
+	s.mux.HandleFunc("POST /api/v1/orders/{id}/out-for-delivery", s.advanceOrder(s.service.SendOrderOutForDelivery))
+	s.mux.HandleFunc("POST /api/v1/orders/{id}/deliver", s.advanceOrder(s.service.DeliverOrder))
+	s.mux.HandleFunc("GET /api/v1/restaurants/{id}/orders", s.listRestaurantOrders)
+	s.mux.HandleFunc("POST /api/v1/restaurants/{id}/orders/{orderID}/accept", s.acceptRestaurantOrder)
+	s.mux.HandleFunc("POST /api/v1/restaurants/{id}/orders/{orderID}/reject", s.rejectRestaurantOrder)
+	s.mux.HandleFunc("POST /api/v1/restaurants/{id}/webhooks", s.createWebhook)
+	s.mux.HandleFunc("GET /api/v1/restaurants/{id}/webhooks", s.listWebhooks)
+	s.mux.HandleFunc("GET /api/v1/restaurants/{id}/webhooks/{webhookID}", s.getWebhook)
+	s.mux.HandleFunc("PATCH /api/v1/restaurants/{id}/webhooks/{webhookID}", s.updateWebhook)
+	s.mux.HandleFunc("DELETE /api/v1/restaurants/{id}/webhooks/{webhookID}", s.deleteWebhook)
+	s.mux.HandleFunc("GET /api/v1/restaurants/{id}/webhooks/{webhookID}/deliveries", s.listWebhookDeliveries)
+	s.mux.Handle("/metrics", promhttp.Handler())
+	s.mux.HandleFunc("GET /health", s.healthCheck)
+}
+
+func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
+	var req CreateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		s.respondError(w, http.StatusBadRequest, "Invalid Idempotency-Key", fmt.Sprintf("key must be at most %d characters", maxIdempotencyKeyLength))
+		return
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = domain.DefaultCurrency
+	}
+
+	var items []domain.OrderItem
+	for _, item := range req.Items {
+		price := item.Price
+		if price.Currency == "" {
+			price.Currency = currency
+		}
+
+		items = append(items, domain.OrderItem{
+			ID:       item.ItemID,
+			Name:     item.Name,
+			Price:    price,
+			Quantity: item.Quantity,
+		})
+	}
+
+	var (
+		order    *domain.Order
+		replayed bool
+		err      error
+	)
+	if idempotencyKey != "" {
+		key := repository.IdempotencyKey{Key: idempotencyKey, RequestHash: requestHash(req)}
+		order, replayed, err = s.service.CreateOrderOnce(r.Context(), key, s.idempotencyKeyTTL, req.UserID, req.RestaurantID, items)
+	} else {
+		order, err = s.service.CreateOrder(r.Context(), req.UserID, req.RestaurantID, items)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrIdempotencyKeyReused) {
+			s.respondError(w, http.StatusUnprocessableEntity, "Idempotency-Key already used", err.Error())
+			return
+		}
+		s.metrics.OrdersFailed.Inc()
+		s.respondError(w, http.StatusBadRequest, "Failed to create order", err.Error())
+		return
+	}
+
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	} else {
+		s.metrics.OrdersCreated.Inc()
+	}
+
+	resp := newOrderResponse(order)
+
+	w.Header().Set("ETag", etag(order.Version))
+	s.respondJSON(w, http.StatusCreated, resp)
+}
+
+func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	// Extract order ID from /api/v1/orders/{id}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/orders/")
+	if path == "" {
+		s.respondError(w, http.StatusBadRequest, "Missing order ID", "")
+		return
+	}
+
+	order, err := s.service.GetOrder(r.Context(), path)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrderNotFound) {
+			s.respondError(w, http.StatusNotFound, "Order not found", err.Error())
+		} else {
+			s.respondError(w, http.StatusInternalServerError, "Failed to fetch order", err.Error())
+		}
+		return
+	}
+
+	resp := newOrderResponse(order)
+
+	w.Header().Set("ETag", etag(order.Version))
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) cancelOrder(w http.ResponseWriter, r *http.Request) {
+	actor, err := s.actor(r)
+	if err != nil {
+		s.respondAuthError(w, err)
+		return
+	}
+
+	var req CancelOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Reason == "" {
+		s.respondError(w, http.StatusBadRequest, "Missing cancellation reason", "")
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(r)
+	if !ok {
+		s.respondError(w, http.StatusPreconditionFailed, "Precondition failed", "If-Match does not name an order version")
+		return
+	}
+
+	order, err := s.service.CancelOrder(r.Context(), r.PathValue("id"), expectedVersion, actor, req.Reason)
+	if err != nil {
+		s.respondCancelError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(order.Version))
+	s.respondJSON(w, http.StatusOK, newOrderResponse(order))
+}
+
+// advanceOrder returns a handler for one fulfilment step of the order
+// lifecycle, taken on behalf of the authenticated caller.
+func (s *Server) advanceOrder(step func(ctx context.Context, orderID string, expectedVersion int, actor domain.Actor) (*domain.Order, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, err := s.actor(r)
+		if err != nil {
+			s.respondAuthError(w, err)
+			return
+		}
+
+		expectedVersion, ok := ifMatchVersion(r)
+		if !ok {
+			s.respondError(w, http.StatusPreconditionFailed, "Precondition failed", "If-Match does not name an order version")
+			return
+		}
+
+		order, err := step(r.Context(), r.PathValue("id"), expectedVersion, actor)
+		if err != nil {
+			s.respondAdvanceError(w, r, err)
+			return
+		}
+
+		w.Header().Set("ETag", etag(order.Version))
+		s.respondJSON(w, http.StatusOK, newOrderResponse(order))
+	}
+}
+
+func (s *Server) respondCancelError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrVersionConflict):
+		s.respondVersionConflict(w, r, err)
+	case errors.Is(err, repository.ErrOrderNotFound):
+		s.respondError(w, http.StatusNotFound, "Order not found", err.Error())
+	case errors.Is(err, domain.ErrCancelNotPermitted):
+		s.respondError(w, http.StatusForbidden, "Cancellation not permitted", err.Error())
+	case errors.Is(err, domain.ErrInvalidTransition):
+		s.respondError(w, http.StatusConflict, "Order cannot be cancelled", err.Error())
+	default:
+		s.respondError(w, http.StatusInternalServerError, "Failed to cancel order", err.Error())
+	}
+}
+
+func (s *Server) respondAdvanceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrVersionConflict):
+		s.respondVersionConflict(w, r, err)
+	case errors.Is(err, repository.ErrOrderNotFound):
+		s.respondError(w, http.StatusNotFound, "Order not found", err.Error())
+	case errors.Is(err, domain.ErrAdvanceNotPermitted):
+		s.respondError(w, http.StatusForbidden, "Fulfilment step not permitted", err.Error())
+	case errors.Is(err, domain.ErrInvalidTransition):
+		s.respondError(w, http.StatusConflict, "Invalid order status transition", err.Error())
+	default:
+		s.respondError(w, http.StatusInternalServerError, "Failed to update order", err.Error())
+	}
+}
+
+func (s *Server) listOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := repository.OrderFilter{
+		UserID:       query.Get("user_id"),
+		RestaurantID: query.Get("restaurant_id"),
+	}
+
+	s.listOrderPage(w, r, filter)
+}
+
+// listOrderPage reads the status, created range, limit and cursor query
+// parameters into filter and responds with the requested page.
+func (s *Server) listOrderPage(w http.ResponseWriter, r *http.Request, filter repository.OrderFilter) {
+	query := r.URL.Query()
+
+	for _, value := range query["status"] {
+		for _, status := range strings.Split(value, ",") {
+			status := domain.OrderStatus(strings.ToUpper(strings.TrimSpace(status)))
+			if !status.IsValid() {
+				s.respondError(w, http.StatusBadRequest, "Invalid status", fmt.Sprintf("unknown order status %q", status))
+				return
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseTimeParam(query.Get("created_from")); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid created_from", err.Error())
+		return
+	}
+	if filter.CreatedTo, err = parseTimeParam(query.Get("created_to")); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid created_to", err.Error())
+		return
+	}
+
+	limit := defaultPageSize
+	if raw := query.Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			s.respondError(w, http.StatusBadRequest, "Invalid limit", fmt.Sprintf("limit must be between 1 and %d", maxPageSize))
+			return
+		}
+	}
+
+	var cursor *repository.OrderCursor
+	if raw := query.Get("cursor"); raw != "" {
+		if cursor, err = repository.DecodeOrderCursor(raw); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid cursor", err.Error())
+			return
+		}
+	}
+
+	page, err := s.service.ListOrders(r.Context(), filter, cursor, limit)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to list orders", err.Error())
+		return
+	}
+
+	resp := ListOrdersResponse{Orders: []OrderResponse{}}
+	for _, order := range page.Orders {
+		resp.Orders = append(resp.Orders, newOrderResponse(&order))
+	}
+	if page.Next != nil {
+		resp.NextCursor = page.Next.Encode()
+	}
+	if page.Prev != nil {
+		resp.PrevCursor = page.Prev.Encode()
+	}
+
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// parseTimeParam parses an RFC 3339 query parameter; empty means unset.
+func parseTimeParam(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// respondVersionConflict answers 412 when the client sent If-Match and 409
+// when the order changed underneath a request without one.
+func (s *Server) respondVersionConflict(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Header.Get("If-Match") != "" {
+		s.respondError(w, http.StatusPreconditionFailed, "Precondition failed", err.Error())
+		return
+	}
+	s.respondError(w, http.StatusConflict, "Order was modified concurrently", err.Error())
+}
+
+// etag formats an order version as a strong entity tag, e.g. "3".
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatchVersion returns the order version named by the If-Match header, or 0
+// when the header is absent or "*". ok is false when the header is set but
+// does not hold a single order version, which can never match.
+func ifMatchVersion(r *http.Request) (version int, ok bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, false
+	}
+
+	version, err = strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// requestHash fingerprints a create request after decoding, so that
+// differences in whitespace or key order do not count as a different body.
+func requestHash(req CreateOrderRequest) string {
+	canonical, _ := json.Marshal(req)
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// PurgeIdempotencyKeys deletes expired idempotency keys every interval until
+// ctx is cancelled.
+func PurgeIdempotencyKeys(ctx context.Context, svc *service.OrderService, l *logger.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := svc.PurgeIdempotencyKeys(ctx)
+			if err != nil {
+				l.Warn("Failed to purge idempotency keys", map[string]any{
+					"error": err,
+				})
+				continue
+			}
+			if deleted > 0 {
+				l.Debug("Purged idempotency keys", map[string]any{
+					"deleted": deleted,
+				})
+			}
+		}
+	}
+}
+
+func newOrderResponse(order *domain.Order) OrderResponse {
+	return OrderResponse{
+		ID:           order.ID,
+		UserID:       order.UserID,
+		RestaurantID: order.RestaurantID,
+		Items:        order.Items,
+		TotalAmount:  order.TotalAmount,
+		Status:       string(order.Status),
+		Version:      order.Version,
+		CreatedAt:    order.CreatedAt,
+		UpdatedAt:    order.UpdatedAt,
+
+		AcceptedAt:       order.AcceptedAt,
+		PreparingAt:      order.PreparingAt,
+		ReadyAt:          order.ReadyAt,
+		PickedUpAt:       order.PickedUpAt,
+		OutForDeliveryAt: order.OutForDeliveryAt,
+		DeliveredAt:      order.DeliveredAt,
+	}
+}
+
+func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]any{
+		"status": "healthy",
+	})
+}
+
+func (s *Server) respondJSON(w http.ResponseWriter, code int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (s *Server) respondError(w http.ResponseWriter, code int, message, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	resp := ErrorResponse{
+		Error:   message,
+		Message: detail,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RequestIDHeader names the request ID a caller may send. It is echoed on
+// every response, with a generated ID when the caller sent none.
+const RequestIDHeader = "X-Request-ID"
+
+// loggingMiddleware tags each request's context with its request ID, which
+// follows the order through the outbox and Kafka, and logs the request.
+func loggingMiddleware(next http.Handler, l *logger.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		r = r.WithContext(requestid.NewContext(r.Context(), id))
+
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		duration := time.Since(start)
+		l.WithContext(r.Context()).Debug("HTTP Request", map[string]any{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"duration": duration.Milliseconds(),
+		})
+	})
+}
+
+var tracer = tracing.Tracer("github.com/dmehra2102/order-management-platform/internal/api")
+
+// instrumentMiddleware serves each request in a server span that continues
+// the caller's trace, if it sent a traceparent header, and counts and times
+// it by method, route pattern and status code. The route is known once the
+// mux has matched it, so next must be the ServeMux itself.
+func instrumentMiddleware(next http.Handler, m *metrics.Metrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(rec, r)
+
+		// Unmatched paths share one label so that they cannot grow the series
+		route := "unmatched"
+		if r.Pattern != "" {
+			route = routePath(r.Pattern)
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+
+		status := strconv.Itoa(rec.status)
+		m.HTTPRequests.WithLabelValues(r.Method, route, status).Inc()
+		m.HTTPRequestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routePath strips the method from a ServeMux pattern such as
+// "POST /api/v1/orders/{id}/cancel".
+func routePath(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
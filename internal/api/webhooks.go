@@ -0,0 +1,252 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/service"
+)
+
+// CreateWebhookRequest subscribes URL to EventTypes, or to every event
+// webhooks are sent for when empty.
+type CreateWebhookRequest struct {
+	URL        string             `json:"url"`
+	EventTypes []domain.EventType `json:"event_types"`
+}
+
+// UpdateWebhookRequest changes the fields that are present.
+type UpdateWebhookRequest struct {
+	URL        *string            `json:"url"`
+	EventTypes []domain.EventType `json:"event_types"`
+	Active     *bool              `json:"active"`
+}
+
+// WebhookResponse is a subscription. Secret, which signs every delivery, is
+// only returned when the subscription is created.
+type WebhookResponse struct {
+	ID                  string             `json:"id"`
+	RestaurantID        string             `json:"restaurant_id"`
+	URL                 string             `json:"url"`
+	EventTypes          []domain.EventType `json:"event_types"`
+	Secret              string             `json:"secret,omitempty"`
+	Active              bool               `json:"active"`
+	ConsecutiveFailures int                `json:"consecutive_failures"`
+	DisabledAt          *time.Time         `json:"disabled_at,omitempty"`
+	DisabledReason      string             `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time          `json:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at"`
+}
+
+type ListWebhooksResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+// WebhookDeliveryResponse is an entry of the delivery log. NextAttemptAt is
+// set while the delivery is pending.
+type WebhookDeliveryResponse struct {
+	ID             int64           `json:"id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	OrderID        string          `json:"order_id"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type ListWebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+}
+
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := s.restaurant(r)
+	if err != nil {
+		s.respondAuthError(w, err)
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	sub, err := s.webhooks.CreateSubscription(r.Context(), restaurantID, req.URL, req.EventTypes)
+	if err != nil {
+		s.respondWebhookError(w, err)
+		return
+	}
+
+	resp := newWebhookResponse(sub)
+	resp.Secret = sub.Secret
+	s.respondJSON(w, http.StatusCreated, resp)
+}
+
+func (s *Server) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := s.restaurant(r)
+	if err != nil {
+		s.respondAuthError(w, err)
+		return
+	}
+
+	subs, err := s.webhooks.ListSubscriptions(r.Context(), restaurantID)
+	if err != nil {
+		s.respondWebhookError(w, err)
+		return
+	}
+
+	resp := ListWebhooksResponse{Webhooks: []WebhookResponse{}}
+	for _, sub := range subs {
+		resp.Webhooks = append(resp.Webhooks, newWebhookResponse(&sub))
+	}
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) getWebhook(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := s.restaurant(r)
+	if err != nil {
+		s.respondAuthError(w, err)
+		return
+	}
+
+	sub, err := s.webhooks.GetSubscription(r.Context(), restaurantID, r.PathValue("webhookID"))
+	if err != nil {
+		s.respondWebhookError(w, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, newWebhookResponse(sub))
+}
+
+func (s *Server) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := s.restaurant(r)
+	if err != nil {
+		s.respondAuthError(w, err)
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	update := service.WebhookUpdate{URL: req.URL, EventTypes: req.EventTypes, Active: req.Active}
+	sub, err := s.webhooks.UpdateSubscription(r.Context(), restaurantID, r.PathValue("webhookID"), update)
+	if err != nil {
+		s.respondWebhookError(w, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, newWebhookResponse(sub))
+}
+
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := s.restaurant(r)
+	if err != nil {
+		s.respondAuthError(w, err)
+		return
+	}
+
+	if err := s.webhooks.DeleteSubscription(r.Context(), restaurantID, r.PathValue("webhookID")); err != nil {
+		s.respondWebhookError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listWebhookDeliveries is the subscription's delivery log, newest first,
+// optionally of one status.
+func (s *Server) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := s.restaurant(r)
+	if err != nil {
+		s.respondAuthError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	status := repository.WebhookDeliveryStatus(strings.ToUpper(query.Get("status")))
+	switch status {
+	case "", repository.WebhookDeliveryPending, repository.WebhookDeliveryDelivered, repository.WebhookDeliveryFailed:
+	default:
+		s.respondError(w, http.StatusBadRequest, "Invalid status", fmt.Sprintf("status %q is not PENDING, DELIVERED or FAILED", status))
+		return
+	}
+
+	limit := defaultPageSize
+	if raw := query.Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			s.respondError(w, http.StatusBadRequest, "Invalid limit", fmt.Sprintf("limit must be between 1 and %d", maxPageSize))
+			return
+		}
+	}
+
+	deliveries, err := s.webhooks.ListDeliveries(r.Context(), restaurantID, r.PathValue("webhookID"), status, limit)
+	if err != nil {
+		s.respondWebhookError(w, err)
+		return
+	}
+
+	resp := ListWebhookDeliveriesResponse{Deliveries: []WebhookDeliveryResponse{}}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, newWebhookDeliveryResponse(&d))
+	}
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) respondWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidWebhook):
+		s.respondError(w, http.StatusBadRequest, "Invalid webhook subscription", err.Error())
+	case errors.Is(err, repository.ErrWebhookNotFound):
+		s.respondError(w, http.StatusNotFound, "Webhook subscription not found", err.Error())
+	default:
+		s.respondError(w, http.StatusInternalServerError, "Failed to manage webhook subscription", err.Error())
+	}
+}
+
+func newWebhookResponse(sub *repository.WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		ID:                  sub.ID,
+		RestaurantID:        sub.RestaurantID,
+		URL:                 sub.URL,
+		EventTypes:          sub.EventTypes,
+		Active:              sub.Active,
+		ConsecutiveFailures: sub.ConsecutiveFailures,
+		DisabledAt:          sub.DisabledAt,
+		DisabledReason:      sub.DisabledReason,
+		CreatedAt:           sub.CreatedAt,
+		UpdatedAt:           sub.UpdatedAt,
+	}
+}
+
+func newWebhookDeliveryResponse(d *repository.WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		ID:             d.ID,
+		EventID:        d.EventID,
+		EventType:      string(d.EventType),
+		OrderID:        d.OrderID,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		LastAttemptAt:  d.LastAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		Payload:        d.Payload,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == repository.WebhookDeliveryPending {
+		resp.NextAttemptAt = &d.NextAttemptAt
+	}
+	return resp
+}
@@ -1,66 +1,352 @@
 package config
 
 import (
+	"database/sql"
+	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
+	"github.com/dmehra2102/order-management-platform/internal/eventpb"
+	"github.com/dmehra2102/order-management-platform/internal/kafka"
+	"github.com/dmehra2102/order-management-platform/internal/rules"
+	"github.com/dmehra2102/order-management-platform/internal/schema"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+	"github.com/dmehra2102/order-management-platform/internal/webhook"
+	"go.yaml.in/yaml/v2"
 )
 
+// Config is the configuration shared by every binary. It is built in layers:
+// Default, then the YAML file named by --config or CONFIG_FILE, then
+// environment variables. Secrets may also be read from the file named by the
+// matching *_FILE variable or key.
+//
+// See config/order-platform.yaml for every key and its environment variable.
 type Config struct {
-	// Server
-	HTTPPort string
-	HTTPHost string
+	Environment string `yaml:"environment"`
+	LogLevel    string `yaml:"log_level"`
+
+	HTTP        HTTPConfig        `yaml:"http"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Kafka       KafkaConfig       `yaml:"kafka"`
+	Outbox      OutboxConfig      `yaml:"outbox"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Metrics     MetricsConfig     `yaml:"metrics"`
+	Tracing     tracing.Config    `yaml:"tracing"`
+	Validation  ValidationConfig  `yaml:"validation"`
+	Consistency ConsistencyConfig `yaml:"consistency"`
+}
+
+type HTTPConfig struct {
+	Host            string        `yaml:"host"`
+	Port            string        `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// Presented by the API gateway with the caller identities it forwards.
+	// Required outside development, where callers may otherwise name themselves.
+	GatewayToken     string `yaml:"gateway_token"`
+	GatewayTokenFile string `yaml:"gateway_token_file"`
+}
+
+type DatabaseConfig struct {
+	Host         string `yaml:"host"`
+	Port         string `yaml:"port"`
+	User         string `yaml:"user"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+	Name         string `yaml:"name"`
+	SSLMode      string `yaml:"ssl_mode"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// Bounds the connectivity check made at startup
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+}
 
-	// Database
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	DBSSLMode  string
+type KafkaConfig struct {
+	// Comma separated broker list such as "host1:9092,host2:9092"
+	Brokers       string `yaml:"brokers"`
+	ConsumerGroup string `yaml:"consumer_group"`
+	// Group of order-processor --mode=status
+	StatusConsumerGroup string `yaml:"status_consumer_group"`
+	// Group of order-processor --mode=webhooks
+	WebhookConsumerGroup string       `yaml:"webhook_consumer_group"`
+	Topics               kafka.Topics `yaml:"topics"`
+	// How published events are encoded, one of kafka.Encodings
+	Encoding string `yaml:"encoding"`
+	// How event data is serialized, one of kafka.Serializations
+	Serialization string `yaml:"serialization"`
+	// Schema registry of protobuf data; consumers read protobuf once it is set
+	SchemaRegistryFile string            `yaml:"schema_registry_file"`
+	FetchMinBytes      int               `yaml:"fetch_min_bytes"`
+	FetchMaxBytes      int               `yaml:"fetch_max_bytes"`
+	Retry              kafka.RetryPolicy `yaml:"retry"`
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+}
+
+// WebhooksConfig controls the webhook worker of order-processor
+// --mode=webhooks.
+type WebhooksConfig struct {
+	PollInterval time.Duration  `yaml:"poll_interval"`
+	BatchSize    int            `yaml:"batch_size"`
+	Delivery     webhook.Policy `yaml:"delivery"`
+}
+
+type IdempotencyConfig struct {
+	// How long Idempotency-Key values of order creation are remembered
+	KeyTTL        time.Duration `yaml:"key_ttl"`
+	PurgeInterval time.Duration `yaml:"purge_interval"`
+}
 
-	// Kafka
-	KafkaBrokers string
+type MetricsConfig struct {
+	OpenOrdersInterval time.Duration `yaml:"open_orders_interval"`
+	// order-processor serves /metrics on http.host at this port
+	Port string `yaml:"port"`
+}
 
-	// Logging
-	Environment string
-	LogLevel    string
+// ValidationConfig holds the order validation rules, either inline or in a
+// separate rules file. With neither, rules.DefaultConfig applies.
+type ValidationConfig struct {
+	RulesFile string        `yaml:"rules_file"`
+	Rules     *rules.Config `yaml:"rules"`
 }
 
-func Load() *Config {
+type ConsistencyConfig struct {
+	BatchSize int `yaml:"batch_size"`
+}
+
+// Default returns the built-in settings. They suit local development, except
+// that no database password is set.
+func Default() *Config {
 	return &Config{
-		HTTPPort:     getEnv("HTTP_PORT", "8080"),
-		HTTPHost:     getEnv("HTTP_HOST", "0.0.0.0"),
-		DBHost:       getEnv("DB_HOST", "localhost"),
-		DBPort:       getEnv("DB_PORT", "5432"),
-		DBUser:       getEnv("DB_USER", "orderuser"),
-		DBPassword:   getEnv("DB_PASSWORD", "orderpass123"),
-		DBName:       getEnv("DB_NAME", "order_db"),
-		DBSSLMode:    getEnv("DB_SSL_MODE", "disable"),
-		KafkaBrokers: getEnv("KAFKA_BROKERS", "localhost:9092,localhost:9094"),
-		Environment:  getEnv("ENV", "development"),
-		LogLevel:     getEnv("LOG_LEVEL", "INFO"),
+		Environment: "development",
+		LogLevel:    "INFO",
+		HTTP: HTTPConfig{
+			Host:            "0.0.0.0",
+			Port:            "8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            "5432",
+			User:            "orderuser",
+			Name:            "order_db",
+			SSLMode:         "disable",
+			MaxOpenConns:    20,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnectTimeout:  5 * time.Second,
+		},
+		Kafka: KafkaConfig{
+			Brokers:              "localhost:9092,localhost:9094",
+			ConsumerGroup:        "order-processor-group",
+			StatusConsumerGroup:  "order-status-group",
+			WebhookConsumerGroup: "order-webhook-group",
+			Topics:               kafka.DefaultTopics(),
+			Encoding:             kafka.EncodingEnvelope,
+			Serialization:        kafka.SerializationJSON,
+			FetchMinBytes:        10e3,
+			FetchMaxBytes:        10e6,
+			Retry:                kafka.DefaultRetryPolicy(),
+		},
+		Outbox: OutboxConfig{
+			PollInterval: time.Second,
+			BatchSize:    100,
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval: time.Second,
+			BatchSize:    50,
+			Delivery:     webhook.DefaultPolicy(),
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL:        24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Metrics: MetricsConfig{
+			OpenOrdersInterval: 15 * time.Second,
+			Port:               "9100",
+		},
+		Tracing: tracing.DefaultConfig(),
+		Consistency: ConsistencyConfig{
+			BatchSize: 500,
+		},
+	}
+}
+
+// Load builds the configuration from Default, the YAML file at path if any,
+// and the environment, and validates the result.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.readSecrets(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// MustLoad parses the --config and --print-config flags along with any flags
+// the binary registered on flag.CommandLine, and loads the configuration. It
+// exits on invalid configuration, and after printing it with --print-config.
+func MustLoad() *Config {
+	path := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	cfg, err := Load(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	return cfg
+}
+
+// readSecrets replaces secrets configured as file paths with the contents of
+// those files.
+func (c *Config) readSecrets() error {
+	if err := readSecret("database password", &c.Database.Password, c.Database.PasswordFile); err != nil {
+		return err
+	}
+	return readSecret("http gateway token", &c.HTTP.GatewayToken, c.HTTP.GatewayTokenFile)
+}
+
+func readSecret(name string, value *string, file string) error {
+	if file == "" {
+		return nil
+	}
+	if *value != "" {
+		return fmt.Errorf("%s and %s file are both set", name, name)
 	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("read %s file: %w", name, err)
+	}
+	*value = strings.TrimRight(string(data), "\r\n")
+	return nil
+}
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of c with secrets masked.
+func (c *Config) Redacted() *Config {
+	out := *c
+	if out.Database.Password != "" {
+		out.Database.Password = redacted
+	}
+	if out.HTTP.GatewayToken != "" {
+		out.HTTP.GatewayToken = redacted
+	}
+	return &out
+}
+
+// Development reports whether c configures a development environment, where
+// the API trusts callers to name themselves.
+func (c *Config) Development() bool {
+	return c.Environment == "development"
+}
+
+// Print writes the configuration as YAML with secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
 }
 
 func (c *Config) DatabaseURL() string {
-	return fmt.Sprintf(
-		"postgresql://%s:%s@%s:%s/%s?sslmode=%s",
-		c.DBUser,
-		c.DBPassword,
-		c.DBHost,
-		c.DBPort,
-		c.DBName,
-		c.DBSSLMode,
-	)
+	u := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(c.Database.User, c.Database.Password),
+		Host:   c.Database.Host + ":" + c.Database.Port,
+		Path:   "/" + c.Database.Name,
+	}
+	u.RawQuery = url.Values{"sslmode": {c.Database.SSLMode}}.Encode()
+	return u.String()
+}
+
+// ConfigurePool applies the connection pool settings to db.
+func (c DatabaseConfig) ConfigurePool(db *sql.DB) {
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
 }
 
 func (c *Config) KafkaAddress() string {
-	return c.KafkaBrokers
+	return c.Kafka.Brokers
+}
+
+// Broker connects to the configured Kafka cluster.
+func (c KafkaConfig) Broker() *kafka.KafkaBroker {
+	broker := kafka.NewKafkaBroker(c.Brokers)
+	broker.MinBytes = c.FetchMinBytes
+	broker.MaxBytes = c.FetchMaxBytes
+	return broker
+}
+
+// Serializers returns the serializer producers publish with, and those that
+// consumers read with besides JSON: protobuf, once a schema registry is set.
+func (c KafkaConfig) Serializers() (envelope.Serializer, []envelope.Serializer, error) {
+	var publish envelope.Serializer = envelope.JSON{}
+	if c.SchemaRegistryFile == "" {
+		return publish, nil, nil
+	}
+
+	registry, err := schema.OpenRegistry(c.SchemaRegistryFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	protobuf := eventpb.NewSerializer(registry)
+	if c.Serialization == kafka.SerializationProtobuf {
+		publish = protobuf
+	}
+	return publish, []envelope.Serializer{protobuf}, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// LoadRules returns the configured validation rules.
+func (c ValidationConfig) LoadRules() (rules.Config, error) {
+	if c.Rules != nil {
+		return *c.Rules, nil
 	}
-	return defaultValue
+	return rules.LoadConfig(c.RulesFile)
 }
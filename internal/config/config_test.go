@@ -0,0 +1,242 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Default().Validate() = %v", err)
+	}
+}
+
+func TestLoadLayersFileThenEnv(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+log_level: DEBUG
+http:
+  port: "9090"
+  read_timeout: 5s
+kafka:
+  consumer_group: processors
+  topics:
+    orders: orders-v2
+outbox:
+  batch_size: 10
+`)
+	t.Setenv("HTTP_PORT", "9191")
+	t.Setenv("OUTBOX_POLL_INTERVAL", "250ms")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.LogLevel != "DEBUG" || cfg.HTTP.ReadTimeout != 5*time.Second || cfg.Kafka.ConsumerGroup != "processors" || cfg.Outbox.BatchSize != 10 {
+		t.Errorf("file settings not applied: %+v", cfg)
+	}
+	if cfg.HTTP.Port != "9191" || cfg.Outbox.PollInterval != 250*time.Millisecond {
+		t.Errorf("env settings not applied: port %s, poll interval %s", cfg.HTTP.Port, cfg.Outbox.PollInterval)
+	}
+	if cfg.Kafka.Topics.Orders != "orders-v2" || cfg.Kafka.Topics.OrderStatus != "order-status" {
+		t.Errorf("topics = %+v, want orders-v2 with the default status topic", cfg.Kafka.Topics)
+	}
+	if cfg.HTTP.WriteTimeout != Default().HTTP.WriteTimeout {
+		t.Errorf("unset write_timeout = %s, want the default", cfg.HTTP.WriteTimeout)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeFile(t, "config.yaml", "http:\n  prot: \"8080\"\n")
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Fatalf("Load = %v, want an error naming the unknown key", err)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+log_level: VERBOSE
+http:
+  port: "http"
+kafka:
+  topics:
+    dead_letter: order-status
+outbox:
+  batch_size: 0
+`)
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("Load succeeded")
+	}
+	for _, key := range []string{"log_level", "http.port", "kafka.topics.dead_letter", "outbox.batch_size"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error does not mention %s:\n%v", key, err)
+		}
+	}
+}
+
+func TestLoadRejectsMalformedEnv(t *testing.T) {
+	t.Setenv("OUTBOX_BATCH_SIZE", "lots")
+	t.Setenv("HTTP_READ_TIMEOUT", "15")
+
+	_, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), "OUTBOX_BATCH_SIZE") || !strings.Contains(err.Error(), "HTTP_READ_TIMEOUT") {
+		t.Fatalf("Load = %v, want errors for both variables", err)
+	}
+}
+
+func TestLoadReadsSecretFiles(t *testing.T) {
+	t.Setenv("DB_PASSWORD_FILE", writeFile(t, "password", "s3cret\n"))
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Database.Password != "s3cret" {
+		t.Errorf("password = %q, want the file contents without the newline", cfg.Database.Password)
+	}
+
+	t.Setenv("DB_PASSWORD", "other")
+	if _, err := Load(""); err == nil {
+		t.Error("Load accepted both DB_PASSWORD and DB_PASSWORD_FILE")
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "s3cret"
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if strings.Contains(out.String(), "s3cret") || !strings.Contains(out.String(), redacted) {
+		t.Errorf("printed config does not redact the password:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "read_timeout: 15s") {
+		t.Errorf("durations are not printed readably:\n%s", out.String())
+	}
+	if cfg.Database.Password != "s3cret" {
+		t.Error("Print changed the configuration")
+	}
+}
+
+func TestLoadValidatesTracing(t *testing.T) {
+	t.Setenv("TRACING_EXPORTER", "file")
+	t.Setenv("TRACING_SAMPLE_RATIO", "1.5")
+
+	_, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), "tracing.file") || !strings.Contains(err.Error(), "tracing.sample_ratio") {
+		t.Fatalf("Load = %v, want errors for the trace file and sample ratio", err)
+	}
+
+	t.Setenv("TRACING_FILE", "traces.jsonl")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Tracing.File != "traces.jsonl" || cfg.Tracing.SampleRatio != 0.25 {
+		t.Errorf("tracing = %+v, want the file exporter at a quarter of traces", cfg.Tracing)
+	}
+}
+
+func TestLoadValidatesEncoding(t *testing.T) {
+	t.Setenv("KAFKA_ENCODING", "avro")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "kafka.encoding") {
+		t.Fatalf("Load = %v, want an error for the encoding", err)
+	}
+
+	t.Setenv("KAFKA_ENCODING", "cloudevents-binary")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Kafka.Encoding != "cloudevents-binary" {
+		t.Errorf("encoding = %q, want cloudevents-binary", cfg.Kafka.Encoding)
+	}
+}
+
+func TestLoadValidatesProtobufSerialization(t *testing.T) {
+	t.Setenv("KAFKA_SERIALIZATION", "protobuf")
+	_, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), "kafka.encoding") || !strings.Contains(err.Error(), "kafka.schema_registry_file") {
+		t.Fatalf("Load = %v, want errors for the encoding and schema registry", err)
+	}
+
+	t.Setenv("KAFKA_ENCODING", "cloudevents-binary")
+	t.Setenv("KAFKA_SCHEMA_REGISTRY_FILE", filepath.Join(t.TempDir(), "schemas.json"))
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	publish, accepted, err := cfg.Kafka.Serializers()
+	if err != nil {
+		t.Fatalf("Serializers: %v", err)
+	}
+	if publish.ContentType() != "application/protobuf" || len(accepted) != 1 {
+		t.Errorf("publishing %s data and reading %d more, want protobuf and 1", publish.ContentType(), len(accepted))
+	}
+}
+
+func TestLoadValidatesWebhooks(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+kafka:
+  consumer_group: processors
+  webhook_consumer_group: processors
+webhooks:
+  delivery:
+    initial_backoff: 1m
+    max_backoff: 10s
+`)
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "0")
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("Load succeeded")
+	}
+	for _, key := range []string{"kafka.webhook_consumer_group", "webhooks.delivery.max_backoff", "webhooks.delivery.max_attempts"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error does not mention %s:\n%v", key, err)
+		}
+	}
+}
+
+func TestLoadRequiresGatewayTokenOutsideDevelopment(t *testing.T) {
+	t.Setenv("ENV", "production")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "http.gateway_token") {
+		t.Fatalf("Load = %v, want an error for the gateway token", err)
+	}
+
+	t.Setenv("HTTP_GATEWAY_TOKEN_FILE", writeFile(t, "gateway", "t0ken\n"))
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.HTTP.GatewayToken != "t0ken" {
+		t.Errorf("gateway token = %q, want the file contents without the newline", cfg.HTTP.GatewayToken)
+	}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if strings.Contains(out.String(), "t0ken") {
+		t.Errorf("printed config does not redact the gateway token:\n%s", out.String())
+	}
+}
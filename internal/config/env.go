@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// applyEnv overrides settings with the environment variables that are set.
+// Malformed values are reported together.
+func (c *Config) applyEnv() error {
+	var errs []error
+
+	str := func(key string, dst *string) {
+		if value := os.Getenv(key); value != "" {
+			*dst = value
+		}
+	}
+	num := func(key string, dst *int) {
+		value := os.Getenv(key)
+		if value == "" {
+			return
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q is not an integer", key, value))
+			return
+		}
+		*dst = n
+	}
+	ratio := func(key string, dst *float64) {
+		value := os.Getenv(key)
+		if value == "" {
+			return
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q is not a number", key, value))
+			return
+		}
+		*dst = f
+	}
+	dur := func(key string, dst *time.Duration) {
+		value := os.Getenv(key)
+		if value == "" {
+			return
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q is not a duration", key, value))
+			return
+		}
+		*dst = d
+	}
+	// secret applies key, or key_FILE naming a file that holds the value.
+	// Either replaces both the value and the file set by earlier layers.
+	secret := func(key string, value, file *string) {
+		v, f := os.Getenv(key), os.Getenv(key+"_FILE")
+		switch {
+		case v != "" && f != "":
+			errs = append(errs, fmt.Errorf("%s and %s_FILE are both set", key, key))
+		case v != "":
+			*value, *file = v, ""
+		case f != "":
+			*value, *file = "", f
+		}
+	}
+
+	str("ENV", &c.Environment)
+	str("LOG_LEVEL", &c.LogLevel)
+
+	str("HTTP_HOST", &c.HTTP.Host)
+	str("HTTP_PORT", &c.HTTP.Port)
+	dur("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout)
+	dur("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout)
+	dur("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout)
+	dur("HTTP_SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout)
+	secret("HTTP_GATEWAY_TOKEN", &c.HTTP.GatewayToken, &c.HTTP.GatewayTokenFile)
+
+	str("DB_HOST", &c.Database.Host)
+	str("DB_PORT", &c.Database.Port)
+	str("DB_USER", &c.Database.User)
+	str("DB_NAME", &c.Database.Name)
+	str("DB_SSL_MODE", &c.Database.SSLMode)
+	secret("DB_PASSWORD", &c.Database.Password, &c.Database.PasswordFile)
+	num("DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns)
+	num("DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns)
+	dur("DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime)
+	dur("DB_CONNECT_TIMEOUT", &c.Database.ConnectTimeout)
+
+	str("KAFKA_BROKERS", &c.Kafka.Brokers)
+	str("KAFKA_CONSUMER_GROUP", &c.Kafka.ConsumerGroup)
+	str("KAFKA_STATUS_CONSUMER_GROUP", &c.Kafka.StatusConsumerGroup)
+	str("KAFKA_WEBHOOK_CONSUMER_GROUP", &c.Kafka.WebhookConsumerGroup)
+	str("KAFKA_TOPIC_ORDERS", &c.Kafka.Topics.Orders)
+	str("KAFKA_TOPIC_ORDER_STATUS", &c.Kafka.Topics.OrderStatus)
+	str("KAFKA_TOPIC_DEAD_LETTER", &c.Kafka.Topics.DeadLetter)
+	str("KAFKA_ENCODING", &c.Kafka.Encoding)
+	str("KAFKA_SERIALIZATION", &c.Kafka.Serialization)
+	str("KAFKA_SCHEMA_REGISTRY_FILE", &c.Kafka.SchemaRegistryFile)
+	num("KAFKA_FETCH_MIN_BYTES", &c.Kafka.FetchMinBytes)
+	num("KAFKA_FETCH_MAX_BYTES", &c.Kafka.FetchMaxBytes)
+	num("CONSUMER_MAX_ATTEMPTS", &c.Kafka.Retry.MaxAttempts)
+	dur("CONSUMER_INITIAL_BACKOFF", &c.Kafka.Retry.InitialBackoff)
+	dur("CONSUMER_MAX_BACKOFF", &c.Kafka.Retry.MaxBackoff)
+
+	dur("OUTBOX_POLL_INTERVAL", &c.Outbox.PollInterval)
+	num("OUTBOX_BATCH_SIZE", &c.Outbox.BatchSize)
+
+	dur("WEBHOOK_POLL_INTERVAL", &c.Webhooks.PollInterval)
+	num("WEBHOOK_BATCH_SIZE", &c.Webhooks.BatchSize)
+	dur("WEBHOOK_TIMEOUT", &c.Webhooks.Delivery.Timeout)
+	num("WEBHOOK_MAX_ATTEMPTS", &c.Webhooks.Delivery.MaxAttempts)
+	dur("WEBHOOK_INITIAL_BACKOFF", &c.Webhooks.Delivery.InitialBackoff)
+	dur("WEBHOOK_MAX_BACKOFF", &c.Webhooks.Delivery.MaxBackoff)
+	num("WEBHOOK_DISABLE_AFTER", &c.Webhooks.Delivery.DisableAfter)
+
+	dur("IDEMPOTENCY_KEY_TTL", &c.Idempotency.KeyTTL)
+	dur("IDEMPOTENCY_PURGE_INTERVAL", &c.Idempotency.PurgeInterval)
+
+	dur("OPEN_ORDERS_INTERVAL", &c.Metrics.OpenOrdersInterval)
+	str("METRICS_PORT", &c.Metrics.Port)
+
+	str("TRACING_EXPORTER", &c.Tracing.Exporter)
+	str("TRACING_ENDPOINT", &c.Tracing.Endpoint)
+	str("TRACING_FILE", &c.Tracing.File)
+	ratio("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	if value := os.Getenv("RULES_FILE"); value != "" {
+		c.Validation.RulesFile, c.Validation.Rules = value, nil
+	}
+
+	num("CONSISTENCY_BATCH_SIZE", &c.Consistency.BatchSize)
+
+	return errors.Join(errs...)
+}
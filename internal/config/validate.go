@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/kafka"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+)
+
+var (
+	logLevels = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+	sslModes  = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+)
+
+// Validate checks every setting and reports all problems at once, naming
+// each by its YAML key.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+	required := func(key, value string) {
+		if strings.TrimSpace(value) == "" {
+			fail(key, "must be set")
+		}
+	}
+	positive := func(key string, value int) {
+		if value <= 0 {
+			fail(key, "must be positive, got %d", value)
+		}
+	}
+	positiveDuration := func(key string, value time.Duration) {
+		if value <= 0 {
+			fail(key, "must be a positive duration, got %s", value)
+		}
+	}
+	port := func(key, value string) {
+		if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+			fail(key, "%q is not a port number", value)
+		}
+	}
+
+	if !slices.Contains(logLevels, c.LogLevel) {
+		fail("log_level", "%q is not one of %s", c.LogLevel, strings.Join(logLevels, ", "))
+	}
+
+	port("http.port", c.HTTP.Port)
+	positiveDuration("http.read_timeout", c.HTTP.ReadTimeout)
+	positiveDuration("http.write_timeout", c.HTTP.WriteTimeout)
+	positiveDuration("http.idle_timeout", c.HTTP.IdleTimeout)
+	positiveDuration("http.shutdown_timeout", c.HTTP.ShutdownTimeout)
+	if !c.Development() && c.HTTP.GatewayToken == "" {
+		fail("http.gateway_token", "must be set outside development")
+	}
+
+	required("database.host", c.Database.Host)
+	port("database.port", c.Database.Port)
+	required("database.user", c.Database.User)
+	required("database.name", c.Database.Name)
+	if !slices.Contains(sslModes, c.Database.SSLMode) {
+		fail("database.ssl_mode", "%q is not one of %s", c.Database.SSLMode, strings.Join(sslModes, ", "))
+	}
+	positive("database.max_open_conns", c.Database.MaxOpenConns)
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		fail("database.max_idle_conns", "must be between 0 and max_open_conns, got %d", c.Database.MaxIdleConns)
+	}
+	positiveDuration("database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+	positiveDuration("database.connect_timeout", c.Database.ConnectTimeout)
+
+	required("kafka.brokers", c.Kafka.Brokers)
+	required("kafka.consumer_group", c.Kafka.ConsumerGroup)
+	required("kafka.status_consumer_group", c.Kafka.StatusConsumerGroup)
+	if c.Kafka.StatusConsumerGroup == c.Kafka.ConsumerGroup {
+		fail("kafka.status_consumer_group", "must differ from consumer_group")
+	}
+	required("kafka.webhook_consumer_group", c.Kafka.WebhookConsumerGroup)
+	if c.Kafka.WebhookConsumerGroup == c.Kafka.ConsumerGroup || c.Kafka.WebhookConsumerGroup == c.Kafka.StatusConsumerGroup {
+		fail("kafka.webhook_consumer_group", "must differ from consumer_group and status_consumer_group")
+	}
+	seen := make(map[string]bool)
+	for _, topic := range [][2]string{
+		{"kafka.topics.orders", c.Kafka.Topics.Orders},
+		{"kafka.topics.order_status", c.Kafka.Topics.OrderStatus},
+		{"kafka.topics.dead_letter", c.Kafka.Topics.DeadLetter},
+	} {
+		key, name := topic[0], topic[1]
+		required(key, name)
+		if name != "" && seen[name] {
+			fail(key, "topic %q is used twice", name)
+		}
+		seen[name] = true
+	}
+	if !slices.Contains(kafka.Encodings, c.Kafka.Encoding) {
+		fail("kafka.encoding", "%q is not one of %s", c.Kafka.Encoding, strings.Join(kafka.Encodings, ", "))
+	}
+	if !slices.Contains(kafka.Serializations, c.Kafka.Serialization) {
+		fail("kafka.serialization", "%q is not one of %s", c.Kafka.Serialization, strings.Join(kafka.Serializations, ", "))
+	}
+	if c.Kafka.Serialization == kafka.SerializationProtobuf {
+		if c.Kafka.Encoding != kafka.EncodingCloudEventsBinary {
+			fail("kafka.encoding", "must be %s for protobuf data, got %q", kafka.EncodingCloudEventsBinary, c.Kafka.Encoding)
+		}
+		required("kafka.schema_registry_file", c.Kafka.SchemaRegistryFile)
+	}
+	positive("kafka.fetch_min_bytes", c.Kafka.FetchMinBytes)
+	if c.Kafka.FetchMaxBytes < c.Kafka.FetchMinBytes {
+		fail("kafka.fetch_max_bytes", "must be at least fetch_min_bytes, got %d", c.Kafka.FetchMaxBytes)
+	}
+	positive("kafka.retry.max_attempts", c.Kafka.Retry.MaxAttempts)
+	positiveDuration("kafka.retry.initial_backoff", c.Kafka.Retry.InitialBackoff)
+	if c.Kafka.Retry.MaxBackoff < c.Kafka.Retry.InitialBackoff {
+		fail("kafka.retry.max_backoff", "must be at least initial_backoff, got %s", c.Kafka.Retry.MaxBackoff)
+	}
+
+	positiveDuration("outbox.poll_interval", c.Outbox.PollInterval)
+	positive("outbox.batch_size", c.Outbox.BatchSize)
+
+	positiveDuration("webhooks.poll_interval", c.Webhooks.PollInterval)
+	positive("webhooks.batch_size", c.Webhooks.BatchSize)
+	positiveDuration("webhooks.delivery.timeout", c.Webhooks.Delivery.Timeout)
+	positive("webhooks.delivery.max_attempts", c.Webhooks.Delivery.MaxAttempts)
+	positiveDuration("webhooks.delivery.initial_backoff", c.Webhooks.Delivery.InitialBackoff)
+	if c.Webhooks.Delivery.MaxBackoff < c.Webhooks.Delivery.InitialBackoff {
+		fail("webhooks.delivery.max_backoff", "must be at least initial_backoff, got %s", c.Webhooks.Delivery.MaxBackoff)
+	}
+	positive("webhooks.delivery.disable_after", c.Webhooks.Delivery.DisableAfter)
+
+	positiveDuration("idempotency.key_ttl", c.Idempotency.KeyTTL)
+	positiveDuration("idempotency.purge_interval", c.Idempotency.PurgeInterval)
+
+	positiveDuration("metrics.open_orders_interval", c.Metrics.OpenOrdersInterval)
+	port("metrics.port", c.Metrics.Port)
+
+	if !slices.Contains(tracing.Exporters, c.Tracing.Exporter) {
+		fail("tracing.exporter", "%q is not one of %s", c.Tracing.Exporter, strings.Join(tracing.Exporters, ", "))
+	}
+	if c.Tracing.Exporter == tracing.ExporterFile {
+		required("tracing.file", c.Tracing.File)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	if c.Validation.RulesFile != "" && c.Validation.Rules != nil {
+		fail("validation", "rules_file and rules are both set")
+	}
+
+	positive("consistency.batch_size", c.Consistency.BatchSize)
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+)
+
+// Drift is one field whose value in the orders table differs from the value
+// obtained by replaying the order's events.
+type Drift struct {
+	OrderID  string `json:"order_id"`
+	Field    string `json:"field"`
+	Stored   string `json:"stored"`
+	Replayed string `json:"replayed"`
+}
+
+type Report struct {
+	Checked int     `json:"checked"`
+	Drifted int     `json:"drifted"`
+	Drifts  []Drift `json:"drifts,omitempty"`
+}
+
+// Checker compares every order row against the state replayed from its events.
+type Checker struct {
+	repo      *repository.OrderRepository
+	logger    *logger.Logger
+	batchSize int
+}
+
+func NewChecker(repo *repository.OrderRepository, l *logger.Logger, batchSize int) *Checker {
+	return &Checker{
+		repo:      repo,
+		logger:    l,
+		batchSize: batchSize,
+	}
+}
+
+// CheckAll checks all orders and reports the ones that drifted.
+func (c *Checker) CheckAll(ctx context.Context) (Report, error) {
+	var (
+		report Report
+		after  string
+	)
+
+	for {
+		ids, err := c.repo.OrderIDs(ctx, after, c.batchSize)
+		if err != nil {
+			return report, err
+		}
+
+		for _, id := range ids {
+			drifts, err := c.Check(ctx, id)
+			if err != nil {
+				return report, err
+			}
+
+			report.Checked++
+			if len(drifts) > 0 {
+				report.Drifted++
+				report.Drifts = append(report.Drifts, drifts...)
+				c.logger.Warn("Order drifted from its events", map[string]any{
+					"order_id": id,
+					"fields":   len(drifts),
+				})
+			}
+		}
+
+		if len(ids) < c.batchSize {
+			return report, nil
+		}
+		after = ids[len(ids)-1]
+	}
+}
+
+// Check compares one order row with its replayed state. An order whose events
+// cannot be replayed is reported as drift on the "events" field.
+func (c *Checker) Check(ctx context.Context, orderID string) ([]Drift, error) {
+	stored, err := c.repo.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("load order %s: %w", orderID, err)
+	}
+
+	events, err := c.repo.OrderEvents(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed, err := domain.ReplayOrder(events)
+	if err != nil {
+		return []Drift{{OrderID: orderID, Field: "events", Replayed: err.Error()}}, nil
+	}
+
+	return compare(stored, replayed), nil
+}
+
+func compare(stored, replayed *domain.Order) []Drift {
+	var drifts []Drift
+	check := func(field, s, r string) {
+		if s != r {
+			drifts = append(drifts, Drift{OrderID: stored.ID, Field: field, Stored: s, Replayed: r})
+		}
+	}
+
+	check("user_id", stored.UserID, replayed.UserID)
+	check("restaurant_id", stored.RestaurantID, replayed.RestaurantID)
+	check("status", string(stored.Status), string(replayed.Status))
+	check("version", fmt.Sprint(stored.Version), fmt.Sprint(replayed.Version))
+	check("total_amount", stored.TotalAmount.String(), replayed.TotalAmount.String())
+	check("currency", stored.TotalAmount.Currency, replayed.TotalAmount.Currency)
+	check("item_count", fmt.Sprint(len(stored.Items)), fmt.Sprint(len(replayed.Items)))
+	check("created_at", formatTime(&stored.CreatedAt), formatTime(&replayed.CreatedAt))
+	check("updated_at", formatTime(&stored.UpdatedAt), formatTime(&replayed.UpdatedAt))
+	check("accepted_at", formatTime(stored.AcceptedAt), formatTime(replayed.AcceptedAt))
+	check("preparing_at", formatTime(stored.PreparingAt), formatTime(replayed.PreparingAt))
+	check("ready_at", formatTime(stored.ReadyAt), formatTime(replayed.ReadyAt))
+	check("picked_up_at", formatTime(stored.PickedUpAt), formatTime(replayed.PickedUpAt))
+	check("out_for_delivery_at", formatTime(stored.OutForDeliveryAt), formatTime(replayed.OutForDeliveryAt))
+	check("delivered_at", formatTime(stored.DeliveredAt), formatTime(replayed.DeliveredAt))
+
+	return drifts
+}
+
+// formatTime renders t at the microsecond precision of Postgres timestamps.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
@@ -0,0 +1,82 @@
+package consistency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+func deliveredOrder() *domain.Order {
+	at := func(minutes int) *time.Time {
+		t := time.Date(2026, 3, 14, 12, minutes, 0, 0, time.UTC)
+		return &t
+	}
+	return &domain.Order{
+		ID:               "order-1",
+		UserID:           "user-1",
+		RestaurantID:     "rest-1",
+		Items:            []domain.OrderItem{{ID: "line-1", ItemID: "item-1", Price: domain.NewMoney(24950, "INR"), Quantity: 2}},
+		TotalAmount:      domain.NewMoney(49900, "INR"),
+		Status:           domain.OrderStatusDelivered,
+		CreatedAt:        *at(0),
+		UpdatedAt:        *at(40),
+		AcceptedAt:       at(5),
+		PreparingAt:      at(10),
+		ReadyAt:          at(20),
+		PickedUpAt:       at(25),
+		OutForDeliveryAt: at(26),
+		DeliveredAt:      at(40),
+		Version:          8,
+	}
+}
+
+func TestCompareReportsEachDriftedField(t *testing.T) {
+	later := time.Date(2026, 3, 14, 13, 0, 0, 0, time.UTC)
+	tests := []struct {
+		field  string
+		mutate func(o *domain.Order)
+	}{
+		{"user_id", func(o *domain.Order) { o.UserID = "user-2" }},
+		{"restaurant_id", func(o *domain.Order) { o.RestaurantID = "rest-2" }},
+		{"status", func(o *domain.Order) { o.Status = domain.OrderStatusCancelled }},
+		{"version", func(o *domain.Order) { o.Version = 9 }},
+		{"total_amount", func(o *domain.Order) { o.TotalAmount.Amount = 49800 }},
+		{"currency", func(o *domain.Order) { o.TotalAmount.Currency = "" }},
+		{"item_count", func(o *domain.Order) { o.Items = nil }},
+		{"created_at", func(o *domain.Order) { o.CreatedAt = later }},
+		{"updated_at", func(o *domain.Order) { o.UpdatedAt = later }},
+		{"accepted_at", func(o *domain.Order) { o.AcceptedAt = &later }},
+		{"preparing_at", func(o *domain.Order) { o.PreparingAt = nil }},
+		{"ready_at", func(o *domain.Order) { o.ReadyAt = &later }},
+		{"picked_up_at", func(o *domain.Order) { o.PickedUpAt = nil }},
+		{"out_for_delivery_at", func(o *domain.Order) { o.OutForDeliveryAt = &later }},
+		{"delivered_at", func(o *domain.Order) { o.DeliveredAt = nil }},
+	}
+
+	for _, tt := range tests {
+		stored, replayed := deliveredOrder(), deliveredOrder()
+		tt.mutate(replayed)
+
+		drifts := compare(stored, replayed)
+		if len(drifts) != 1 || drifts[0].Field != tt.field || drifts[0].OrderID != "order-1" {
+			t.Errorf("%s changed: drifts = %+v, want exactly one on %s", tt.field, drifts, tt.field)
+			continue
+		}
+		if drifts[0].Stored == drifts[0].Replayed {
+			t.Errorf("%s changed: drift %+v shows equal values", tt.field, drifts[0])
+		}
+	}
+}
+
+func TestCompareIgnoresSubMicrosecondDifferences(t *testing.T) {
+	stored, replayed := deliveredOrder(), deliveredOrder()
+	// Postgres keeps microseconds; the replayed event times carry nanoseconds
+	replayed.UpdatedAt = replayed.UpdatedAt.Add(999 * time.Nanosecond)
+	nanos := replayed.AcceptedAt.Add(500 * time.Nanosecond).In(time.FixedZone("IST", 5*3600+1800))
+	replayed.AcceptedAt = &nanos
+
+	if drifts := compare(stored, replayed); len(drifts) != 0 {
+		t.Errorf("drifts = %+v, want none", drifts)
+	}
+}
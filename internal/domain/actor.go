@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+type ActorRole string
+
+const (
+	ActorCustomer   ActorRole = "customer"
+	ActorRestaurant ActorRole = "restaurant"
+	ActorSupport    ActorRole = "support"
+	ActorCourier    ActorRole = "courier"
+)
+
+// Actor identifies who asked for a change to an order.
+type Actor struct {
+	ID   string
+	Role ActorRole
+}
+
+func (a Actor) owns(o *Order) bool {
+	switch a.Role {
+	case ActorCustomer:
+		return a.ID != "" && a.ID == o.UserID
+	case ActorRestaurant:
+		return a.ID != "" && a.ID == o.RestaurantID
+	case ActorSupport:
+		return true
+	default:
+		return false
+	}
+}
+
+// cancellableBy lists the statuses from which each role may cancel an order.
+var cancellableBy = map[ActorRole][]OrderStatus{
+	ActorCustomer:   {OrderStatusPending, OrderStatusConfirmed},
+	ActorRestaurant: {OrderStatusPending, OrderStatusConfirmed, OrderStatusAccepted},
+	ActorSupport:    {OrderStatusPending, OrderStatusConfirmed, OrderStatusAccepted, OrderStatusPreparing, OrderStatusReady},
+}
+
+// advancableBy lists the roles that may move an order to each fulfilment
+// status. Restaurants may only advance their own orders.
+var advancableBy = map[OrderStatus][]ActorRole{
+	OrderStatusAccepted:       {ActorRestaurant},
+	OrderStatusPreparing:      {ActorRestaurant},
+	OrderStatusReady:          {ActorRestaurant},
+	OrderStatusPickedUp:       {ActorCourier, ActorSupport},
+	OrderStatusOutForDelivery: {ActorCourier, ActorSupport},
+	OrderStatusDelivered:      {ActorCourier, ActorSupport},
+}
+
+func (a Actor) mayAdvance(o *Order, to OrderStatus) bool {
+	if !slices.Contains(advancableBy[to], a.Role) {
+		return false
+	}
+	return a.Role != ActorRestaurant || a.owns(o)
+}
+
+// ErrCancelNotPermitted matches every *CancelNotPermittedError with errors.Is.
+var ErrCancelNotPermitted = errors.New("cancellation not permitted")
+
+type CancelNotPermittedError struct {
+	OrderID string
+	Actor   Actor
+	Status  OrderStatus
+}
+
+func (e *CancelNotPermittedError) Error() string {
+	return fmt.Sprintf("%s %q may not cancel order %s in status %s", e.Actor.Role, e.Actor.ID, e.OrderID, e.Status)
+}
+
+func (e *CancelNotPermittedError) Is(target error) bool {
+	return target == ErrCancelNotPermitted
+}
+
+// ErrAdvanceNotPermitted matches every *AdvanceNotPermittedError with
+// errors.Is.
+var ErrAdvanceNotPermitted = errors.New("fulfilment step not permitted")
+
+type AdvanceNotPermittedError struct {
+	OrderID string
+	Actor   Actor
+	To      OrderStatus
+}
+
+func (e *AdvanceNotPermittedError) Error() string {
+	return fmt.Sprintf("%s %q may not move order %s to %s", e.Actor.Role, e.Actor.ID, e.OrderID, e.To)
+}
+
+func (e *AdvanceNotPermittedError) Is(target error) bool {
+	return target == ErrAdvanceNotPermitted
+}
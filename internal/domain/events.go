@@ -9,13 +9,20 @@ import (
 type EventType string
 
 const (
-	OrderCreatedEventType   EventType = "OrderCreated"
-	OrderConfirmedEventType EventType = "OrderConfirmed"
-	OrderFailedEventType    EventType = "OrderFailed"
-	OrderCancelledEventType EventType = "OrderCancelled"
+	OrderCreatedEventType        EventType = "OrderCreated"
+	OrderConfirmedEventType      EventType = "OrderConfirmed"
+	OrderFailedEventType         EventType = "OrderFailed"
+	OrderCancelledEventType      EventType = "OrderCancelled"
+	OrderAcceptedEventType       EventType = "OrderAccepted"
+	OrderPreparingEventType      EventType = "OrderPreparing"
+	OrderReadyEventType          EventType = "OrderReady"
+	OrderPickedUpEventType       EventType = "OrderPickedUp"
+	OrderOutForDeliveryEventType EventType = "OrderOutForDelivery"
+	OrderDeliveredEventType      EventType = "OrderDelivered"
 )
 
 type Event interface {
+	ID() string
 	AggregateID() string
 	EventType() EventType
 	Timestamp() time.Time
@@ -27,10 +34,11 @@ type OrderCreatedEvent struct {
 	UserID       string      `json:"user_id"`
 	RestaurantID string      `json:"restaurant_id"`
 	Items        []OrderItem `json:"items"`
-	TotalAmount  float64     `json:"total_amount"`
+	TotalAmount  Money       `json:"total_amount"`
 	CreatedAt    time.Time   `json:"created_at"`
 }
 
+func (e OrderCreatedEvent) ID() string           { return e.EventID }
 func (e OrderCreatedEvent) AggregateID() string  { return e.OrderID }
 func (e OrderCreatedEvent) EventType() EventType { return OrderCreatedEventType }
 func (e OrderCreatedEvent) Timestamp() time.Time { return e.CreatedAt }
@@ -41,6 +49,7 @@ type OrderConfirmedEvent struct {
 	ConfirmedAt time.Time `json:"confirmed_at"`
 }
 
+func (e OrderConfirmedEvent) ID() string           { return e.EventID }
 func (e OrderConfirmedEvent) AggregateID() string  { return e.OrderID }
 func (e OrderConfirmedEvent) EventType() EventType { return OrderConfirmedEventType }
 func (e OrderConfirmedEvent) Timestamp() time.Time { return e.ConfirmedAt }
@@ -52,10 +61,25 @@ type OrderFailedEvent struct {
 	FailedAt time.Time `json:"failed_at"`
 }
 
+func (e OrderFailedEvent) ID() string           { return e.EventID }
 func (e OrderFailedEvent) AggregateID() string  { return e.OrderID }
 func (e OrderFailedEvent) EventType() EventType { return OrderFailedEventType }
 func (e OrderFailedEvent) Timestamp() time.Time { return e.FailedAt }
 
+type OrderCancelledEvent struct {
+	EventID         string    `json:"event_id"`
+	OrderID         string    `json:"order_id"`
+	Reason          string    `json:"reason"`
+	CancelledBy     string    `json:"cancelled_by"`
+	CancelledByRole ActorRole `json:"cancelled_by_role"`
+	CancelledAt     time.Time `json:"cancelled_at"`
+}
+
+func (e OrderCancelledEvent) ID() string           { return e.EventID }
+func (e OrderCancelledEvent) AggregateID() string  { return e.OrderID }
+func (e OrderCancelledEvent) EventType() EventType { return OrderCancelledEventType }
+func (e OrderCancelledEvent) Timestamp() time.Time { return e.CancelledAt }
+
 func NewOrderCreatedEvent(order *Order) OrderCreatedEvent {
 	return OrderCreatedEvent{
 		EventID:      uuid.New().String(),
@@ -84,3 +108,14 @@ func NewOrderFailedEvent(orderID, reason string) OrderFailedEvent {
 		FailedAt: time.Now().UTC(),
 	}
 }
+
+func NewOrderCancelledEvent(orderID string, actor Actor, reason string) OrderCancelledEvent {
+	return OrderCancelledEvent{
+		EventID:         uuid.New().String(),
+		OrderID:         orderID,
+		Reason:          reason,
+		CancelledBy:     actor.ID,
+		CancelledByRole: actor.Role,
+		CancelledAt:     time.Now().UTC(),
+	}
+}
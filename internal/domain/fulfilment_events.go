@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OrderAcceptedEvent struct {
+	EventID      string    `json:"event_id"`
+	OrderID      string    `json:"order_id"`
+	RestaurantID string    `json:"restaurant_id"`
+	AcceptedAt   time.Time `json:"accepted_at"`
+}
+
+func (e OrderAcceptedEvent) ID() string           { return e.EventID }
+func (e OrderAcceptedEvent) AggregateID() string  { return e.OrderID }
+func (e OrderAcceptedEvent) EventType() EventType { return OrderAcceptedEventType }
+func (e OrderAcceptedEvent) Timestamp() time.Time { return e.AcceptedAt }
+
+type OrderPreparingEvent struct {
+	EventID     string    `json:"event_id"`
+	OrderID     string    `json:"order_id"`
+	PreparingAt time.Time `json:"preparing_at"`
+}
+
+func (e OrderPreparingEvent) ID() string           { return e.EventID }
+func (e OrderPreparingEvent) AggregateID() string  { return e.OrderID }
+func (e OrderPreparingEvent) EventType() EventType { return OrderPreparingEventType }
+func (e OrderPreparingEvent) Timestamp() time.Time { return e.PreparingAt }
+
+type OrderReadyEvent struct {
+	EventID string    `json:"event_id"`
+	OrderID string    `json:"order_id"`
+	ReadyAt time.Time `json:"ready_at"`
+}
+
+func (e OrderReadyEvent) ID() string           { return e.EventID }
+func (e OrderReadyEvent) AggregateID() string  { return e.OrderID }
+func (e OrderReadyEvent) EventType() EventType { return OrderReadyEventType }
+func (e OrderReadyEvent) Timestamp() time.Time { return e.ReadyAt }
+
+type OrderPickedUpEvent struct {
+	EventID    string    `json:"event_id"`
+	OrderID    string    `json:"order_id"`
+	PickedUpAt time.Time `json:"picked_up_at"`
+}
+
+func (e OrderPickedUpEvent) ID() string           { return e.EventID }
+func (e OrderPickedUpEvent) AggregateID() string  { return e.OrderID }
+func (e OrderPickedUpEvent) EventType() EventType { return OrderPickedUpEventType }
+func (e OrderPickedUpEvent) Timestamp() time.Time { return e.PickedUpAt }
+
+type OrderOutForDeliveryEvent struct {
+	EventID          string    `json:"event_id"`
+	OrderID          string    `json:"order_id"`
+	OutForDeliveryAt time.Time `json:"out_for_delivery_at"`
+}
+
+func (e OrderOutForDeliveryEvent) ID() string           { return e.EventID }
+func (e OrderOutForDeliveryEvent) AggregateID() string  { return e.OrderID }
+func (e OrderOutForDeliveryEvent) EventType() EventType { return OrderOutForDeliveryEventType }
+func (e OrderOutForDeliveryEvent) Timestamp() time.Time { return e.OutForDeliveryAt }
+
+type OrderDeliveredEvent struct {
+	EventID     string    `json:"event_id"`
+	OrderID     string    `json:"order_id"`
+	UserID      string    `json:"user_id"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+func (e OrderDeliveredEvent) ID() string           { return e.EventID }
+func (e OrderDeliveredEvent) AggregateID() string  { return e.OrderID }
+func (e OrderDeliveredEvent) EventType() EventType { return OrderDeliveredEventType }
+func (e OrderDeliveredEvent) Timestamp() time.Time { return e.DeliveredAt }
+
+// The constructors below expect the order to have just reached the matching
+// status, so its fulfilment timestamp is set.
+
+func NewOrderAcceptedEvent(order *Order) OrderAcceptedEvent {
+	return OrderAcceptedEvent{
+		EventID:      uuid.New().String(),
+		OrderID:      order.ID,
+		RestaurantID: order.RestaurantID,
+		AcceptedAt:   *order.AcceptedAt,
+	}
+}
+
+func NewOrderPreparingEvent(order *Order) OrderPreparingEvent {
+	return OrderPreparingEvent{
+		EventID:     uuid.New().String(),
+		OrderID:     order.ID,
+		PreparingAt: *order.PreparingAt,
+	}
+}
+
+func NewOrderReadyEvent(order *Order) OrderReadyEvent {
+	return OrderReadyEvent{
+		EventID: uuid.New().String(),
+		OrderID: order.ID,
+		ReadyAt: *order.ReadyAt,
+	}
+}
+
+func NewOrderPickedUpEvent(order *Order) OrderPickedUpEvent {
+	return OrderPickedUpEvent{
+		EventID:    uuid.New().String(),
+		OrderID:    order.ID,
+		PickedUpAt: *order.PickedUpAt,
+	}
+}
+
+func NewOrderOutForDeliveryEvent(order *Order) OrderOutForDeliveryEvent {
+	return OrderOutForDeliveryEvent{
+		EventID:          uuid.New().String(),
+		OrderID:          order.ID,
+		OutForDeliveryAt: *order.OutForDeliveryAt,
+	}
+}
+
+func NewOrderDeliveredEvent(order *Order) OrderDeliveredEvent {
+	return OrderDeliveredEvent{
+		EventID:     uuid.New().String(),
+		OrderID:     order.ID,
+		UserID:      order.UserID,
+		DeliveredAt: *order.DeliveredAt,
+	}
+}
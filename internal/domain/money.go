@@ -0,0 +1,269 @@
+package domain
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used when a request does not name a currency.
+const DefaultCurrency = "INR"
+
+// minorUnitDigits is the number of decimal places of every supported currency
+// and matches the DECIMAL(10,2) columns in the schema.
+const (
+	minorUnitDigits    = 2
+	minorUnitsPerMajor = 100
+)
+
+// Currencies are the ISO 4217 codes money may be in. Each has minorUnitDigits
+// decimal places; currencies with other minor units, such as JPY or KWD,
+// cannot be represented and are not supported.
+var Currencies = []string{"AED", "AUD", "CAD", "EUR", "GBP", "INR", "SGD", "USD"}
+
+var (
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+)
+
+// ValidateCurrency reports whether code is one of Currencies.
+func ValidateCurrency(code string) error {
+	if !slices.Contains(Currencies, code) {
+		return fmt.Errorf("%w %q, want one of %s", ErrUnsupportedCurrency, code, strings.Join(Currencies, ", "))
+	}
+	return nil
+}
+
+// Money is an exact amount held as an integer number of minor units (paise,
+// cents) together with its ISO 4217 currency code.
+//
+// Rounding: decimal amounts with more digits than the currency allows are
+// rounded half away from zero to the nearest minor unit, when parsed from
+// JSON, from the database or with ParseMoney. Arithmetic on Money never rounds.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(minorUnits int64, currency string) Money {
+	return Money{Amount: minorUnits, Currency: currency}
+}
+
+// ParseMoney parses a decimal amount such as "249.50" in the given currency,
+// which must be one of Currencies.
+func ParseMoney(amount, currency string) (Money, error) {
+	if err := ValidateCurrency(currency); err != nil {
+		return Money{}, err
+	}
+	minor, err := parseMinorUnits(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m+o. Both amounts must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	if (o.Amount > 0 && m.Amount > math.MaxInt64-o.Amount) || (o.Amount < 0 && m.Amount < math.MinInt64-o.Amount) {
+		return Money{}, fmt.Errorf("money overflow")
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Mul returns m multiplied by a whole quantity.
+func (m Money) Mul(quantity int) (Money, error) {
+	q := int64(quantity)
+	product := m.Amount * q
+	if q != 0 && product/q != m.Amount {
+		return Money{}, fmt.Errorf("money overflow")
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// Cmp compares the amounts of m and o, returning -1, 0 or +1. Both amounts
+// must be in the same currency.
+func (m Money) Cmp(o Money) (int, error) {
+	if m.Currency != o.Currency {
+		return 0, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// String formats the amount as a plain decimal, e.g. "249.50".
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+	}
+
+	units := amount / minorUnitsPerMajor
+	cents := amount % minorUnitsPerMajor
+	if units < 0 {
+		units = -units
+	}
+	if cents < 0 {
+		cents = -cents
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, units, cents)
+}
+
+type moneyJSON struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON encodes Money as {"amount":"249.50","currency":"INR"}. The
+// amount is a string so that clients never parse it as a float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.String(), m.Currency})
+}
+
+// UnmarshalJSON accepts the object form written by MarshalJSON, with the
+// amount as a string or a number, and a bare decimal number or string for
+// payloads written before Money existed. Bare amounts have no currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '{' {
+		var v moneyJSON
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		minor, err := parseMinorUnits(v.Amount.String())
+		if err != nil {
+			return err
+		}
+		m.Amount, m.Currency = minor, v.Currency
+		return nil
+	}
+
+	raw := string(data)
+	if unquoted, err := strconv.Unquote(raw); err == nil {
+		raw = unquoted
+	}
+
+	minor, err := parseMinorUnits(raw)
+	if err != nil {
+		return err
+	}
+	m.Amount = minor
+	return nil
+}
+
+// Value stores the amount as a decimal string. The currency lives in its own column.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a DECIMAL column into the amount and leaves Currency untouched.
+func (m *Money) Scan(src any) error {
+	var raw string
+	switch v := src.(type) {
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	case int64:
+		m.Amount = v * minorUnitsPerMajor
+		return nil
+	case float64:
+		raw = strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		m.Amount = 0
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+
+	minor, err := parseMinorUnits(raw)
+	if err != nil {
+		return err
+	}
+	m.Amount = minor
+	return nil
+}
+
+// parseMinorUnits converts a decimal string with at most one leading sign
+// into minor units, rounding half away from zero beyond minorUnitDigits
+// decimal places.
+func parseMinorUnits(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	digits := s
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative, digits = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		digits = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" || !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	roundUp := len(frac) > minorUnitDigits && frac[minorUnitDigits] >= '5'
+	if len(frac) > minorUnitDigits {
+		frac = frac[:minorUnitDigits]
+	}
+	frac += strings.Repeat("0", minorUnitDigits-len(frac))
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	if roundUp {
+		if minor == math.MaxInt64 {
+			return 0, fmt.Errorf("invalid amount %q: out of range", s)
+		}
+		minor++
+	}
+
+	if negative {
+		minor = -minor
+	}
+	return minor, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
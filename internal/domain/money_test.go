@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMinorUnits(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"249.50", 24950, true},
+		{"249.5", 24950, true},
+		{"249", 24900, true},
+		{" 0.01 ", 1, true},
+		{"+1.00", 100, true},
+		{"-1.00", -100, true},
+		{"-0.00", 0, true},
+
+		// Rounding half away from zero
+		{"0.004", 0, true},
+		{"0.005", 1, true},
+		{"1.994", 199, true},
+		{"1.995", 200, true},
+		{"-0.005", -1, true},
+		{"-1.994", -199, true},
+		{"-1.995", -200, true},
+
+		// Fraction digits beyond the minor unit are only read for rounding
+		{"1.00000000000000000000000000009", 100, true},
+		{"1.00500000000000000000000000000", 101, true},
+
+		{"92233720368547758.07", 9223372036854775807, true},
+		{"92233720368547758.08", 0, false},
+		{"92233720368547758.069", 9223372036854775807, true},
+		{"92233720368547758.075", 0, false},
+		{"-92233720368547758.08", 0, false},
+		{"100000000000000000000", 0, false},
+
+		{"", 0, false},
+		{"-", 0, false},
+		{"+", 0, false},
+		{".50", 0, false},
+		{"1.", 100, true},
+		{"-+5", 0, false},
+		{"+-5", 0, false},
+		{"--5", 0, false},
+		{"++5", 0, false},
+		{"5-", 0, false},
+		{"1.2.3", 0, false},
+		{"1e3", 0, false},
+		{"1,000.00", 0, false},
+		{"NaN", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMinorUnits(tt.in)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("parseMinorUnits(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("parseMinorUnits(%q) = %d; want an error", tt.in, got)
+		}
+	}
+}
+
+func TestParseMoneyChecksCurrency(t *testing.T) {
+	for _, currency := range []string{"", "JPY", "KWD", "inr", "RUPEES"} {
+		if _, err := ParseMoney("10.00", currency); !errors.Is(err, ErrUnsupportedCurrency) {
+			t.Errorf("ParseMoney in %q: err = %v, want ErrUnsupportedCurrency", currency, err)
+		}
+	}
+
+	m, err := ParseMoney("10.005", "EUR")
+	if err != nil || m != NewMoney(1001, "EUR") {
+		t.Errorf("ParseMoney = %+v, %v; want 10.01 EUR", m, err)
+	}
+	if _, err := ParseMoney("ten", "EUR"); err == nil || errors.Is(err, ErrUnsupportedCurrency) {
+		t.Errorf("ParseMoney of a malformed amount: err = %v, want an amount error", err)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		ok   bool
+	}{
+		{`{"amount":"249.50","currency":"INR"}`, NewMoney(24950, "INR"), true},
+		{`{"amount":249.5,"currency":"USD"}`, NewMoney(24950, "USD"), true},
+		{`{"amount":"-0.005","currency":"USD"}`, NewMoney(-1, "USD"), true},
+		// Payloads from before Money carry bare amounts and no currency
+		{`249.50`, NewMoney(24950, ""), true},
+		{`"249.505"`, NewMoney(24951, ""), true},
+		{`null`, Money{}, true},
+
+		{`{"amount":"-+5","currency":"INR"}`, Money{}, false},
+		{`{"amount":"1e3","currency":"INR"}`, Money{}, false},
+		{`{"amount":"92233720368547758.08","currency":"INR"}`, Money{}, false},
+		{`"abc"`, Money{}, false},
+		{`true`, Money{}, false},
+	}
+
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("unmarshal %s = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("unmarshal %s = %+v; want an error", tt.in, got)
+		}
+	}
+
+	for _, m := range []Money{NewMoney(24950, "INR"), NewMoney(-1, "USD"), NewMoney(-250, "EUR"), NewMoney(0, "GBP")} {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("marshal %+v: %v", m, err)
+		}
+		var back Money
+		if err := json.Unmarshal(data, &back); err != nil || back != m {
+			t.Errorf("round trip of %+v through %s = %+v, %v", m, data, back, err)
+		}
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		src  any
+		want int64
+		ok   bool
+	}{
+		{[]byte("249.50"), 24950, true},
+		{"-12.345", -1235, true},
+		{int64(7), 700, true},
+		{float64(249.5), 24950, true},
+		{nil, 0, true},
+		{[]byte("-+1.00"), 0, false},
+		{"twelve", 0, false},
+		{true, 0, false},
+	}
+
+	for _, tt := range tests {
+		m := NewMoney(99, "INR")
+		err := m.Scan(tt.src)
+		if tt.ok && (err != nil || m.Amount != tt.want) {
+			t.Errorf("Scan(%#v) = %d, %v; want %d", tt.src, m.Amount, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("Scan(%#v) = %d; want an error", tt.src, m.Amount)
+		}
+		if m.Currency != "INR" {
+			t.Errorf("Scan(%#v) changed the currency to %q", tt.src, m.Currency)
+		}
+	}
+}
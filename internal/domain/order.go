@@ -1,6 +1,9 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,19 +12,24 @@ import (
 type OrderStatus string
 
 const (
-	OrderStatusPending   OrderStatus = "PENDING"
-	OrderStatusConfirmed OrderStatus = "CONFIRMED"
-	OrderStatusFailed    OrderStatus = "FAILED"
-	OrderStatusDelivered OrderStatus = "DELIVERED"
-	OrderStatusCancelled OrderStatus = "CANCELLED"
+	OrderStatusPending        OrderStatus = "PENDING"
+	OrderStatusConfirmed      OrderStatus = "CONFIRMED"
+	OrderStatusFailed         OrderStatus = "FAILED"
+	OrderStatusAccepted       OrderStatus = "ACCEPTED"
+	OrderStatusPreparing      OrderStatus = "PREPARING"
+	OrderStatusReady          OrderStatus = "READY"
+	OrderStatusPickedUp       OrderStatus = "PICKED_UP"
+	OrderStatusOutForDelivery OrderStatus = "OUT_FOR_DELIVERY"
+	OrderStatusDelivered      OrderStatus = "DELIVERED"
+	OrderStatusCancelled      OrderStatus = "CANCELLED"
 )
 
 type OrderItem struct {
-	ID       string  `json:"id"`
-	ItemID   string  `json:"item_id"`
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Quantity int     `json:"quantity"`
+	ID       string `json:"id"`
+	ItemID   string `json:"item_id"`
+	Name     string `json:"name"`
+	Price    Money  `json:"price"`
+	Quantity int    `json:"quantity"`
 }
 
 type Order struct {
@@ -29,17 +37,47 @@ type Order struct {
 	UserID       string      `json:"user_id"`
 	RestaurantID string      `json:"restaurant_id"`
 	Items        []OrderItem `json:"items"`
-	TotalAmount  float64     `json:"total_amount"`
+	TotalAmount  Money       `json:"total_amount"`
 	Status       OrderStatus `json:"status"`
 	CreatedAt    time.Time   `json:"created_at"`
 	UpdatedAt    time.Time   `json:"updated_at"`
 	Version      int         `json:"version"`
+
+	// Fulfilment timestamps, set when the order reaches each status
+	AcceptedAt       *time.Time `json:"accepted_at,omitempty"`
+	PreparingAt      *time.Time `json:"preparing_at,omitempty"`
+	ReadyAt          *time.Time `json:"ready_at,omitempty"`
+	PickedUpAt       *time.Time `json:"picked_up_at,omitempty"`
+	OutForDeliveryAt *time.Time `json:"out_for_delivery_at,omitempty"`
+	DeliveredAt      *time.Time `json:"delivered_at,omitempty"`
 }
 
+// NewOrder builds a pending order. All item prices must share one of
+// Currencies, which becomes the currency of the order total.
 func NewOrder(userID, restaurantID string, items []OrderItem) (*Order, error) {
-	var total float64
+	if len(items) == 0 {
+		return nil, fmt.Errorf("order must have at least one item")
+	}
+	if err := ValidateCurrency(items[0].Price.Currency); err != nil {
+		return nil, fmt.Errorf("item %s: %w", items[0].ItemID, err)
+	}
+
+	total := Money{Currency: items[0].Price.Currency}
 	for _, item := range items {
-		total += item.Price * float64(item.Quantity)
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("item %s: quantity must be positive", item.ItemID)
+		}
+		if item.Price.IsNegative() {
+			return nil, fmt.Errorf("item %s: price must not be negative", item.ItemID)
+		}
+
+		line, err := item.Price.Mul(item.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("item %s: %w", item.ItemID, err)
+		}
+		if total, err = total.Add(line); err != nil {
+			return nil, fmt.Errorf("item %s: %w", item.ItemID, err)
+		}
 	}
 
 	now := time.Now().UTC()
@@ -56,20 +94,154 @@ func NewOrder(userID, restaurantID string, items []OrderItem) (*Order, error) {
 	}, nil
 }
 
-func (o *Order) Confirm() {
-	o.Status = OrderStatusConfirmed
+// OpenStatuses are the statuses in which an order waits on its restaurant,
+// from confirmation until it is handed over for delivery.
+var OpenStatuses = []OrderStatus{
+	OrderStatusConfirmed,
+	OrderStatusAccepted,
+	OrderStatusPreparing,
+	OrderStatusReady,
+}
+
+// transitions lists the statuses each status may move to. Statuses without
+// an entry are terminal.
+var transitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:        {OrderStatusConfirmed, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusConfirmed:      {OrderStatusAccepted, OrderStatusCancelled},
+	OrderStatusAccepted:       {OrderStatusPreparing, OrderStatusCancelled},
+	OrderStatusPreparing:      {OrderStatusReady, OrderStatusCancelled},
+	OrderStatusReady:          {OrderStatusPickedUp, OrderStatusCancelled},
+	OrderStatusPickedUp:       {OrderStatusOutForDelivery},
+	OrderStatusOutForDelivery: {OrderStatusDelivered},
+}
+
+// ErrInvalidTransition matches every *InvalidTransitionError with errors.Is.
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+type InvalidTransitionError struct {
+	OrderID string
+	From    OrderStatus
+	To      OrderStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("order %s cannot move from %s to %s", e.OrderID, e.From, e.To)
+}
+
+func (e *InvalidTransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid reports whether s is one of the statuses above.
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusPending, OrderStatusConfirmed, OrderStatusFailed, OrderStatusAccepted, OrderStatusPreparing,
+		OrderStatusReady, OrderStatusPickedUp, OrderStatusOutForDelivery, OrderStatusDelivered, OrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (s OrderStatus) IsTerminal() bool {
+	return len(transitions[s]) == 0
+}
+
+// SourceStatuses returns every status from which an order may move to status.
+func SourceStatuses(status OrderStatus) []OrderStatus {
+	var sources []OrderStatus
+	for from, targets := range transitions {
+		for _, to := range targets {
+			if to == status {
+				sources = append(sources, from)
+			}
+		}
+	}
+	return sources
+}
+
+func (o *Order) transitionTo(status OrderStatus) error {
+	if !o.Status.CanTransitionTo(status) {
+		return &InvalidTransitionError{OrderID: o.ID, From: o.Status, To: status}
+	}
+
+	o.Status = status
 	o.UpdatedAt = time.Now().UTC() // UTC -> timezone-independent standard
 	o.Version++
+	return nil
 }
 
-func (o *Order) Fail() {
-	o.Status = OrderStatusFailed
-	o.UpdatedAt = time.Now().UTC()
-	o.Version++
+func (o *Order) Confirm() error {
+	return o.transitionTo(OrderStatusConfirmed)
 }
 
-func (o *Order) Cancel() {
-	o.Status = OrderStatusCancelled
-	o.UpdatedAt = time.Now().UTC()
-	o.Version++
+func (o *Order) Fail() error {
+	return o.transitionTo(OrderStatusFailed)
+}
+
+// advance moves the order along the fulfilment lifecycle on behalf of actor
+// and records when the new status was reached in stamp. Restaurants take an
+// order up to READY; couriers, or support, carry it from there.
+func (o *Order) advance(actor Actor, status OrderStatus, stamp **time.Time) error {
+	if !o.Status.CanTransitionTo(status) {
+		return &InvalidTransitionError{OrderID: o.ID, From: o.Status, To: status}
+	}
+	if !actor.mayAdvance(o, status) {
+		return &AdvanceNotPermittedError{OrderID: o.ID, Actor: actor, To: status}
+	}
+
+	if err := o.transitionTo(status); err != nil {
+		return err
+	}
+
+	at := o.UpdatedAt
+	*stamp = &at
+	return nil
+}
+
+func (o *Order) Accept(actor Actor) error {
+	return o.advance(actor, OrderStatusAccepted, &o.AcceptedAt)
+}
+
+func (o *Order) StartPreparing(actor Actor) error {
+	return o.advance(actor, OrderStatusPreparing, &o.PreparingAt)
+}
+
+func (o *Order) MarkReady(actor Actor) error {
+	return o.advance(actor, OrderStatusReady, &o.ReadyAt)
+}
+
+func (o *Order) PickUp(actor Actor) error {
+	return o.advance(actor, OrderStatusPickedUp, &o.PickedUpAt)
+}
+
+func (o *Order) SendOutForDelivery(actor Actor) error {
+	return o.advance(actor, OrderStatusOutForDelivery, &o.OutForDeliveryAt)
+}
+
+func (o *Order) Deliver(actor Actor) error {
+	return o.advance(actor, OrderStatusDelivered, &o.DeliveredAt)
+}
+
+// Cancel cancels the order on behalf of actor. Customers and restaurants may
+// only cancel their own orders and only before fulfilment has started;
+// support may cancel any order that is not yet in a final status.
+func (o *Order) Cancel(actor Actor) error {
+	if !o.Status.CanTransitionTo(OrderStatusCancelled) {
+		return &InvalidTransitionError{OrderID: o.ID, From: o.Status, To: OrderStatusCancelled}
+	}
+
+	if !actor.owns(o) || !slices.Contains(cancellableBy[actor.Role], o.Status) {
+		return &CancelNotPermittedError{OrderID: o.ID, Actor: actor, Status: o.Status}
+	}
+
+	return o.transitionTo(OrderStatusCancelled)
 }
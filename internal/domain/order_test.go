@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+var allStatuses = []OrderStatus{
+	OrderStatusPending,
+	OrderStatusConfirmed,
+	OrderStatusFailed,
+	OrderStatusAccepted,
+	OrderStatusPreparing,
+	OrderStatusReady,
+	OrderStatusPickedUp,
+	OrderStatusOutForDelivery,
+	OrderStatusDelivered,
+	OrderStatusCancelled,
+}
+
+func TestOrderTransitions(t *testing.T) {
+	allowed := map[OrderStatus][]OrderStatus{
+		OrderStatusPending:        {OrderStatusConfirmed, OrderStatusFailed, OrderStatusCancelled},
+		OrderStatusConfirmed:      {OrderStatusAccepted, OrderStatusCancelled},
+		OrderStatusAccepted:       {OrderStatusPreparing, OrderStatusCancelled},
+		OrderStatusPreparing:      {OrderStatusReady, OrderStatusCancelled},
+		OrderStatusReady:          {OrderStatusPickedUp, OrderStatusCancelled},
+		OrderStatusPickedUp:       {OrderStatusOutForDelivery},
+		OrderStatusOutForDelivery: {OrderStatusDelivered},
+	}
+
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			want := false
+			for _, next := range allowed[from] {
+				want = want || next == to
+			}
+
+			order := &Order{ID: "order-1", Status: from, Version: 3}
+			err := order.transitionTo(to)
+			if !want {
+				var transitionErr *InvalidTransitionError
+				if !errors.As(err, &transitionErr) || transitionErr.From != from || transitionErr.To != to || transitionErr.OrderID != "order-1" {
+					t.Errorf("%s -> %s: err = %v, want an InvalidTransitionError naming both", from, to, err)
+				}
+				if !errors.Is(err, ErrInvalidTransition) {
+					t.Errorf("%s -> %s: err = %v does not match ErrInvalidTransition", from, to, err)
+				}
+				if order.Status != from || order.Version != 3 {
+					t.Errorf("%s -> %s: refused transition left the order %s at version %d", from, to, order.Status, order.Version)
+				}
+				continue
+			}
+
+			if err != nil {
+				t.Errorf("%s -> %s: %v", from, to, err)
+				continue
+			}
+			if order.Status != to || order.Version != 4 {
+				t.Errorf("%s -> %s: order is %s at version %d, want %s at version 4", from, to, order.Status, order.Version, to)
+			}
+		}
+
+		if terminal := len(allowed[from]) == 0; from.IsTerminal() != terminal {
+			t.Errorf("%s.IsTerminal() = %t, want %t", from, !terminal, terminal)
+		}
+	}
+}
+
+func TestFulfilmentStepsStampTheirStatus(t *testing.T) {
+	order := &Order{ID: "order-1", RestaurantID: "rest-1", Status: OrderStatusConfirmed}
+	restaurant := Actor{ID: "rest-1", Role: ActorRestaurant}
+	courier := Actor{ID: "courier-1", Role: ActorCourier}
+	steps := []struct {
+		advance func(Actor) error
+		actor   Actor
+		stamp   func() bool
+	}{
+		{order.Accept, restaurant, func() bool { return order.AcceptedAt != nil }},
+		{order.StartPreparing, restaurant, func() bool { return order.PreparingAt != nil }},
+		{order.MarkReady, restaurant, func() bool { return order.ReadyAt != nil }},
+		{order.PickUp, courier, func() bool { return order.PickedUpAt != nil }},
+		{order.SendOutForDelivery, courier, func() bool { return order.OutForDeliveryAt != nil }},
+		{order.Deliver, courier, func() bool { return order.DeliveredAt != nil }},
+	}
+	for _, step := range steps {
+		from := order.Status
+		if err := step.advance(step.actor); err != nil {
+			t.Fatalf("advancing from %s: %v", from, err)
+		}
+		if !step.stamp() {
+			t.Errorf("moving from %s to %s set no timestamp", from, order.Status)
+		}
+	}
+
+	var transitionErr *InvalidTransitionError
+	if err := order.Accept(restaurant); !errors.As(err, &transitionErr) || transitionErr.From != OrderStatusDelivered || transitionErr.To != OrderStatusAccepted {
+		t.Errorf("accepting a delivered order: err = %v, want DELIVERED -> ACCEPTED refused", err)
+	}
+}
+
+func TestFulfilmentStepPermissions(t *testing.T) {
+	restaurantSteps := []OrderStatus{OrderStatusAccepted, OrderStatusPreparing, OrderStatusReady}
+	deliverySteps := []OrderStatus{OrderStatusPickedUp, OrderStatusOutForDelivery, OrderStatusDelivered}
+
+	tests := []struct {
+		actor   Actor
+		allowed []OrderStatus
+	}{
+		{Actor{ID: "rest-1", Role: ActorRestaurant}, restaurantSteps},
+		{Actor{ID: "rest-2", Role: ActorRestaurant}, nil},
+		{Actor{ID: "courier-1", Role: ActorCourier}, deliverySteps},
+		{Actor{ID: "agent-1", Role: ActorSupport}, deliverySteps},
+		{Actor{ID: "user-1", Role: ActorCustomer}, nil},
+	}
+
+	from := map[OrderStatus]OrderStatus{
+		OrderStatusAccepted:       OrderStatusConfirmed,
+		OrderStatusPreparing:      OrderStatusAccepted,
+		OrderStatusReady:          OrderStatusPreparing,
+		OrderStatusPickedUp:       OrderStatusReady,
+		OrderStatusOutForDelivery: OrderStatusPickedUp,
+		OrderStatusDelivered:      OrderStatusOutForDelivery,
+	}
+	for _, tt := range tests {
+		for _, to := range append(slices.Clone(restaurantSteps), deliverySteps...) {
+			order := &Order{ID: "order-1", UserID: "user-1", RestaurantID: "rest-1", Status: from[to]}
+			var stamp *time.Time
+			err := order.advance(tt.actor, to, &stamp)
+
+			if slices.Contains(tt.allowed, to) {
+				if err != nil || order.Status != to {
+					t.Errorf("%s %s moving to %s: %v, want it allowed", tt.actor.Role, tt.actor.ID, to, err)
+				}
+				continue
+			}
+			var notPermitted *AdvanceNotPermittedError
+			if !errors.As(err, &notPermitted) || notPermitted.To != to || notPermitted.Actor != tt.actor {
+				t.Errorf("%s %s moving to %s: err = %v, want AdvanceNotPermittedError", tt.actor.Role, tt.actor.ID, to, err)
+			}
+			if order.Status != from[to] || stamp != nil {
+				t.Errorf("%s %s moving to %s: refused step changed the order", tt.actor.Role, tt.actor.ID, to)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNoEvents = errors.New("no events for order")
+
+// RecordedEvent is an event as stored in the event log, with the order
+// version it produced.
+type RecordedEvent struct {
+	Event   Event
+	Version int
+}
+
+// ErrUnknownEventType is returned for event types this version does not know.
+var ErrUnknownEventType = errors.New("unknown event type")
+
+var decoders = map[EventType]func(data []byte) (Event, error){
+	OrderCreatedEventType:        decodeAs[OrderCreatedEvent],
+	OrderConfirmedEventType:      decodeAs[OrderConfirmedEvent],
+	OrderFailedEventType:         decodeAs[OrderFailedEvent],
+	OrderCancelledEventType:      decodeAs[OrderCancelledEvent],
+	OrderAcceptedEventType:       decodeAs[OrderAcceptedEvent],
+	OrderPreparingEventType:      decodeAs[OrderPreparingEvent],
+	OrderReadyEventType:          decodeAs[OrderReadyEvent],
+	OrderPickedUpEventType:       decodeAs[OrderPickedUpEvent],
+	OrderOutForDeliveryEventType: decodeAs[OrderOutForDeliveryEvent],
+	OrderDeliveredEventType:      decodeAs[OrderDeliveredEvent],
+}
+
+// Known reports whether DecodeEvent can decode events of type t.
+func (t EventType) Known() bool {
+	_, ok := decoders[t]
+	return ok
+}
+
+// DecodeEvent turns a stored payload back into the typed event for eventType.
+func DecodeEvent(eventType EventType, data []byte) (Event, error) {
+	decode, ok := decoders[eventType]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownEventType, eventType)
+	}
+	return decode(data)
+}
+
+func decodeAs[T Event](data []byte) (Event, error) {
+	var event T
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", event.EventType(), err)
+	}
+	return event, nil
+}
+
+// ReplayOrder rebuilds an order by folding its events, oldest first. The first
+// event must be the OrderCreatedEvent and versions must have no gaps.
+func ReplayOrder(events []RecordedEvent) (*Order, error) {
+	if len(events) == 0 {
+		return nil, ErrNoEvents
+	}
+
+	order := &Order{}
+	for _, recorded := range events {
+		if err := order.Apply(recorded.Event, recorded.Version); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Apply folds a single recorded event into the order. State transitions are
+// checked against the same rules as live changes.
+func (o *Order) Apply(event Event, version int) error {
+	if version != o.Version+1 {
+		return fmt.Errorf("order %s: event %s has version %d, expected %d", event.AggregateID(), event.EventType(), version, o.Version+1)
+	}
+
+	if created, ok := event.(OrderCreatedEvent); ok {
+		if o.ID != "" {
+			return fmt.Errorf("order %s: duplicate %s event", created.OrderID, created.EventType())
+		}
+		*o = Order{
+			ID:           created.OrderID,
+			UserID:       created.UserID,
+			RestaurantID: created.RestaurantID,
+			Items:        created.Items,
+			TotalAmount:  created.TotalAmount,
+			Status:       OrderStatusPending,
+			CreatedAt:    created.CreatedAt,
+			UpdatedAt:    created.CreatedAt,
+			Version:      version,
+		}
+		return nil
+	}
+
+	if o.ID == "" {
+		return fmt.Errorf("order %s: %s event before OrderCreated", event.AggregateID(), event.EventType())
+	}
+
+	var (
+		status OrderStatus
+		stamp  **time.Time
+	)
+
+	switch event.(type) {
+	case OrderConfirmedEvent:
+		status = OrderStatusConfirmed
+	case OrderFailedEvent:
+		status = OrderStatusFailed
+	case OrderCancelledEvent:
+		status = OrderStatusCancelled
+	case OrderAcceptedEvent:
+		status, stamp = OrderStatusAccepted, &o.AcceptedAt
+	case OrderPreparingEvent:
+		status, stamp = OrderStatusPreparing, &o.PreparingAt
+	case OrderReadyEvent:
+		status, stamp = OrderStatusReady, &o.ReadyAt
+	case OrderPickedUpEvent:
+		status, stamp = OrderStatusPickedUp, &o.PickedUpAt
+	case OrderOutForDeliveryEvent:
+		status, stamp = OrderStatusOutForDelivery, &o.OutForDeliveryAt
+	case OrderDeliveredEvent:
+		status, stamp = OrderStatusDelivered, &o.DeliveredAt
+	default:
+		return fmt.Errorf("order %s: cannot apply %s event", o.ID, event.EventType())
+	}
+
+	if !o.Status.CanTransitionTo(status) {
+		return &InvalidTransitionError{OrderID: o.ID, From: o.Status, To: status}
+	}
+
+	at := event.Timestamp()
+	o.Status = status
+	o.UpdatedAt = at
+	o.Version = version
+	if stamp != nil {
+		*stamp = &at
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type replayStep struct {
+	apply func(o *Order) error
+	event func(o *Order) Event
+}
+
+func TestReplayOrder(t *testing.T) {
+	restaurant := Actor{ID: "rest-1", Role: ActorRestaurant}
+	courier := Actor{ID: "courier-1", Role: ActorCourier}
+
+	confirm := replayStep{(*Order).Confirm, func(o *Order) Event { return NewOrderConfirmedEvent(o.ID) }}
+	accept := replayStep{
+		func(o *Order) error { return o.Accept(restaurant) },
+		func(o *Order) Event { return NewOrderAcceptedEvent(o) },
+	}
+
+	tests := []struct {
+		name  string
+		steps []replayStep
+		want  OrderStatus
+	}{
+		{"created", nil, OrderStatusPending},
+		{"delivered", []replayStep{
+			confirm,
+			accept,
+			{func(o *Order) error { return o.StartPreparing(restaurant) }, func(o *Order) Event { return NewOrderPreparingEvent(o) }},
+			{func(o *Order) error { return o.MarkReady(restaurant) }, func(o *Order) Event { return NewOrderReadyEvent(o) }},
+			{func(o *Order) error { return o.PickUp(courier) }, func(o *Order) Event { return NewOrderPickedUpEvent(o) }},
+			{func(o *Order) error { return o.SendOutForDelivery(courier) }, func(o *Order) Event { return NewOrderOutForDeliveryEvent(o) }},
+			{func(o *Order) error { return o.Deliver(courier) }, func(o *Order) Event { return NewOrderDeliveredEvent(o) }},
+		}, OrderStatusDelivered},
+		{"cancelled", []replayStep{
+			confirm,
+			accept,
+			{func(o *Order) error { return o.Cancel(restaurant) }, func(o *Order) Event { return NewOrderCancelledEvent(o.ID, restaurant, "out of stock") }},
+		}, OrderStatusCancelled},
+		{"failed", []replayStep{
+			{(*Order).Fail, func(o *Order) Event { return NewOrderFailedEvent(o.ID, "AMOUNT_ABOVE_MAXIMUM") }},
+		}, OrderStatusFailed},
+	}
+
+	for _, tt := range tests {
+		// Drive a live order as the service does and record what it would
+		// have written to the event log
+		live, err := NewOrder("user-1", "rest-1", []OrderItem{
+			{ID: "line-1", ItemID: "item-1", Name: "Thali", Price: NewMoney(24950, "INR"), Quantity: 2},
+		})
+		if err != nil {
+			t.Fatalf("NewOrder: %v", err)
+		}
+		events := []RecordedEvent{{Event: NewOrderCreatedEvent(live), Version: live.Version}}
+		for _, step := range tt.steps {
+			if err := step.apply(live); err != nil {
+				t.Fatalf("%s: step from %s: %v", tt.name, live.Status, err)
+			}
+			events = append(events, RecordedEvent{Event: step.event(live), Version: live.Version})
+		}
+
+		replayed, err := ReplayOrder(events)
+		if err != nil {
+			t.Errorf("%s: ReplayOrder: %v", tt.name, err)
+			continue
+		}
+		if replayed.Status != tt.want || replayed.Version != live.Version {
+			t.Errorf("%s: replayed %s at version %d, want %s at version %d", tt.name, replayed.Status, replayed.Version, tt.want, live.Version)
+		}
+		if replayed.ID != live.ID || replayed.UserID != live.UserID || replayed.RestaurantID != live.RestaurantID ||
+			replayed.TotalAmount != live.TotalAmount || !reflect.DeepEqual(replayed.Items, live.Items) || !replayed.CreatedAt.Equal(live.CreatedAt) {
+			t.Errorf("%s: replayed %+v, want the fields of %+v", tt.name, replayed, live)
+		}
+		if last := events[len(events)-1].Event.Timestamp(); !replayed.UpdatedAt.Equal(last) {
+			t.Errorf("%s: updated at %s, want the last event's %s", tt.name, replayed.UpdatedAt, last)
+		}
+
+		stamps := []struct {
+			field          string
+			live, replayed *time.Time
+		}{
+			{"accepted_at", live.AcceptedAt, replayed.AcceptedAt},
+			{"preparing_at", live.PreparingAt, replayed.PreparingAt},
+			{"ready_at", live.ReadyAt, replayed.ReadyAt},
+			{"picked_up_at", live.PickedUpAt, replayed.PickedUpAt},
+			{"out_for_delivery_at", live.OutForDeliveryAt, replayed.OutForDeliveryAt},
+			{"delivered_at", live.DeliveredAt, replayed.DeliveredAt},
+		}
+		for _, s := range stamps {
+			if (s.live == nil) != (s.replayed == nil) || s.live != nil && !s.live.Equal(*s.replayed) {
+				t.Errorf("%s: replayed %s = %v, want %v", tt.name, s.field, s.replayed, s.live)
+			}
+		}
+	}
+}
+
+func TestReplayOrderRejectsBrokenHistories(t *testing.T) {
+	order, err := NewOrder("user-1", "rest-1", []OrderItem{
+		{ID: "line-1", ItemID: "item-1", Name: "Thali", Price: NewMoney(24950, "INR"), Quantity: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewOrder: %v", err)
+	}
+	created := RecordedEvent{Event: NewOrderCreatedEvent(order), Version: 1}
+	confirmed := RecordedEvent{Event: NewOrderConfirmedEvent(order.ID), Version: 2}
+	ready := OrderReadyEvent{EventID: "e-3", OrderID: order.ID, ReadyAt: time.Now().UTC()}
+
+	tests := []struct {
+		name   string
+		events []RecordedEvent
+		want   string
+	}{
+		{"version gap", []RecordedEvent{created, {Event: confirmed.Event, Version: 3}}, "expected 2"},
+		{"event before created", []RecordedEvent{{Event: confirmed.Event, Version: 1}}, "before OrderCreated"},
+		{"duplicate created", []RecordedEvent{created, {Event: created.Event, Version: 2}}, "duplicate"},
+		{"skipped steps", []RecordedEvent{created, confirmed, {Event: ready, Version: 3}}, "cannot move from CONFIRMED to READY"},
+	}
+
+	for _, tt := range tests {
+		if _, err := ReplayOrder(tt.events); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ReplayOrder = %v, want an error mentioning %q", tt.name, err, tt.want)
+		}
+	}
+
+	_, err = ReplayOrder([]RecordedEvent{created, confirmed, {Event: NewOrderConfirmedEvent(order.ID), Version: 3}})
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("confirming twice: err = %v, want ErrInvalidTransition", err)
+	}
+	if _, err := ReplayOrder(nil); !errors.Is(err, ErrNoEvents) {
+		t.Errorf("ReplayOrder(nil) = %v, want ErrNoEvents", err)
+	}
+}
@@ -0,0 +1,111 @@
+package envelope
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+// CloudEvents 1.0 attributes of published events. The schema version and
+// correlation ID travel as extension attributes.
+const (
+	CloudEventsSpecVersion = "1.0"
+	// Content type of structured-mode events
+	CloudEventsContentType = "application/cloudevents+json"
+	// Content type of JSON data, and of data without one
+	DataContentType        = "application/json"
+	SchemaVersionExtension = "schemaversion"
+	CorrelationIDExtension = "correlationid"
+)
+
+// CloudEvent is an envelope as a CloudEvent. Its JSON is the structured-mode
+// encoding; binary-mode transports carry the attributes separately.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            *time.Time      `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	SchemaVersion   int             `json:"schemaversion,omitempty"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+func NewCloudEvent(env *Envelope) CloudEvent {
+	ce := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              env.ID,
+		Source:          env.Source,
+		Type:            string(env.Type),
+		DataContentType: env.DataContentType,
+		SchemaVersion:   env.SchemaVersion,
+		CorrelationID:   env.CorrelationID,
+		Data:            env.Data,
+	}
+	if ce.DataContentType == "" {
+		ce.DataContentType = DataContentType
+	}
+	if !env.Time.IsZero() {
+		t := env.Time
+		ce.Time = &t
+	}
+	return ce
+}
+
+// ParseCloudEvent reads a structured-mode CloudEvent, whose data must be JSON.
+func ParseCloudEvent(payload []byte) (*Envelope, error) {
+	var ce CloudEvent
+	if err := json.Unmarshal(payload, &ce); err != nil {
+		return nil, fmt.Errorf("parse cloudevent: %w", err)
+	}
+
+	env, err := ce.Envelope()
+	if err != nil {
+		return nil, err
+	}
+	if env.DataContentType != DataContentType {
+		return nil, fmt.Errorf("cloudevent data content type %q, want %s", ce.DataContentType, DataContentType)
+	}
+	return env, nil
+}
+
+// Envelope checks the required attributes of ce and returns its envelope.
+// Events without a schema version extension are at version 1, and events
+// without a data content type hold JSON.
+func (ce CloudEvent) Envelope() (*Envelope, error) {
+	if ce.SpecVersion != CloudEventsSpecVersion {
+		return nil, fmt.Errorf("cloudevent spec version %q, want %s", ce.SpecVersion, CloudEventsSpecVersion)
+	}
+	if ce.ID == "" || ce.Source == "" || ce.Type == "" {
+		return nil, fmt.Errorf("cloudevent missing id, source or type: %q, %q, %q", ce.ID, ce.Source, ce.Type)
+	}
+	contentType := DataContentType
+	if ce.DataContentType != "" {
+		mediaType, _, err := mime.ParseMediaType(ce.DataContentType)
+		if err != nil {
+			return nil, fmt.Errorf("cloudevent data content type %q: %w", ce.DataContentType, err)
+		}
+		contentType = mediaType
+	}
+	if ce.SchemaVersion < 0 {
+		return nil, fmt.Errorf("cloudevent schema version %d", ce.SchemaVersion)
+	}
+
+	env := &Envelope{
+		ID:              ce.ID,
+		Type:            domain.EventType(ce.Type),
+		SchemaVersion:   max(ce.SchemaVersion, 1),
+		Source:          ce.Source,
+		CorrelationID:   ce.CorrelationID,
+		Data:            ce.Data,
+		DataContentType: contentType,
+	}
+	if ce.Time != nil {
+		env.Time = *ce.Time
+	}
+	return env, nil
+}
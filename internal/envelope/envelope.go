@@ -0,0 +1,81 @@
+// Package envelope defines how events travel between services: the event
+// data wrapped with its type, schema version and origin, and the upcasters
+// that bring data written at older schema versions up to date.
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+// Envelope is the published form of an event. Data is the event at
+// SchemaVersion of its Type, as JSON unless DataContentType says otherwise;
+// only transports carrying the content type beside the data, such as binary
+// CloudEvents, hold other data.
+type Envelope struct {
+	ID            string           `json:"id"`
+	Type          domain.EventType `json:"type"`
+	SchemaVersion int              `json:"schema_version"`
+	Source        string           `json:"source"`
+	Time          time.Time        `json:"time"`
+	CorrelationID string           `json:"correlation_id,omitempty"`
+	Data          json.RawMessage  `json:"data"`
+
+	DataContentType string `json:"-"`
+}
+
+// New wraps event serialized by s, at the schema version Default gives its
+// type. source names the service publishing it and correlationID the request
+// that led to it.
+func New(s Serializer, event domain.Event, source, correlationID string) (*Envelope, error) {
+	data, err := s.Serialize(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		ID:              event.ID(),
+		Type:            event.EventType(),
+		SchemaVersion:   Default.Version(event.EventType()),
+		Source:          source,
+		Time:            event.Timestamp().UTC(),
+		CorrelationID:   correlationID,
+		Data:            data,
+		DataContentType: s.ContentType(),
+	}, nil
+}
+
+// Parse reads an envelope. Payloads published before envelopes existed are
+// the bare event; they are read as schema version 1 of eventType.
+func Parse(payload []byte, eventType domain.EventType) (*Envelope, error) {
+	var probe struct {
+		SchemaVersion *int            `json:"schema_version"`
+		Data          json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return nil, fmt.Errorf("parse envelope: %w", err)
+	}
+
+	if probe.SchemaVersion == nil || probe.Data == nil {
+		return &Envelope{
+			Type:            eventType,
+			SchemaVersion:   1,
+			Data:            bytes.Clone(payload),
+			DataContentType: DataContentType,
+		}, nil
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return nil, fmt.Errorf("parse envelope: %w", err)
+	}
+	if env.Type == "" || env.SchemaVersion < 1 {
+		return nil, fmt.Errorf("parse envelope: type %q at schema version %d", env.Type, env.SchemaVersion)
+	}
+	env.DataContentType = DataContentType
+	return &env, nil
+}
@@ -0,0 +1,173 @@
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	event := domain.NewOrderConfirmedEvent("order-1")
+	env, err := New(JSON{}, event, "order-processor", "req-1")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("marshal envelope: %v", err)
+	}
+
+	got, err := Parse(payload, "")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got.ID != event.EventID || got.Type != domain.OrderConfirmedEventType || got.SchemaVersion != 1 {
+		t.Errorf("envelope = %+v, want ID %s of %s at version 1", got, event.EventID, domain.OrderConfirmedEventType)
+	}
+	if got.Source != "order-processor" || got.CorrelationID != "req-1" || !got.Time.Equal(event.ConfirmedAt) {
+		t.Errorf("envelope = %+v, want source, correlation ID and time of the event", got)
+	}
+
+	decoded, err := Default.Decode(got)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if decoded.(domain.OrderConfirmedEvent).OrderID != "order-1" {
+		t.Errorf("decoded %+v, want order-1", decoded)
+	}
+}
+
+func TestParseReadsBarePayloadsAsVersionOne(t *testing.T) {
+	env, err := Parse([]byte(`{"event_id":"e-1","order_id":"order-1"}`), domain.OrderConfirmedEventType)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if env.Type != domain.OrderConfirmedEventType || env.SchemaVersion != 1 {
+		t.Errorf("envelope = %+v, want version 1 of %s", env, domain.OrderConfirmedEventType)
+	}
+}
+
+func TestOrderCreatedVersionOneGainsCurrency(t *testing.T) {
+	env := &Envelope{
+		Type:          domain.OrderCreatedEventType,
+		SchemaVersion: 1,
+		Data: json.RawMessage(`{"event_id":"e-1","order_id":"order-1","total_amount":249.5,
+			"items":[{"item_id":"i-1","price":"124.75","quantity":2}],"created_at":"2024-01-01T00:00:00Z"}`),
+	}
+
+	event, err := Default.Decode(env)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	created := event.(domain.OrderCreatedEvent)
+	if want := domain.NewMoney(24950, domain.DefaultCurrency); created.TotalAmount != want {
+		t.Errorf("total = %+v, want %+v", created.TotalAmount, want)
+	}
+	if want := domain.NewMoney(12475, domain.DefaultCurrency); len(created.Items) != 1 || created.Items[0].Price != want {
+		t.Errorf("items = %+v, want one priced %+v", created.Items, want)
+	}
+	if env.SchemaVersion != Default.Version(domain.OrderCreatedEventType) {
+		t.Errorf("schema version = %d, want %d", env.SchemaVersion, Default.Version(domain.OrderCreatedEventType))
+	}
+}
+
+func TestDecodeStoredUpcastsLegacyRows(t *testing.T) {
+	legacy := []byte(`{"event_id":"e-1","order_id":"order-1","total_amount":249.5,"created_at":"2024-01-01T00:00:00Z"}`)
+	event, err := DecodeStored(domain.OrderCreatedEventType, legacy)
+	if err != nil {
+		t.Fatalf("DecodeStored legacy row: %v", err)
+	}
+	if want := domain.NewMoney(24950, domain.DefaultCurrency); event.(domain.OrderCreatedEvent).TotalAmount != want {
+		t.Errorf("legacy total = %+v, want %+v", event.(domain.OrderCreatedEvent).TotalAmount, want)
+	}
+
+	order, err := domain.NewOrder("user-1", "rest-1", []domain.OrderItem{
+		{ID: "line-1", ItemID: "item-1", Name: "Burger", Price: domain.NewMoney(1299, "USD"), Quantity: 2},
+	})
+	if err != nil {
+		t.Fatalf("NewOrder: %v", err)
+	}
+	current, err := json.Marshal(domain.NewOrderCreatedEvent(order))
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	event, err = DecodeStored(domain.OrderCreatedEventType, current)
+	if err != nil {
+		t.Fatalf("DecodeStored current row: %v", err)
+	}
+	created := event.(domain.OrderCreatedEvent)
+	if want := domain.NewMoney(2598, "USD"); created.TotalAmount != want || created.Items[0].Price.Currency != "USD" {
+		t.Errorf("current row decoded as %+v, want it unchanged at %+v", created, want)
+	}
+}
+
+func TestUpcastChainsVersionsAndRejectsNewerOnes(t *testing.T) {
+	r := NewRegistry()
+	rename := func(from, to string) Upcaster {
+		return func(data json.RawMessage) (json.RawMessage, error) {
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(data, &fields); err != nil {
+				return nil, err
+			}
+			fields[to] = fields[from]
+			delete(fields, from)
+			return json.Marshal(fields)
+		}
+	}
+	r.Register(domain.OrderFailedEventType, 1, rename("why", "cause"))
+	r.Register(domain.OrderFailedEventType, 2, rename("cause", "reason"))
+
+	env := &Envelope{
+		Type:          domain.OrderFailedEventType,
+		SchemaVersion: 1,
+		Data:          json.RawMessage(`{"order_id":"order-1","why":"too small","failed_at":"` + time.Now().UTC().Format(time.RFC3339) + `"}`),
+	}
+	event, err := r.Decode(env)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got := event.(domain.OrderFailedEvent).Reason; got != "too small" {
+		t.Errorf("reason = %q, want it carried through both upcasters", got)
+	}
+
+	env = &Envelope{Type: domain.OrderFailedEventType, SchemaVersion: 4, Data: json.RawMessage(`{}`)}
+	if _, err := r.Decode(env); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Decode version 4 = %v, want ErrNewerSchema", err)
+	}
+}
+
+func TestCloudEventRoundTrip(t *testing.T) {
+	env, err := New(JSON{}, domain.NewOrderConfirmedEvent("order-1"), "order-processor", "req-1")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	payload, err := json.Marshal(NewCloudEvent(env))
+	if err != nil {
+		t.Fatalf("marshal cloudevent: %v", err)
+	}
+
+	got, err := ParseCloudEvent(payload)
+	if err != nil {
+		t.Fatalf("ParseCloudEvent: %v", err)
+	}
+	if got.ID != env.ID || got.Type != env.Type || got.SchemaVersion != env.SchemaVersion ||
+		got.Source != env.Source || got.CorrelationID != env.CorrelationID || !got.Time.Equal(env.Time) {
+		t.Errorf("parsed %+v, want %+v", got, env)
+	}
+	if string(got.Data) != string(env.Data) {
+		t.Errorf("data = %s, want %s", got.Data, env.Data)
+	}
+
+	for name, payload := range map[string]string{
+		"spec version": `{"specversion":"0.3","id":"e-1","source":"/x","type":"OrderConfirmed","data":{}}`,
+		"missing id":   `{"specversion":"1.0","source":"/x","type":"OrderConfirmed","data":{}}`,
+		"xml data":     `{"specversion":"1.0","id":"e-1","source":"/x","type":"OrderConfirmed","datacontenttype":"application/xml","data":"<a/>"}`,
+	} {
+		if _, err := ParseCloudEvent([]byte(payload)); err == nil {
+			t.Errorf("ParseCloudEvent accepted an event with a bad %s", name)
+		}
+	}
+}
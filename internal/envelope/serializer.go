@@ -0,0 +1,41 @@
+package envelope
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+// Serializer turns events into the data of envelopes and back.
+type Serializer interface {
+	// ContentType is the media type of the data, as in a content-type header.
+	ContentType() string
+	Serialize(event domain.Event) ([]byte, error)
+	// Deserialize decodes the data of env, which is of this content type.
+	Deserialize(env *Envelope) (domain.Event, error)
+}
+
+// JSON serializes events as JSON. Data written at older schema versions is
+// upcast with Upcasters, or Default when nil.
+type JSON struct {
+	Upcasters *Registry
+}
+
+func (JSON) ContentType() string { return DataContentType }
+
+func (JSON) Serialize(event domain.Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s event: %w", event.EventType(), err)
+	}
+	return data, nil
+}
+
+func (j JSON) Deserialize(env *Envelope) (domain.Event, error) {
+	upcasters := j.Upcasters
+	if upcasters == nil {
+		upcasters = Default
+	}
+	return upcasters.Decode(env)
+}
@@ -0,0 +1,161 @@
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+// ErrNewerSchema is returned for data written at a schema version this build
+// does not know yet.
+var ErrNewerSchema = errors.New("newer schema version")
+
+// Upcaster converts event data from one schema version to the next.
+type Upcaster func(data json.RawMessage) (json.RawMessage, error)
+
+type step struct {
+	eventType domain.EventType
+	from      int
+}
+
+// Registry holds the upcasters of each event type. The current schema version
+// of a type is 1 plus the number of upcasters registered for it.
+type Registry struct {
+	upcasters map[step]Upcaster
+	current   map[domain.EventType]int
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		upcasters: make(map[step]Upcaster),
+		current:   make(map[domain.EventType]int),
+	}
+}
+
+// Register adds the upcaster from version from of eventType to from+1.
+// Upcasters must be registered in version order, starting from 1.
+func (r *Registry) Register(eventType domain.EventType, from int, fn Upcaster) {
+	if from != r.Version(eventType) {
+		panic(fmt.Sprintf("envelope: upcaster for %s from version %d registered at version %d", eventType, from, r.Version(eventType)))
+	}
+	r.upcasters[step{eventType, from}] = fn
+	r.current[eventType] = from + 1
+}
+
+// Version returns the current schema version of eventType.
+func (r *Registry) Version(eventType domain.EventType) int {
+	if v, ok := r.current[eventType]; ok {
+		return v
+	}
+	return 1
+}
+
+// Upcast brings the data of env to the current version of its type.
+func (r *Registry) Upcast(env *Envelope) error {
+	current := r.Version(env.Type)
+	if env.SchemaVersion > current {
+		return fmt.Errorf("%s at version %d, at most %d known: %w", env.Type, env.SchemaVersion, current, ErrNewerSchema)
+	}
+
+	for env.SchemaVersion < current {
+		data, err := r.upcasters[step{env.Type, env.SchemaVersion}](env.Data)
+		if err != nil {
+			return fmt.Errorf("upcast %s from version %d: %w", env.Type, env.SchemaVersion, err)
+		}
+		env.Data = data
+		env.SchemaVersion++
+	}
+	return nil
+}
+
+// Decode upcasts env and decodes its data into the current event struct.
+func (r *Registry) Decode(env *Envelope) (domain.Event, error) {
+	if err := r.Upcast(env); err != nil {
+		return nil, err
+	}
+	return domain.DecodeEvent(env.Type, env.Data)
+}
+
+// DecodeStored decodes event data from the event log, which keeps no schema
+// version. Rows are read as version 1, like payloads published before
+// envelopes, and upcast with Default; the upcasters leave data already in a
+// later shape as it is, so current rows come through unchanged.
+func DecodeStored(eventType domain.EventType, data []byte) (domain.Event, error) {
+	return Default.Decode(&Envelope{
+		Type:            eventType,
+		SchemaVersion:   1,
+		Data:            data,
+		DataContentType: DataContentType,
+	})
+}
+
+// Default holds the upcasters of the platform's events. Producers stamp
+// events with its versions and consumers upcast with it.
+var Default = builtin()
+
+func builtin() *Registry {
+	r := NewRegistry()
+	// Version 2 carries amounts as {"amount", "currency"} objects
+	r.Register(domain.OrderCreatedEventType, 1, orderCreatedCurrency)
+	return r
+}
+
+// orderCreatedCurrency gives the bare decimal amounts of version 1
+// OrderCreatedEvents the currency every order was in at the time.
+func orderCreatedCurrency(data json.RawMessage) (json.RawMessage, error) {
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+
+	if err := withCurrency(event, "total_amount"); err != nil {
+		return nil, err
+	}
+
+	var items []map[string]json.RawMessage
+	if raw, ok := event["items"]; ok {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+	}
+	for i, item := range items {
+		if err := withCurrency(item, "price"); err != nil {
+			return nil, fmt.Errorf("items[%d]: %w", i, err)
+		}
+	}
+	if items != nil {
+		raw, err := json.Marshal(items)
+		if err != nil {
+			return nil, err
+		}
+		event["items"] = raw
+	}
+
+	return json.Marshal(event)
+}
+
+// withCurrency turns the bare amount under key into a Money object in
+// domain.DefaultCurrency. Objects and missing amounts are left as they are.
+func withCurrency(fields map[string]json.RawMessage, key string) error {
+	raw, ok := fields[key]
+	if !ok {
+		return nil
+	}
+
+	var money domain.Money
+	if err := json.Unmarshal(raw, &money); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if money.Currency == "" {
+		money.Currency = domain.DefaultCurrency
+	}
+
+	out, err := json.Marshal(money)
+	if err != nil {
+		return err
+	}
+	fields[key] = out
+	return nil
+}
@@ -0,0 +1,319 @@
+package eventpb
+
+import (
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/schema"
+)
+
+// Package of the messages in proto/orderplatform/events/v1/events.proto
+const protoPackage = "orderplatform.events.v1"
+
+const timestampType = "google.protobuf.Timestamp"
+
+func fullName(message string) string {
+	return protoPackage + "." + message
+}
+
+// codec encodes one event type as the message its schema describes.
+type codec struct {
+	schema schema.Schema
+	encode func(domain.Event) encoder
+	decode func([]byte) (domain.Event, error)
+}
+
+func newCodec[E domain.Event](message schema.Message, encode func(E) encoder, read func(f field, event *E) error, uses ...schema.Message) codec {
+	return codec{
+		schema: schema.Schema{Subject: message.Name, Messages: append([]schema.Message{message}, uses...)},
+		encode: func(event domain.Event) encoder { return encode(event.(E)) },
+		decode: func(b []byte) (domain.Event, error) {
+			var event E
+			err := decode(b, func(f field) error { return read(f, &event) })
+			return event, err
+		},
+	}
+}
+
+var codecs = map[domain.EventType]codec{
+	domain.OrderCreatedEventType: newCodec(orderCreatedMessage, encodeOrderCreated, readOrderCreated, orderItemMessage, moneyMessage),
+	domain.OrderConfirmedEventType: newCodec(statusMessage("OrderConfirmed", "confirmed_at"),
+		func(e domain.OrderConfirmedEvent) encoder { return encodeStatus(e.EventID, e.OrderID, e.ConfirmedAt) },
+		func(f field, e *domain.OrderConfirmedEvent) error {
+			return readStatus(f, &e.EventID, &e.OrderID, &e.ConfirmedAt)
+		}),
+	domain.OrderFailedEventType:    newCodec(orderFailedMessage, encodeOrderFailed, readOrderFailed),
+	domain.OrderCancelledEventType: newCodec(orderCancelledMessage, encodeOrderCancelled, readOrderCancelled),
+	domain.OrderAcceptedEventType:  newCodec(orderAcceptedMessage, encodeOrderAccepted, readOrderAccepted),
+	domain.OrderPreparingEventType: newCodec(statusMessage("OrderPreparing", "preparing_at"),
+		func(e domain.OrderPreparingEvent) encoder { return encodeStatus(e.EventID, e.OrderID, e.PreparingAt) },
+		func(f field, e *domain.OrderPreparingEvent) error {
+			return readStatus(f, &e.EventID, &e.OrderID, &e.PreparingAt)
+		}),
+	domain.OrderReadyEventType: newCodec(statusMessage("OrderReady", "ready_at"),
+		func(e domain.OrderReadyEvent) encoder { return encodeStatus(e.EventID, e.OrderID, e.ReadyAt) },
+		func(f field, e *domain.OrderReadyEvent) error {
+			return readStatus(f, &e.EventID, &e.OrderID, &e.ReadyAt)
+		}),
+	domain.OrderPickedUpEventType: newCodec(statusMessage("OrderPickedUp", "picked_up_at"),
+		func(e domain.OrderPickedUpEvent) encoder { return encodeStatus(e.EventID, e.OrderID, e.PickedUpAt) },
+		func(f field, e *domain.OrderPickedUpEvent) error {
+			return readStatus(f, &e.EventID, &e.OrderID, &e.PickedUpAt)
+		}),
+	domain.OrderOutForDeliveryEventType: newCodec(statusMessage("OrderOutForDelivery", "out_for_delivery_at"),
+		func(e domain.OrderOutForDeliveryEvent) encoder {
+			return encodeStatus(e.EventID, e.OrderID, e.OutForDeliveryAt)
+		},
+		func(f field, e *domain.OrderOutForDeliveryEvent) error {
+			return readStatus(f, &e.EventID, &e.OrderID, &e.OutForDeliveryAt)
+		}),
+	domain.OrderDeliveredEventType: newCodec(orderDeliveredMessage, encodeOrderDelivered, readOrderDelivered),
+}
+
+var moneyMessage = schema.Message{Name: fullName("Money"), Fields: []schema.Field{
+	{Number: 1, Name: "amount", Type: "int64"},
+	{Number: 2, Name: "currency", Type: "string"},
+}}
+
+var orderItemMessage = schema.Message{Name: fullName("OrderItem"), Fields: []schema.Field{
+	{Number: 1, Name: "id", Type: "string"},
+	{Number: 2, Name: "item_id", Type: "string"},
+	{Number: 3, Name: "name", Type: "string"},
+	{Number: 4, Name: "price", Type: moneyMessage.Name},
+	{Number: 5, Name: "quantity", Type: "int32"},
+}}
+
+func encodeOrderItem(item domain.OrderItem) encoder {
+	var b encoder
+	b.string(1, item.ID)
+	b.string(2, item.ItemID)
+	b.string(3, item.Name)
+	b.money(4, item.Price)
+	b.int32(5, int32(item.Quantity))
+	return b
+}
+
+func readOrderItem(f field, item *domain.OrderItem) error {
+	switch f.num {
+	case 1:
+		return f.string(&item.ID)
+	case 2:
+		return f.string(&item.ItemID)
+	case 3:
+		return f.string(&item.Name)
+	case 4:
+		return f.money(&item.Price)
+	case 5:
+		var quantity int32
+		err := f.int32(&quantity)
+		item.Quantity = int(quantity)
+		return err
+	}
+	return nil
+}
+
+var orderCreatedMessage = schema.Message{Name: fullName("OrderCreated"), Fields: []schema.Field{
+	{Number: 1, Name: "event_id", Type: "string"},
+	{Number: 2, Name: "order_id", Type: "string"},
+	{Number: 3, Name: "user_id", Type: "string"},
+	{Number: 4, Name: "restaurant_id", Type: "string"},
+	{Number: 5, Name: "items", Type: orderItemMessage.Name, Repeated: true},
+	{Number: 6, Name: "total_amount", Type: moneyMessage.Name},
+	{Number: 7, Name: "created_at", Type: timestampType},
+}}
+
+func encodeOrderCreated(e domain.OrderCreatedEvent) encoder {
+	var b encoder
+	b.string(1, e.EventID)
+	b.string(2, e.OrderID)
+	b.string(3, e.UserID)
+	b.string(4, e.RestaurantID)
+	for _, item := range e.Items {
+		b.message(5, encodeOrderItem(item))
+	}
+	b.money(6, e.TotalAmount)
+	b.time(7, e.CreatedAt)
+	return b
+}
+
+func readOrderCreated(f field, e *domain.OrderCreatedEvent) error {
+	switch f.num {
+	case 1:
+		return f.string(&e.EventID)
+	case 2:
+		return f.string(&e.OrderID)
+	case 3:
+		return f.string(&e.UserID)
+	case 4:
+		return f.string(&e.RestaurantID)
+	case 5:
+		var item domain.OrderItem
+		if err := f.message(func(f field) error { return readOrderItem(f, &item) }); err != nil {
+			return err
+		}
+		e.Items = append(e.Items, item)
+	case 6:
+		return f.money(&e.TotalAmount)
+	case 7:
+		return f.time(&e.CreatedAt)
+	}
+	return nil
+}
+
+// statusMessage describes the events that carry no more than their ID, the
+// order's and when the order got to the status.
+func statusMessage(name, at string) schema.Message {
+	return schema.Message{Name: fullName(name), Fields: []schema.Field{
+		{Number: 1, Name: "event_id", Type: "string"},
+		{Number: 2, Name: "order_id", Type: "string"},
+		{Number: 3, Name: at, Type: timestampType},
+	}}
+}
+
+func encodeStatus(eventID, orderID string, at time.Time) encoder {
+	var b encoder
+	b.string(1, eventID)
+	b.string(2, orderID)
+	b.time(3, at)
+	return b
+}
+
+func readStatus(f field, eventID, orderID *string, at *time.Time) error {
+	switch f.num {
+	case 1:
+		return f.string(eventID)
+	case 2:
+		return f.string(orderID)
+	case 3:
+		return f.time(at)
+	}
+	return nil
+}
+
+var orderFailedMessage = schema.Message{Name: fullName("OrderFailed"), Fields: []schema.Field{
+	{Number: 1, Name: "event_id", Type: "string"},
+	{Number: 2, Name: "order_id", Type: "string"},
+	{Number: 3, Name: "reason", Type: "string"},
+	{Number: 4, Name: "failed_at", Type: timestampType},
+}}
+
+func encodeOrderFailed(e domain.OrderFailedEvent) encoder {
+	var b encoder
+	b.string(1, e.EventID)
+	b.string(2, e.OrderID)
+	b.string(3, e.Reason)
+	b.time(4, e.FailedAt)
+	return b
+}
+
+func readOrderFailed(f field, e *domain.OrderFailedEvent) error {
+	switch f.num {
+	case 1:
+		return f.string(&e.EventID)
+	case 2:
+		return f.string(&e.OrderID)
+	case 3:
+		return f.string(&e.Reason)
+	case 4:
+		return f.time(&e.FailedAt)
+	}
+	return nil
+}
+
+var orderCancelledMessage = schema.Message{Name: fullName("OrderCancelled"), Fields: []schema.Field{
+	{Number: 1, Name: "event_id", Type: "string"},
+	{Number: 2, Name: "order_id", Type: "string"},
+	{Number: 3, Name: "reason", Type: "string"},
+	{Number: 4, Name: "cancelled_by", Type: "string"},
+	{Number: 5, Name: "cancelled_by_role", Type: "string"},
+	{Number: 6, Name: "cancelled_at", Type: timestampType},
+}}
+
+func encodeOrderCancelled(e domain.OrderCancelledEvent) encoder {
+	var b encoder
+	b.string(1, e.EventID)
+	b.string(2, e.OrderID)
+	b.string(3, e.Reason)
+	b.string(4, e.CancelledBy)
+	b.string(5, string(e.CancelledByRole))
+	b.time(6, e.CancelledAt)
+	return b
+}
+
+func readOrderCancelled(f field, e *domain.OrderCancelledEvent) error {
+	switch f.num {
+	case 1:
+		return f.string(&e.EventID)
+	case 2:
+		return f.string(&e.OrderID)
+	case 3:
+		return f.string(&e.Reason)
+	case 4:
+		return f.string(&e.CancelledBy)
+	case 5:
+		return f.string((*string)(&e.CancelledByRole))
+	case 6:
+		return f.time(&e.CancelledAt)
+	}
+	return nil
+}
+
+var orderAcceptedMessage = schema.Message{Name: fullName("OrderAccepted"), Fields: []schema.Field{
+	{Number: 1, Name: "event_id", Type: "string"},
+	{Number: 2, Name: "order_id", Type: "string"},
+	{Number: 3, Name: "restaurant_id", Type: "string"},
+	{Number: 4, Name: "accepted_at", Type: timestampType},
+}}
+
+func encodeOrderAccepted(e domain.OrderAcceptedEvent) encoder {
+	var b encoder
+	b.string(1, e.EventID)
+	b.string(2, e.OrderID)
+	b.string(3, e.RestaurantID)
+	b.time(4, e.AcceptedAt)
+	return b
+}
+
+func readOrderAccepted(f field, e *domain.OrderAcceptedEvent) error {
+	switch f.num {
+	case 1:
+		return f.string(&e.EventID)
+	case 2:
+		return f.string(&e.OrderID)
+	case 3:
+		return f.string(&e.RestaurantID)
+	case 4:
+		return f.time(&e.AcceptedAt)
+	}
+	return nil
+}
+
+var orderDeliveredMessage = schema.Message{Name: fullName("OrderDelivered"), Fields: []schema.Field{
+	{Number: 1, Name: "event_id", Type: "string"},
+	{Number: 2, Name: "order_id", Type: "string"},
+	{Number: 3, Name: "user_id", Type: "string"},
+	{Number: 4, Name: "delivered_at", Type: timestampType},
+}}
+
+func encodeOrderDelivered(e domain.OrderDeliveredEvent) encoder {
+	var b encoder
+	b.string(1, e.EventID)
+	b.string(2, e.OrderID)
+	b.string(3, e.UserID)
+	b.time(4, e.DeliveredAt)
+	return b
+}
+
+func readOrderDelivered(f field, e *domain.OrderDeliveredEvent) error {
+	switch f.num {
+	case 1:
+		return f.string(&e.EventID)
+	case 2:
+		return f.string(&e.OrderID)
+	case 3:
+		return f.string(&e.UserID)
+	case 4:
+		return f.time(&e.DeliveredAt)
+	}
+	return nil
+}
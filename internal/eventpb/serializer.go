@@ -0,0 +1,88 @@
+// Package eventpb serializes domain events as the protobuf messages of
+// proto/orderplatform/events/v1/events.proto. Payloads carry the registry ID
+// of their message's schema, which is registered on first use, so a build
+// whose messages break readers of the registered ones cannot publish.
+package eventpb
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
+	"github.com/dmehra2102/order-management-platform/internal/schema"
+)
+
+const ContentType = "application/protobuf"
+
+type Serializer struct {
+	registry *schema.Registry
+
+	mu  sync.Mutex
+	ids map[domain.EventType]uint32
+}
+
+func NewSerializer(registry *schema.Registry) *Serializer {
+	return &Serializer{
+		registry: registry,
+		ids:      make(map[domain.EventType]uint32),
+	}
+}
+
+func (s *Serializer) ContentType() string { return ContentType }
+
+func (s *Serializer) Serialize(event domain.Event) ([]byte, error) {
+	c, ok := codecs[event.EventType()]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", domain.ErrUnknownEventType, event.EventType())
+	}
+
+	id, err := s.schemaID(event.EventType(), c)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Frame(id, c.encode(event)), nil
+}
+
+// schemaID registers the schema of eventType once and returns its ID.
+func (s *Serializer) schemaID(eventType domain.EventType, c codec) (uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.ids[eventType]; ok {
+		return id, nil
+	}
+	id, err := s.registry.Register(c.schema)
+	if err != nil {
+		return 0, err
+	}
+	s.ids[eventType] = id
+	return id, nil
+}
+
+// Deserialize decodes data written with any registered schema of the message
+// of env's type.
+func (s *Serializer) Deserialize(env *envelope.Envelope) (domain.Event, error) {
+	c, ok := codecs[env.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", domain.ErrUnknownEventType, env.Type)
+	}
+
+	id, payload, err := schema.Unframe(env.Data)
+	if err != nil {
+		return nil, err
+	}
+	registered, err := s.registry.Lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	if registered.Subject != c.schema.Subject {
+		return nil, fmt.Errorf("%s event written with schema %d of %s", env.Type, id, registered.Subject)
+	}
+
+	event, err := c.decode(payload)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", registered.Subject, err)
+	}
+	return event, nil
+}
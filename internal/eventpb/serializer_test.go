@@ -0,0 +1,195 @@
+package eventpb
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
+	"github.com/dmehra2102/order-management-platform/internal/schema"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var at = time.Date(2025, 3, 14, 9, 26, 53, 589793238, time.UTC)
+
+// events holds one event of every type with all of its fields set.
+var events = []domain.Event{
+	domain.OrderCreatedEvent{
+		EventID: "e-1", OrderID: "order-1", UserID: "user-1", RestaurantID: "rest-1",
+		Items: []domain.OrderItem{
+			{ID: "line-1", ItemID: "item-1", Name: "Thali", Price: domain.NewMoney(24950, "INR"), Quantity: 2},
+			{ID: "line-2", ItemID: "item-2", Name: "Lassi", Price: domain.NewMoney(-100, "INR"), Quantity: 1},
+		},
+		TotalAmount: domain.NewMoney(49800, "INR"),
+		CreatedAt:   at,
+	},
+	domain.OrderConfirmedEvent{EventID: "e-2", OrderID: "order-1", ConfirmedAt: at},
+	domain.OrderFailedEvent{EventID: "e-3", OrderID: "order-1", Reason: "too small", FailedAt: at},
+	domain.OrderCancelledEvent{EventID: "e-4", OrderID: "order-1", Reason: "late", CancelledBy: "rest-1", CancelledByRole: domain.ActorRestaurant, CancelledAt: at},
+	domain.OrderAcceptedEvent{EventID: "e-5", OrderID: "order-1", RestaurantID: "rest-1", AcceptedAt: at},
+	domain.OrderPreparingEvent{EventID: "e-6", OrderID: "order-1", PreparingAt: at},
+	domain.OrderReadyEvent{EventID: "e-7", OrderID: "order-1", ReadyAt: at},
+	domain.OrderPickedUpEvent{EventID: "e-8", OrderID: "order-1", PickedUpAt: at},
+	domain.OrderOutForDeliveryEvent{EventID: "e-9", OrderID: "order-1", OutForDeliveryAt: at},
+	domain.OrderDeliveredEvent{EventID: "e-10", OrderID: "order-1", UserID: "user-1", DeliveredAt: at},
+}
+
+func TestSerializerRoundTripsEveryEvent(t *testing.T) {
+	s := NewSerializer(schema.NewRegistry())
+
+	for _, event := range events {
+		data, err := s.Serialize(event)
+		if err != nil {
+			t.Fatalf("Serialize %s: %v", event.EventType(), err)
+		}
+		got, err := s.Deserialize(&envelope.Envelope{Type: event.EventType(), Data: data})
+		if err != nil {
+			t.Fatalf("Deserialize %s: %v", event.EventType(), err)
+		}
+		if !reflect.DeepEqual(got, event) {
+			t.Errorf("%s round trip = %+v, want %+v", event.EventType(), got, event)
+		}
+	}
+
+	for eventType := range codecs {
+		if !slices.ContainsFunc(events, func(e domain.Event) bool { return e.EventType() == eventType }) {
+			t.Errorf("no %s event in the round trip", eventType)
+		}
+	}
+}
+
+func TestSerializerSkipsUnknownFields(t *testing.T) {
+	s := NewSerializer(schema.NewRegistry())
+	data, err := s.Serialize(events[1])
+	if err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+
+	// A field added by a newer writer
+	data = protowire.AppendTag(data, 15, protowire.BytesType)
+	data = protowire.AppendString(data, "courier-1")
+
+	got, err := s.Deserialize(&envelope.Envelope{Type: domain.OrderConfirmedEventType, Data: data})
+	if err != nil || !reflect.DeepEqual(got, events[1]) {
+		t.Errorf("Deserialize = %+v, %v; want %+v", got, err, events[1])
+	}
+}
+
+func TestTimestampsMatchWellKnownType(t *testing.T) {
+	var b encoder
+	b.time(1, at)
+	want, err := proto.Marshal(timestamppb.New(at))
+	if err != nil {
+		t.Fatalf("marshal timestamp: %v", err)
+	}
+
+	_, _, n := protowire.ConsumeTag(b)
+	got, _ := protowire.ConsumeBytes(b[n:])
+	if !bytes.Equal(got, want) {
+		t.Errorf("timestamp = %x, want %x", got, want)
+	}
+}
+
+func TestSerializerRejectsBreakingSchemas(t *testing.T) {
+	registry := schema.NewRegistry()
+	registered := codecs[domain.OrderConfirmedEventType].schema
+	registered.Messages = slices.Clone(registered.Messages)
+	registered.Messages[0].Fields = append(slices.Clone(registered.Messages[0].Fields), schema.Field{Number: 4, Name: "courier_id", Type: "string"})
+	if _, err := registry.Register(registered); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	// This build has no field 4, nor reserves it
+	_, err := NewSerializer(registry).Serialize(events[1])
+	if !errors.Is(err, schema.ErrIncompatible) {
+		t.Errorf("Serialize = %v, want ErrIncompatible", err)
+	}
+}
+
+func TestDeserializeChecksTheSchemaSubject(t *testing.T) {
+	s := NewSerializer(schema.NewRegistry())
+	data, err := s.Serialize(events[2])
+	if err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+
+	if _, err := s.Deserialize(&envelope.Envelope{Type: domain.OrderConfirmedEventType, Data: data}); err == nil {
+		t.Error("Deserialize read an OrderFailed payload as OrderConfirmed")
+	}
+	if _, err := s.Deserialize(&envelope.Envelope{Type: domain.OrderFailedEventType, Data: schema.Frame(42, nil)}); !errors.Is(err, schema.ErrUnknownSchema) {
+		t.Errorf("Deserialize with an unknown schema ID = %v, want ErrUnknownSchema", err)
+	}
+}
+
+// TestSchemasMatchProtoFile keeps the schemas the codecs register in step
+// with the .proto file published to other teams.
+func TestSchemasMatchProtoFile(t *testing.T) {
+	declared := parseProto(t, "../../proto/orderplatform/events/v1/events.proto")
+
+	for eventType, c := range codecs {
+		for _, m := range c.schema.Messages {
+			want, ok := declared[m.Name]
+			if !ok {
+				t.Errorf("%s: message %s is not in the .proto file", eventType, m.Name)
+				continue
+			}
+			if !reflect.DeepEqual(m.Fields, want) {
+				t.Errorf("%s: message %s has fields %v, the .proto file %v", eventType, m.Name, m.Fields, want)
+			}
+		}
+		if c.schema.Subject != fullName(string(eventType)) {
+			t.Errorf("%s is encoded as %s", eventType, c.schema.Subject)
+		}
+	}
+}
+
+var (
+	protoMessage = regexp.MustCompile(`^message (\w+) \{$`)
+	protoField   = regexp.MustCompile(`^(repeated )?([\w.]+) (\w+) = (\d+);$`)
+	protoScalars = []string{"string", "int32", "int64", "bool", "bytes", "double"}
+)
+
+// parseProto reads the fields of every message in the flat, one field per
+// line .proto file at path.
+func parseProto(t *testing.T, path string) map[string][]schema.Field {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open .proto file: %v", err)
+	}
+	defer f.Close()
+
+	messages := make(map[string][]schema.Field)
+	var current string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := protoMessage.FindStringSubmatch(line); m != nil {
+			current = fullName(m[1])
+			continue
+		}
+		if m := protoField.FindStringSubmatch(line); m != nil && current != "" {
+			typ := m[2]
+			if !slices.Contains(protoScalars, typ) && !strings.Contains(typ, ".") {
+				typ = fullName(typ)
+			}
+			number, _ := strconv.Atoi(m[4])
+			messages[current] = append(messages[current], schema.Field{Number: number, Name: m[3], Type: typ, Repeated: m[1] != ""})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read .proto file: %v", err)
+	}
+	return messages
+}
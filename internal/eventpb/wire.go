@@ -0,0 +1,172 @@
+package eventpb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// encoder appends fields in the protobuf wire format. As in proto3, zero
+// values are left out.
+type encoder []byte
+
+func (e *encoder) string(num protowire.Number, s string) {
+	if s == "" {
+		return
+	}
+	*e = protowire.AppendTag(*e, num, protowire.BytesType)
+	*e = protowire.AppendString(*e, s)
+}
+
+func (e *encoder) int64(num protowire.Number, v int64) {
+	if v == 0 {
+		return
+	}
+	*e = protowire.AppendTag(*e, num, protowire.VarintType)
+	*e = protowire.AppendVarint(*e, uint64(v))
+}
+
+func (e *encoder) int32(num protowire.Number, v int32) {
+	e.int64(num, int64(v))
+}
+
+func (e *encoder) message(num protowire.Number, m encoder) {
+	*e = protowire.AppendTag(*e, num, protowire.BytesType)
+	*e = protowire.AppendBytes(*e, m)
+}
+
+// time writes t as a google.protobuf.Timestamp.
+func (e *encoder) time(num protowire.Number, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	var ts encoder
+	ts.int64(1, t.Unix())
+	ts.int32(2, int32(t.Nanosecond()))
+	e.message(num, ts)
+}
+
+func (e *encoder) money(num protowire.Number, m domain.Money) {
+	if m == (domain.Money{}) {
+		return
+	}
+	var b encoder
+	b.int64(1, m.Amount)
+	b.string(2, m.Currency)
+	e.message(num, b)
+}
+
+// field is one field read from the wire. Fields of other wire types than
+// varint and length-delimited are skipped, having no counterpart here.
+type field struct {
+	num    protowire.Number
+	typ    protowire.Type
+	varint uint64
+	bytes  []byte
+}
+
+// decode calls fn for every field of the message in b. Unknown fields are
+// passed to fn too, which ignores them.
+func decode(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f field) want(typ protowire.Type) error {
+	if f.typ != typ {
+		return fmt.Errorf("field %d has wire type %d, want %d", f.num, f.typ, typ)
+	}
+	return nil
+}
+
+func (f field) string(dst *string) error {
+	if err := f.want(protowire.BytesType); err != nil {
+		return err
+	}
+	*dst = string(f.bytes)
+	return nil
+}
+
+func (f field) int64(dst *int64) error {
+	if err := f.want(protowire.VarintType); err != nil {
+		return err
+	}
+	*dst = int64(f.varint)
+	return nil
+}
+
+func (f field) int32(dst *int32) error {
+	if err := f.want(protowire.VarintType); err != nil {
+		return err
+	}
+	*dst = int32(f.varint)
+	return nil
+}
+
+// message reads f as an embedded message, calling fn for each of its fields.
+func (f field) message(fn func(f field) error) error {
+	if err := f.want(protowire.BytesType); err != nil {
+		return err
+	}
+	if err := decode(f.bytes, fn); err != nil {
+		return fmt.Errorf("field %d: %w", f.num, err)
+	}
+	return nil
+}
+
+func (f field) time(dst *time.Time) error {
+	var seconds int64
+	var nanos int32
+	err := f.message(func(f field) error {
+		switch f.num {
+		case 1:
+			return f.int64(&seconds)
+		case 2:
+			return f.int32(&nanos)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*dst = time.Unix(seconds, int64(nanos)).UTC()
+	return nil
+}
+
+func (f field) money(dst *domain.Money) error {
+	return f.message(func(f field) error {
+		switch f.num {
+		case 1:
+			return f.int64(&dst.Amount)
+		case 2:
+			return f.string(&dst.Currency)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,146 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Broker is the message transport behind Producer and Consumer. KafkaBroker
+// talks to a Kafka cluster; MemoryBroker keeps topics in process.
+type Broker interface {
+	// Writer returns a writer that places messages by key hash.
+	Writer() MessageWriter
+
+	// JoinGroup joins the consumer group groupID reading topics.
+	JoinGroup(groupID string, topics []string) (Group, error)
+
+	// Reader reads one partition from offset on, which may be kafka.FirstOffset.
+	Reader(topic string, partition int, offset int64) (MessageReader, error)
+}
+
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type MessageReader interface {
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+	Close() error
+}
+
+// Group is a consumer group membership. Next blocks until the group is
+// rebalanced and returns the generation this member takes part in; functions
+// started on the previous generation have exited by then.
+type Group interface {
+	Next(ctx context.Context) (Generation, error)
+	Close() error
+}
+
+// Generation is one assignment of partitions to the members of a group.
+// Functions passed to Start run until the generation ends.
+type Generation interface {
+	Assignments() []Assignment
+	Start(fn func(ctx context.Context))
+	CommitOffsets(offsets map[string]map[int]int64) error
+}
+
+// Assignment is a partition assigned to a group member, with the offset the
+// group committed for it or kafka.FirstOffset if none.
+type Assignment struct {
+	Topic     string
+	Partition int
+	Offset    int64
+}
+
+// KafkaBroker is a Broker backed by a Kafka cluster. MinBytes and MaxBytes
+// bound the size of each fetch made by readers.
+type KafkaBroker struct {
+	MinBytes int
+	MaxBytes int
+
+	brokers []string
+}
+
+var (
+	_ Broker = (*KafkaBroker)(nil)
+	_ Broker = (*MemoryBroker)(nil)
+)
+
+// NewKafkaBroker connects to a comma separated broker list such as
+// "host1:9092,host2:9092".
+func NewKafkaBroker(brokers string) *KafkaBroker {
+	return &KafkaBroker{
+		MinBytes: 10e3,
+		MaxBytes: 10e6,
+		brokers:  brokerList(brokers),
+	}
+}
+
+func (b *KafkaBroker) Writer() MessageWriter {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(b.brokers...),
+		RequiredAcks: kafka.RequireAll,
+		Balancer:     &kafka.Hash{},
+		Compression:  kafka.Snappy,
+	}
+}
+
+func (b *KafkaBroker) JoinGroup(groupID string, topics []string) (Group, error) {
+	group, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:      groupID,
+		Brokers: b.brokers,
+		Topics:  topics,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create consumer group: %w", err)
+	}
+	return &kafkaGroup{group: group}, nil
+}
+
+func (b *KafkaBroker) Reader(topic string, partition int, offset int64) (MessageReader, error) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   b.brokers,
+		Topic:     topic,
+		Partition: partition,
+		MinBytes:  b.MinBytes,
+		MaxBytes:  b.MaxBytes,
+	})
+
+	if err := reader.SetOffset(offset); err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("seek %s/%d to %d: %w", topic, partition, offset, err)
+	}
+	return reader, nil
+}
+
+type kafkaGroup struct {
+	group *kafka.ConsumerGroup
+}
+
+func (g *kafkaGroup) Next(ctx context.Context) (Generation, error) {
+	gen, err := g.group.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return kafkaGeneration{gen}, nil
+}
+
+func (g *kafkaGroup) Close() error {
+	return g.group.Close()
+}
+
+type kafkaGeneration struct {
+	*kafka.Generation
+}
+
+func (g kafkaGeneration) Assignments() []Assignment {
+	var assignments []Assignment
+	for topic, partitions := range g.Generation.Assignments {
+		for _, p := range partitions {
+			assignments = append(assignments, Assignment{Topic: topic, Partition: p.ID, Offset: p.Offset})
+		}
+	}
+	return assignments
+}
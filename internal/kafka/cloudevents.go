@@ -0,0 +1,140 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strconv"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
+	"github.com/dmehra2102/order-management-platform/internal/requestid"
+	"github.com/segmentio/kafka-go"
+)
+
+// Encodings of published events. Consumers read all of them, as well as the
+// bare event JSON published before envelopes.
+const (
+	EncodingEnvelope = "envelope"
+	// CloudEvents 1.0 with the attributes in ce_* headers and the data as value
+	EncodingCloudEventsBinary = "cloudevents-binary"
+	// CloudEvents 1.0 JSON with attributes and data in the value
+	EncodingCloudEventsStructured = "cloudevents-structured"
+)
+
+var Encodings = []string{EncodingEnvelope, EncodingCloudEventsBinary, EncodingCloudEventsStructured}
+
+// Serializations of event data. Protobuf data is only published with the
+// cloudevents-binary encoding.
+const (
+	SerializationJSON     = "json"
+	SerializationProtobuf = "protobuf"
+)
+
+var Serializations = []string{SerializationJSON, SerializationProtobuf}
+
+// ContentTypeHeader is the content type of a message value, as the CloudEvents
+// Kafka binding names it.
+const ContentTypeHeader = "content-type"
+
+// Binary-mode CloudEvents headers
+const (
+	ceHeaderPrefix        = "ce_"
+	ceSpecVersionHeader   = ceHeaderPrefix + "specversion"
+	ceIDHeader            = ceHeaderPrefix + "id"
+	ceSourceHeader        = ceHeaderPrefix + "source"
+	ceTypeHeader          = ceHeaderPrefix + "type"
+	ceTimeHeader          = ceHeaderPrefix + "time"
+	ceSchemaVersionHeader = ceHeaderPrefix + envelope.SchemaVersionExtension
+	ceCorrelationIDHeader = ceHeaderPrefix + envelope.CorrelationIDExtension
+)
+
+// encode builds the message publishing event to topic in p.Encoding. Only
+// binary CloudEvents carry data other than JSON.
+func (p *Producer) encode(ctx context.Context, topic string, event domain.Event) (kafka.Message, error) {
+	if p.Encoding != EncodingCloudEventsBinary && p.Serializer.ContentType() != envelope.DataContentType {
+		return kafka.Message{}, fmt.Errorf("%s data needs the %s encoding", p.Serializer.ContentType(), EncodingCloudEventsBinary)
+	}
+
+	env, err := envelope.New(p.Serializer, event, p.Source, requestid.FromContext(ctx))
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	msg := newMessage(topic, event.EventType(), event.AggregateID(), nil)
+	switch p.Encoding {
+	case EncodingCloudEventsBinary:
+		msg.Value = env.Data
+		msg.Headers = append(msg.Headers, binaryHeaders(envelope.NewCloudEvent(env))...)
+	case EncodingCloudEventsStructured:
+		msg.Value, err = json.Marshal(envelope.NewCloudEvent(env))
+		msg.Headers = append(msg.Headers, kafka.Header{Key: ContentTypeHeader, Value: []byte(envelope.CloudEventsContentType)})
+	default:
+		msg.Value, err = json.Marshal(env)
+	}
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("encode %s event: %w", event.EventType(), err)
+	}
+	return msg, nil
+}
+
+func binaryHeaders(ce envelope.CloudEvent) []kafka.Header {
+	headers := []kafka.Header{
+		{Key: ceSpecVersionHeader, Value: []byte(ce.SpecVersion)},
+		{Key: ceIDHeader, Value: []byte(ce.ID)},
+		{Key: ceSourceHeader, Value: []byte(ce.Source)},
+		{Key: ceTypeHeader, Value: []byte(ce.Type)},
+		{Key: ceSchemaVersionHeader, Value: []byte(strconv.Itoa(ce.SchemaVersion))},
+		{Key: ContentTypeHeader, Value: []byte(ce.DataContentType)},
+	}
+	if ce.Time != nil {
+		headers = append(headers, kafka.Header{Key: ceTimeHeader, Value: []byte(ce.Time.Format(time.RFC3339Nano))})
+	}
+	if ce.CorrelationID != "" {
+		headers = append(headers, kafka.Header{Key: ceCorrelationIDHeader, Value: []byte(ce.CorrelationID)})
+	}
+	return headers
+}
+
+// openMessage reads the envelope of msg in whichever encoding it was
+// published. eventType is the type of bare event JSON.
+func openMessage(msg kafka.Message, eventType domain.EventType) (*envelope.Envelope, error) {
+	if header(msg, ceSpecVersionHeader) != "" {
+		return openBinary(msg)
+	}
+	if mediaType, _, err := mime.ParseMediaType(header(msg, ContentTypeHeader)); err == nil && mediaType == envelope.CloudEventsContentType {
+		return envelope.ParseCloudEvent(msg.Value)
+	}
+	return envelope.Parse(msg.Value, eventType)
+}
+
+func openBinary(msg kafka.Message) (*envelope.Envelope, error) {
+	ce := envelope.CloudEvent{
+		SpecVersion:     header(msg, ceSpecVersionHeader),
+		ID:              header(msg, ceIDHeader),
+		Source:          header(msg, ceSourceHeader),
+		Type:            header(msg, ceTypeHeader),
+		DataContentType: header(msg, ContentTypeHeader),
+		CorrelationID:   header(msg, ceCorrelationIDHeader),
+		Data:            msg.Value,
+	}
+
+	if raw := header(msg, ceTimeHeader); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s header: %w", ceTimeHeader, err)
+		}
+		ce.Time = &t
+	}
+	if raw := header(msg, ceSchemaVersionHeader); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s header: %w", ceSchemaVersionHeader, err)
+		}
+		ce.SchemaVersion = v
+	}
+
+	return ce.Envelope()
+}
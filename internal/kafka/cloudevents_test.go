@@ -0,0 +1,169 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
+	"github.com/dmehra2102/order-management-platform/internal/eventpb"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/requestid"
+	"github.com/dmehra2102/order-management-platform/internal/schema"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestConsumerReadsEveryEncoding(t *testing.T) {
+	for _, encoding := range Encodings {
+		t.Run(encoding, func(t *testing.T) {
+			broker := NewMemoryBroker(1)
+			producer := NewProducer(broker, logger.New("ERROR"))
+			producer.Source = "order-api"
+			producer.Encoding = encoding
+
+			event := domain.NewOrderCancelledEvent("order-1", domain.Actor{ID: "user-1", Role: domain.ActorCustomer}, "changed my mind")
+			if err := producer.Publish(requestid.NewContext(context.Background(), "req-9"), event); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			msg := broker.Messages(OrderStatusTopic)[0]
+
+			d := NewDispatcher()
+			var got domain.OrderCancelledEvent
+			var env *envelope.Envelope
+			On(d, func(_ context.Context, delivery *Delivery, e domain.OrderCancelledEvent) error {
+				got, env = e, delivery.Envelope
+				return nil
+			})
+			if err := d.Dispatch(context.Background(), &Delivery{Message: msg}, eventType(msg)); err != nil {
+				t.Fatalf("Dispatch: %v", err)
+			}
+
+			if got.EventID != event.EventID || got.Reason != event.Reason {
+				t.Errorf("handled %+v, want %+v", got, event)
+			}
+			if env.ID != event.EventID || env.Source != "order-api" || env.CorrelationID != "req-9" || !env.Time.Equal(event.CancelledAt) {
+				t.Errorf("envelope = %+v, want the event's ID and time from order-api for req-9", env)
+			}
+		})
+	}
+}
+
+func TestProducerFollowsCloudEventsKafkaBinding(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	producer := NewProducer(broker, logger.New("ERROR"))
+	event := domain.NewOrderConfirmedEvent("order-1")
+
+	producer.Encoding = EncodingCloudEventsBinary
+	if err := producer.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	producer.Encoding = EncodingCloudEventsStructured
+	if err := producer.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	msgs := broker.Messages(OrderStatusTopic)
+
+	binary := msgs[0]
+	for key, want := range map[string]string{
+		ceSpecVersionHeader: "1.0",
+		ceIDHeader:          event.EventID,
+		ceSourceHeader:      DefaultSource,
+		ceTypeHeader:        string(domain.OrderConfirmedEventType),
+		ContentTypeHeader:   "application/json",
+	} {
+		if got := header(binary, key); got != want {
+			t.Errorf("binary header %s = %q, want %q", key, got, want)
+		}
+	}
+	var data domain.OrderConfirmedEvent
+	if err := json.Unmarshal(binary.Value, &data); err != nil || data.OrderID != "order-1" {
+		t.Errorf("binary value = %s, want the event itself", binary.Value)
+	}
+
+	structured := msgs[1]
+	if got := header(structured, ContentTypeHeader); got != "application/cloudevents+json" {
+		t.Errorf("structured content type = %q", got)
+	}
+	var ce map[string]any
+	if err := json.Unmarshal(structured.Value, &ce); err != nil {
+		t.Fatalf("structured value: %v", err)
+	}
+	if ce["specversion"] != "1.0" || ce["id"] != event.EventID || ce["type"] != string(domain.OrderConfirmedEventType) {
+		t.Errorf("structured event = %v", ce)
+	}
+}
+
+func TestDispatcherReadsCloudEventsFromOtherProducers(t *testing.T) {
+	d := NewDispatcher()
+	var got domain.OrderCreatedEvent
+	On(d, func(_ context.Context, _ *Delivery, e domain.OrderCreatedEvent) error {
+		got = e
+		return nil
+	})
+
+	// Binary mode without our event-type header or a schema version
+	msg := kafka.Message{
+		Value: []byte(`{"event_id":"e-1","order_id":"order-1","total_amount":"99.00"}`),
+		Headers: []kafka.Header{
+			{Key: "ce_specversion", Value: []byte("1.0")},
+			{Key: "ce_id", Value: []byte("e-1")},
+			{Key: "ce_source", Value: []byte("/storefront")},
+			{Key: "ce_type", Value: []byte("OrderCreated")},
+			{Key: "content-type", Value: []byte("application/json; charset=utf-8")},
+		},
+	}
+	if err := d.Dispatch(context.Background(), &Delivery{Message: msg}, eventType(msg)); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if want := domain.NewMoney(9900, domain.DefaultCurrency); got.OrderID != "order-1" || got.TotalAmount != want {
+		t.Errorf("handled %+v, want order-1 upcast from version 1", got)
+	}
+
+	var permanent *permanentError
+	msg.Headers[0].Value = []byte("0.3")
+	if err := d.Dispatch(context.Background(), &Delivery{Message: msg}, eventType(msg)); !errors.As(err, &permanent) {
+		t.Errorf("Dispatch spec version 0.3 = %v, want a permanent error", err)
+	}
+}
+
+func TestProtobufDataTravelsAsBinaryCloudEvents(t *testing.T) {
+	registry := schema.NewRegistry()
+	broker := NewMemoryBroker(1)
+	producer := NewProducer(broker, logger.New("ERROR"))
+	producer.Serializer = eventpb.NewSerializer(registry)
+
+	event := domain.NewOrderConfirmedEvent("order-1")
+	if err := producer.Publish(context.Background(), event); err == nil {
+		t.Error("Publish protobuf data in an envelope succeeded")
+	}
+
+	producer.Encoding = EncodingCloudEventsBinary
+	if err := producer.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	msg := broker.Messages(OrderStatusTopic)[0]
+	if got := header(msg, ContentTypeHeader); got != eventpb.ContentType {
+		t.Errorf("content type = %q, want %s", got, eventpb.ContentType)
+	}
+
+	var permanent *permanentError
+	d := NewDispatcher()
+	var got domain.OrderConfirmedEvent
+	On(d, func(_ context.Context, _ *Delivery, e domain.OrderConfirmedEvent) error {
+		got = e
+		return nil
+	})
+	if err := d.Dispatch(context.Background(), &Delivery{Message: msg}, eventType(msg)); !errors.As(err, &permanent) {
+		t.Errorf("Dispatch without a protobuf serializer = %v, want a permanent error", err)
+	}
+
+	d.Accept(eventpb.NewSerializer(registry))
+	if err := d.Dispatch(context.Background(), &Delivery{Message: msg}, eventType(msg)); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if got.EventID != event.EventID || !got.ConfirmedAt.Equal(event.ConfirmedAt) {
+		t.Errorf("handled %+v, want %+v", got, event)
+	}
+}
@@ -2,129 +2,512 @@ package kafka
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
 	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/metrics"
 	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/requestid"
+	"github.com/dmehra2102/order-management-platform/internal/rules"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// RetryPolicy controls how often a message that fails to process is retried
+// before it is dead-lettered. Delays double from InitialBackoff up to
+// MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+	}
+}
+
+// Consumer hands each message to the handler its Dispatcher registers for the
+// message's event type, exactly once per consumer group. Each message's
+// database changes and its partition offset are committed in one store
+// transaction, and partitions are read from the offsets stored there.
+//
+// Topics and Retry default to DefaultTopics and DefaultRetryPolicy and may be
+// changed before Start, as may Metrics, which defaults to unregistered
+// collectors.
 type Consumer struct {
-	reader *kafka.Reader
-	logger *logger.Logger
-	repo   *repository.OrderRepository
-	db     *sql.DB
-}
-
-func NewConsumer(brokers, groupID string, l *logger.Logger, repo *repository.OrderRepository, db *sql.DB) *Consumer {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:        []string{brokers},
-		GroupID:        groupID,
-		Topic:          OrdersTopic,
-		MinBytes:       10e3,
-		MaxBytes:       10e6,
-		CommitInterval: 1,
-	})
+	Topics  Topics
+	Retry   RetryPolicy
+	Metrics *metrics.Metrics
+
+	broker    Broker
+	groupID   string
+	group     Group
+	logger    *logger.Logger
+	repo      repository.OrderStore
+	publisher EventPublisher
+
+	dispatcher  *Dispatcher
+	readsOrders bool
+	rules       *rules.Engine
+}
 
+// NewConsumer returns the validating consumer. It reads the orders and order
+// status topics, validates OrderCreatedEvents and logs cancellations.
+func NewConsumer(broker Broker, groupID string, l *logger.Logger, repo repository.OrderStore, engine *rules.Engine, publisher EventPublisher) *Consumer {
+	c := newConsumer(broker, groupID, l, repo, publisher, NewDispatcher())
+	c.readsOrders = true
+	c.rules = engine
+
+	On(c.dispatcher, c.handleOrderCreated)
+	On(c.dispatcher, c.logOrderCancelled)
+	return c
+}
+
+// NewStatusConsumer returns a consumer that reads the order status topic only
+// and drives the reactions registered on dispatcher.
+func NewStatusConsumer(broker Broker, groupID string, l *logger.Logger, repo repository.OrderStore, publisher EventPublisher, dispatcher *Dispatcher) *Consumer {
+	return newConsumer(broker, groupID, l, repo, publisher, dispatcher)
+}
+
+// NewOrderEventsConsumer returns a consumer that reads both the orders and
+// the order status topics and drives the handlers registered on dispatcher.
+func NewOrderEventsConsumer(broker Broker, groupID string, l *logger.Logger, repo repository.OrderStore, publisher EventPublisher, dispatcher *Dispatcher) *Consumer {
+	c := newConsumer(broker, groupID, l, repo, publisher, dispatcher)
+	c.readsOrders = true
+	return c
+}
+
+func newConsumer(broker Broker, groupID string, l *logger.Logger, repo repository.OrderStore, publisher EventPublisher, dispatcher *Dispatcher) *Consumer {
 	return &Consumer{
-		reader: reader,
-		logger: l,
-		repo:   repo,
-		db:     db,
+		Topics:     DefaultTopics(),
+		Retry:      DefaultRetryPolicy(),
+		Metrics:    metrics.New(),
+		broker:     broker,
+		groupID:    groupID,
+		logger:     l,
+		repo:       repo,
+		publisher:  publisher,
+		dispatcher: dispatcher,
 	}
 }
 
+// Accept has c read data of the content type of s with s.
+func (c *Consumer) Accept(s envelope.Serializer) {
+	c.dispatcher.Accept(s)
+}
+
 func (c *Consumer) Start(ctx context.Context) error {
+	topics := []string{c.Topics.OrderStatus}
+	if c.readsOrders {
+		topics = append([]string{c.Topics.Orders}, topics...)
+	}
+	group, err := c.broker.JoinGroup(c.groupID, topics)
+	if err != nil {
+		return err
+	}
+	c.group = group
+
 	c.logger.Info("Consumer started", map[string]any{
-		"topic": OrdersTopic,
+		"topics": topics,
+		"group":  c.groupID,
 	})
 
 	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+		gen, err := group.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.logger.Error("Failed to join consumer group", map[string]any{
+				"error": err,
+			})
+			time.Sleep(time.Second)
+			continue
 		}
 
-		msg, err := c.reader.ReadMessage(ctx)
+		for _, assignment := range gen.Assignments() {
+			gen.Start(func(ctx context.Context) {
+				c.consumePartition(ctx, gen, assignment.Topic, assignment.Partition, assignment.Offset)
+			})
+		}
+	}
+}
+
+// consumePartition reads one assigned partition until the generation ends,
+// starting after the last offset recorded in the store. Failures to open or
+// read the partition are retried with backoff rather than leaving it stalled.
+func (c *Consumer) consumePartition(ctx context.Context, gen Generation, topic string, partition int, committed int64) {
+	reader, start, err := c.openPartition(ctx, topic, partition, committed)
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+
+	c.logger.Info("Partition assigned", map[string]any{
+		"topic":     topic,
+		"partition": partition,
+		"offset":    start,
+	})
+
+	failures := 0
+	for {
+		msg, err := reader.ReadMessage(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failures++
+			delay := c.Retry.backoff(failures)
+			c.Metrics.KafkaErrors.Inc()
 			c.logger.Error("Failed to read message", map[string]any{
-				"error": err,
+				"error":     err,
+				"topic":     topic,
+				"partition": partition,
+				"delay":     delay.String(),
 			})
+			if sleep(ctx, delay) != nil {
+				return
+			}
 			continue
 		}
+		failures = 0
 
-		c.handleOrderCreated(ctx, msg)
+		if err := c.process(ctx, msg); err != nil {
+			c.logger.WithContext(messageContext(ctx, msg)).Error("Failed to process message", map[string]any{
+				"error":     err,
+				"topic":     msg.Topic,
+				"partition": msg.Partition,
+				"offset":    msg.Offset,
+			})
+			continue
+		}
+		c.recordLag(msg)
+
+		// The store is the source of truth; the group commit only keeps lag visible
+		if err := gen.CommitOffsets(map[string]map[int]int64{
+			topic: {partition: msg.Offset + 1},
+		}); err != nil {
+			c.Metrics.KafkaErrors.Inc()
+			c.logger.Warn("Failed to commit Kafka offset", map[string]any{
+				"error":     err,
+				"topic":     topic,
+				"partition": partition,
+			})
+		}
 	}
 }
 
-func (c *Consumer) handleOrderCreated(ctx context.Context, msg kafka.Message) {
-	var event domain.OrderCreatedEvent
-	if err := json.Unmarshal(msg.Value, &event); err != nil {
-		c.logger.Error("Failed to unmarshal OrderCreatedEvent", map[string]any{
-			"error": err,
+// openPartition loads the partition's stored offset and seeks a reader to the
+// message after it, or to committed if none is stored. Both steps are retried
+// with backoff until they succeed or ctx is done.
+func (c *Consumer) openPartition(ctx context.Context, topic string, partition int, committed int64) (MessageReader, int64, error) {
+	for attempt := 1; ; attempt++ {
+		delay := c.Retry.backoff(attempt)
+
+		applied, found, err := c.repo.ConsumerOffset(ctx, c.groupID, topic, partition)
+		if err != nil {
+			c.Metrics.DBErrors.Inc()
+			c.logger.Error("Failed to load consumer offset", map[string]any{
+				"error":     err,
+				"topic":     topic,
+				"partition": partition,
+				"delay":     delay.String(),
+			})
+			if err := sleep(ctx, delay); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		start := committed
+		if found {
+			start = applied + 1
+		}
+
+		reader, err := c.broker.Reader(topic, partition, start)
+		if err != nil {
+			c.Metrics.KafkaErrors.Inc()
+			c.logger.Error("Failed to seek partition", map[string]any{
+				"error":     err,
+				"topic":     topic,
+				"partition": partition,
+				"offset":    start,
+				"delay":     delay.String(),
+			})
+			if err := sleep(ctx, delay); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		return reader, start, nil
+	}
+}
+
+// process handles msg, retrying failures with exponential backoff. Messages
+// that fail permanently or on every attempt are sent to the dead-letter topic
+// and their offset is recorded, so the partition moves on.
+func (c *Consumer) process(ctx context.Context, msg kafka.Message) (err error) {
+	ctx, span := startProcess(messageContext(ctx, msg), msg)
+	defer func() { tracing.End(span, err) }()
+
+	// Only validation counts as processing an order
+	if c.rules != nil && msg.Topic == c.Topics.Orders {
+		defer prometheus.NewTimer(c.Metrics.OrderProcessTime).ObserveDuration()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := c.handleMessage(ctx, msg)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if !errors.As(err, &permanent) {
+			c.Metrics.DBErrors.Inc()
+		}
+		if errors.As(err, &permanent) || attempt >= c.Retry.MaxAttempts {
+			return c.deadLetter(ctx, msg, err, attempt)
+		}
+
+		delay := c.Retry.backoff(attempt)
+		c.logger.WithContext(ctx).Warn("Retrying message", map[string]any{
+			"error":     err,
+			"topic":     msg.Topic,
+			"partition": msg.Partition,
+			"offset":    msg.Offset,
+			"attempt":   attempt,
+			"delay":     delay.String(),
 		})
-		return
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// deadLetter publishes msg to the dead-letter topic and records its offset.
+// Publishing is retried until it succeeds so that no message is skipped.
+func (c *Consumer) deadLetter(ctx context.Context, msg kafka.Message, cause error, attempts int) error {
+	c.logger.WithContext(ctx).Error("Dead-lettering message", map[string]any{
+		"error":     cause,
+		"topic":     msg.Topic,
+		"partition": msg.Partition,
+		"offset":    msg.Offset,
+		"attempts":  attempts,
+	})
+
+	for retry := 1; ; retry++ {
+		err := c.publisher.PublishDeadLetter(ctx, msg, cause, attempts)
+		if err == nil {
+			break
+		}
+		c.Metrics.KafkaErrors.Inc()
+		if err := sleep(ctx, c.Retry.backoff(retry)); err != nil {
+			return err
+		}
+	}
+
+	return c.repo.SaveConsumerOffset(ctx, c.groupID, msg.Topic, msg.Partition, msg.Offset)
+}
+
+// handleMessage applies msg and records its offset in one transaction. Messages
+// at or below the stored offset were already applied and are skipped, and
+// messages of unknown event types are reported and skipped.
+func (c *Consumer) handleMessage(ctx context.Context, msg kafka.Message) error {
+	eventType := c.messageType(msg)
+	delivery := &Delivery{Message: msg}
+
+	err := c.repo.RunInTx(ctx, func(tx repository.OrderStore) error {
+		delivery.Tx = tx
+
+		applied, found, err := tx.ConsumerOffset(ctx, c.groupID, msg.Topic, msg.Partition)
+		if err != nil {
+			return err
+		}
+		if found && msg.Offset <= applied {
+			c.logger.WithContext(ctx).Info("Skipping already processed message", map[string]any{
+				"topic":     msg.Topic,
+				"partition": msg.Partition,
+				"offset":    msg.Offset,
+			})
+			return nil
+		}
+
+		err = c.dispatcher.Dispatch(ctx, delivery, eventType)
+		if errors.Is(err, domain.ErrUnknownEventType) {
+			delivery.AfterCommit(func() {
+				c.Metrics.UnknownEvents.WithLabelValues(msg.Topic, string(eventType)).Inc()
+				c.logger.WithContext(ctx).Warn("Skipping message of unknown event type", map[string]any{
+					"event_type": eventType,
+					"topic":      msg.Topic,
+					"partition":  msg.Partition,
+					"offset":     msg.Offset,
+				})
+			})
+		} else if err != nil {
+			return err
+		}
+
+		return tx.SaveConsumerOffset(ctx, c.groupID, msg.Topic, msg.Partition, msg.Offset)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range delivery.afterCommit {
+		fn()
 	}
+	return nil
+}
 
-	c.logger.Info("Processing OrderCreatedEvent", map[string]any{
+// messageType returns the event type msg carries in its EventTypeHeader.
+// Messages on the orders topic from before the header existed are all
+// OrderCreatedEvents.
+func (c *Consumer) messageType(msg kafka.Message) domain.EventType {
+	eventType := eventType(msg)
+	if eventType == "" && msg.Topic == c.Topics.Orders {
+		return domain.OrderCreatedEventType
+	}
+	return eventType
+}
+
+func (c *Consumer) handleOrderCreated(ctx context.Context, d *Delivery, event domain.OrderCreatedEvent) (err error) {
+	ctx, span := tracer.Start(ctx, "Consumer.handleOrderCreated")
+	defer func() { tracing.End(span, err) }()
+
+	span.SetAttributes(attribute.String("order.id", event.OrderID))
+
+	c.logger.WithContext(ctx).Info("Processing OrderCreatedEvent", map[string]any{
 		"order_id":      event.OrderID,
 		"user_id":       event.UserID,
-		"total_amount":  event.TotalAmount,
+		"total_amount":  event.TotalAmount.String(),
+		"currency":      event.TotalAmount.Currency,
 		"restaurant_id": event.RestaurantID,
 	})
 
-	var newStatus domain.OrderStatus
+	order, err := d.Tx.GetOrderForUpdate(ctx, event.OrderID)
+	if err != nil {
+		return fmt.Errorf("load order %s: %w", event.OrderID, err)
+	}
 
-	if event.TotalAmount < 100 {
-		newStatus = domain.OrderStatusFailed
-		c.logger.Warn("Order rejected: amount too low", map[string]any{
+	if order.Status != domain.OrderStatusPending {
+		// The order moved on (e.g. it was cancelled) before it was validated
+		c.logger.WithContext(ctx).Warn("Order no longer awaiting validation", map[string]any{
 			"order_id": event.OrderID,
-			"amount":   event.TotalAmount,
+			"status":   order.Status,
 		})
-	} else if event.TotalAmount > 50000 {
-		newStatus = domain.OrderStatusFailed
-		c.logger.Warn("Order rejected: amount too high", map[string]any{
-			"order_id": event.OrderID,
-			"amount":   event.TotalAmount,
-		})
-	} else {
-		newStatus = domain.OrderStatusConfirmed
+		return nil
 	}
 
-	if err := c.repo.UpdateOrderStatus(ctx, event.OrderID, newStatus); err != nil {
-		c.logger.Error("Failed to update order status", map[string]any{
-			"error":    err,
-			"order_id": event.OrderID,
-		})
-		return
+	violations, err := c.rules.Evaluate(ctx, d.Tx, event)
+	if err != nil {
+		return fmt.Errorf("validate order %s: %w", event.OrderID, err)
 	}
 
-	// Publishing Event
-	producer := NewProducer("localhost:9092,localhost:9094", c.logger)
-	defer producer.Close()
+	// Status events go through the outbox, so they are published once
+	// the transaction commits and never for a rolled back update
+	newStatus := domain.OrderStatusConfirmed
+	var statusEvent domain.Event = domain.NewOrderConfirmedEvent(event.OrderID)
 
-	if newStatus == domain.OrderStatusConfirmed {
-		confirmEvent := domain.NewOrderConfirmedEvent(event.OrderID)
-		if err := producer.PublishOrderConfirmed(ctx, confirmEvent); err != nil {
-			c.logger.Error("Failed to publish confirmation", map[string]any{
-				"error":    err,
+	if len(violations) > 0 {
+		for _, v := range violations {
+			c.logger.WithContext(ctx).Warn("Order rejected", map[string]any{
 				"order_id": event.OrderID,
+				"rule":     v.Rule,
+				"code":     v.Code,
+				"detail":   v.Message,
 			})
 		}
-	} else {
-		failEvent := domain.NewOrderFailedEvent(event.OrderID, "Validation Failed")
-		if err := producer.PublishedOrderFailed(ctx, failEvent); err != nil {
-			c.logger.Error("Failed to publish failure", map[string]any{
-				"error":    err,
-				"order_id": event.OrderID,
-			})
+
+		newStatus = domain.OrderStatusFailed
+		statusEvent = domain.NewOrderFailedEvent(event.OrderID, rules.Reason(violations))
+	}
+
+	if err := d.Tx.UpdateOrderStatus(ctx, event.OrderID, order.Version, newStatus, statusEvent); err != nil {
+		return fmt.Errorf("update order %s status: %w", event.OrderID, err)
+	}
+
+	d.AfterCommit(func() {
+		if newStatus == domain.OrderStatusConfirmed {
+			c.Metrics.OrdersConfirmed.Inc()
+		} else {
+			c.Metrics.OrdersFailed.Inc()
 		}
+	})
+	return nil
+}
+
+// logOrderCancelled records a cancellation. Validation places no stock or
+// payment holds, so there is nothing to release.
+func (c *Consumer) logOrderCancelled(ctx context.Context, _ *Delivery, event domain.OrderCancelledEvent) error {
+	c.logger.WithContext(ctx).Info("Order cancelled", map[string]any{
+		"order_id":     event.OrderID,
+		"cancelled_by": event.CancelledBy,
+		"role":         event.CancelledByRole,
+		"reason":       event.Reason,
+	})
+	return nil
+}
+
+// recordLag sets the lag gauge of msg's partition to the messages behind it.
+func (c *Consumer) recordLag(msg kafka.Message) {
+	if msg.HighWaterMark <= 0 {
+		return
+	}
+	lag := max(msg.HighWaterMark-msg.Offset-1, 0)
+	c.Metrics.ConsumerLag.WithLabelValues(c.groupID, msg.Topic, strconv.Itoa(msg.Partition)).Set(float64(lag))
+}
+
+// messageContext returns ctx carrying the request ID msg was published with,
+// so that logs and events written while handling it can be traced back to
+// the request.
+func messageContext(ctx context.Context, msg kafka.Message) context.Context {
+	return requestid.NewContext(ctx, header(msg, RequestIDHeader))
+}
+
+// permanentError marks failures that retrying cannot fix, such as payloads
+// that do not decode.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// backoff returns the delay before retry number attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxBackoff)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
 }
 
 func (c *Consumer) Close() error {
-	return c.reader.Close()
+	if c.group == nil {
+		return nil
+	}
+	return c.group.Close()
 }
@@ -0,0 +1,388 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/repository/memstore"
+	"github.com/dmehra2102/order-management-platform/internal/requestid"
+	"github.com/dmehra2102/order-management-platform/internal/rules"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+)
+
+const testGroup = "order-processor-test"
+
+type consumerFixture struct {
+	consumer  *Consumer
+	store     *memstore.Store
+	publisher *MemoryPublisher
+}
+
+func newConsumerFixture(t *testing.T) *consumerFixture {
+	t.Helper()
+
+	store := memstore.New()
+	engine, err := rules.DefaultConfig().Build()
+	if err != nil {
+		t.Fatalf("build rules: %v", err)
+	}
+	publisher := NewMemoryPublisher()
+
+	return &consumerFixture{
+		consumer:  NewConsumer(NewMemoryBroker(1), testGroup, logger.New("ERROR"), store, engine, publisher),
+		store:     store,
+		publisher: publisher,
+	}
+}
+
+// createOrder stores a pending order as the API would and returns the message
+// the relay would publish for it.
+func (f *consumerFixture) createOrder(t *testing.T, price domain.Money, offset int64) (*domain.Order, kafka.Message) {
+	t.Helper()
+
+	order, err := domain.NewOrder("user-1", "rest-1", []domain.OrderItem{
+		{ID: "line-1", ItemID: "item-1", Name: "Thali", Price: price, Quantity: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewOrder: %v", err)
+	}
+
+	event := domain.NewOrderCreatedEvent(order)
+	if err := f.store.CreateOrder(context.Background(), order, event); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	msg := delivery(t, event).Message
+	msg.Topic = OrdersTopic
+	msg.Offset = offset
+	return order, msg
+}
+
+func (f *consumerFixture) order(t *testing.T, id string) *domain.Order {
+	t.Helper()
+	order, err := f.store.GetOrder(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	return order
+}
+
+func TestConsumerConfirmsValidOrder(t *testing.T) {
+	f := newConsumerFixture(t)
+	order, msg := f.createOrder(t, domain.NewMoney(45000, "INR"), 0)
+
+	if err := f.consumer.process(context.Background(), msg); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	if got := f.order(t, order.ID); got.Status != domain.OrderStatusConfirmed || got.Version != 2 {
+		t.Errorf("order is %s at version %d, want CONFIRMED at 2", got.Status, got.Version)
+	}
+
+	offset, found, err := f.store.ConsumerOffset(context.Background(), testGroup, OrdersTopic, 0)
+	if err != nil || !found || offset != 0 {
+		t.Errorf("stored offset = %d, %v, %v; want 0", offset, found, err)
+	}
+}
+
+func TestConsumerFailsOrderBreakingRules(t *testing.T) {
+	f := newConsumerFixture(t)
+	order, msg := f.createOrder(t, domain.NewMoney(5000, "INR"), 0)
+
+	if err := f.consumer.process(context.Background(), msg); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	if got := f.order(t, order.ID); got.Status != domain.OrderStatusFailed {
+		t.Errorf("status = %s, want FAILED", got.Status)
+	}
+
+	events, err := f.store.OrderEvents(context.Background(), order.ID)
+	if err != nil {
+		t.Fatalf("OrderEvents: %v", err)
+	}
+	failed, ok := events[len(events)-1].Event.(domain.OrderFailedEvent)
+	if !ok || failed.Reason != rules.CodeAmountBelowMinimum {
+		t.Errorf("last event = %+v, want OrderFailedEvent with reason %s", events[len(events)-1].Event, rules.CodeAmountBelowMinimum)
+	}
+}
+
+func TestConsumerSkipsRedeliveredMessages(t *testing.T) {
+	f := newConsumerFixture(t)
+	order, msg := f.createOrder(t, domain.NewMoney(45000, "INR"), 7)
+	ctx := context.Background()
+
+	for range 3 {
+		if err := f.consumer.process(ctx, msg); err != nil {
+			t.Fatalf("process: %v", err)
+		}
+	}
+
+	events, err := f.store.OrderEvents(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("OrderEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("%d events recorded, want 2", len(events))
+	}
+
+	// Offsets at or below the stored one count as already applied
+	olderOrder, older := f.createOrder(t, domain.NewMoney(45000, "INR"), 3)
+	if err := f.consumer.process(ctx, older); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if got := f.order(t, olderOrder.ID); got.Status != domain.OrderStatusPending {
+		t.Errorf("message below the stored offset moved the order to %s", got.Status)
+	}
+}
+
+func TestConsumerLeavesOrdersThatMovedOn(t *testing.T) {
+	f := newConsumerFixture(t)
+	order, msg := f.createOrder(t, domain.NewMoney(45000, "INR"), 0)
+	ctx := context.Background()
+
+	cancel := domain.NewOrderCancelledEvent(order.ID, domain.Actor{ID: "user-1", Role: domain.ActorCustomer}, "ordered twice")
+	if err := f.store.UpdateOrderStatus(ctx, order.ID, order.Version, domain.OrderStatusCancelled, cancel); err != nil {
+		t.Fatalf("cancel order: %v", err)
+	}
+
+	if err := f.consumer.process(ctx, msg); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	if got := f.order(t, order.ID); got.Status != domain.OrderStatusCancelled {
+		t.Errorf("status = %s, want CANCELLED", got.Status)
+	}
+	if _, found, _ := f.store.ConsumerOffset(ctx, testGroup, OrdersTopic, 0); !found {
+		t.Error("offset of the skipped message was not stored")
+	}
+}
+
+func TestConsumerDeadLettersUndecodableMessages(t *testing.T) {
+	f := newConsumerFixture(t)
+	msg := newMessage(OrdersTopic, domain.OrderCreatedEventType, "order-1", []byte("{not json"))
+	msg.Offset = 4
+
+	if err := f.consumer.process(context.Background(), msg); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	dead := f.publisher.DeadLetters()
+	if len(dead) != 1 || dead[0].Attempts != 1 || dead[0].Message.Offset != 4 {
+		t.Fatalf("dead letters = %+v, want the message after one attempt", dead)
+	}
+
+	offset, found, err := f.store.ConsumerOffset(context.Background(), testGroup, OrdersTopic, 0)
+	if err != nil || !found || offset != 4 {
+		t.Errorf("stored offset = %d, %v, %v; want 4", offset, found, err)
+	}
+}
+
+func TestConsumerIgnoresOtherStatusEvents(t *testing.T) {
+	f := newConsumerFixture(t)
+	msg := delivery(t, domain.NewOrderConfirmedEvent("order-1")).Message
+
+	if err := f.consumer.process(context.Background(), msg); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(f.publisher.DeadLetters()) != 0 {
+		t.Error("status event was dead-lettered")
+	}
+}
+
+func TestConsumerCarriesRequestIDToNewEvents(t *testing.T) {
+	f := newConsumerFixture(t)
+	order, msg := f.createOrder(t, domain.NewMoney(45000, "INR"), 0)
+	msg.Headers = append(msg.Headers, kafka.Header{Key: RequestIDHeader, Value: []byte("req-42")})
+
+	if err := f.consumer.process(context.Background(), msg); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	correlation := make(map[domain.EventType]string)
+	_, err := f.store.PublishPendingEvents(context.Background(), 10, func(_ context.Context, event repository.OutboxEvent) error {
+		if event.AggregateID == order.ID {
+			correlation[event.EventType] = event.CorrelationID
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("PublishPendingEvents: %v", err)
+	}
+	if got := correlation[domain.OrderConfirmedEventType]; got != "req-42" {
+		t.Errorf("confirmed event correlation ID = %q, want req-42", got)
+	}
+}
+
+func TestProducerTagsMessagesWithRequestID(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	producer := NewProducer(broker, logger.New("ERROR"))
+	ctx := requestid.NewContext(context.Background(), "req-7")
+
+	if err := producer.Publish(ctx, domain.NewOrderConfirmedEvent("order-1")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := producer.Publish(context.Background(), domain.NewOrderConfirmedEvent("order-2")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	msgs := broker.Messages(OrderStatusTopic)
+	if len(msgs) != 2 {
+		t.Fatalf("%d messages written, want 2", len(msgs))
+	}
+	if got := header(msgs[0], RequestIDHeader); got != "req-7" {
+		t.Errorf("request ID header = %q, want req-7", got)
+	}
+	if got := header(msgs[1], RequestIDHeader); got != "" {
+		t.Errorf("message published without a request ID has header %q", got)
+	}
+
+	// Dead letters keep the request ID of the original message only once
+	if err := producer.PublishDeadLetter(ctx, msgs[0], errors.New("boom"), 1); err != nil {
+		t.Fatalf("PublishDeadLetter: %v", err)
+	}
+	var ids int
+	for _, h := range broker.Messages(DeadLetterTopic)[0].Headers {
+		if h.Key == RequestIDHeader {
+			ids++
+		}
+	}
+	if ids != 1 {
+		t.Errorf("dead letter has %d request ID headers, want 1", ids)
+	}
+}
+
+func TestConsumerRecordsMetrics(t *testing.T) {
+	f := newConsumerFixture(t)
+	_, valid := f.createOrder(t, domain.NewMoney(45000, "INR"), 0)
+	_, invalid := f.createOrder(t, domain.NewMoney(5000, "INR"), 1)
+
+	for _, msg := range []kafka.Message{valid, invalid, valid} {
+		if err := f.consumer.process(context.Background(), msg); err != nil {
+			t.Fatalf("process: %v", err)
+		}
+	}
+
+	m := f.consumer.Metrics
+	if got := testutil.ToFloat64(m.OrdersConfirmed); got != 1 {
+		t.Errorf("confirmed = %v, want 1 as the redelivery is skipped", got)
+	}
+	if got := testutil.ToFloat64(m.OrdersFailed); got != 1 {
+		t.Errorf("failed = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.OrderProcessTime); got != 1 {
+		t.Errorf("%d process time series, want 1", got)
+	}
+
+	invalid.HighWaterMark = 10
+	f.consumer.recordLag(invalid)
+	if got := testutil.ToFloat64(m.ConsumerLag.WithLabelValues(testGroup, OrdersTopic, "0")); got != 8 {
+		t.Errorf("lag = %v, want 8 messages after offset 1", got)
+	}
+}
+
+func TestConsumerSkipsUnknownEventTypes(t *testing.T) {
+	f := newConsumerFixture(t)
+	msg := newMessage(OrderStatusTopic, "OrderTeleported", "order-1", []byte(`{"order_id":"order-1"}`))
+	msg.Offset = 3
+
+	if err := f.consumer.process(context.Background(), msg); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	if len(f.publisher.DeadLetters()) != 0 {
+		t.Error("unknown event type was dead-lettered")
+	}
+	offset, found, err := f.store.ConsumerOffset(context.Background(), testGroup, OrderStatusTopic, 0)
+	if err != nil || !found || offset != 3 {
+		t.Errorf("stored offset = %d, %v, %v; want 3", offset, found, err)
+	}
+	if got := testutil.ToFloat64(f.consumer.Metrics.UnknownEvents.WithLabelValues(OrderStatusTopic, "OrderTeleported")); got != 1 {
+		t.Errorf("unknown events = %v, want 1", got)
+	}
+}
+
+// flakyBroker fails the first readerFailures calls to Reader, and hands out
+// readers whose first readFailures reads fail.
+type flakyBroker struct {
+	*MemoryBroker
+	readerFailures int
+	readFailures   int
+}
+
+func (b *flakyBroker) Reader(topic string, partition int, offset int64) (MessageReader, error) {
+	if b.readerFailures > 0 {
+		b.readerFailures--
+		return nil, errors.New("broker unavailable")
+	}
+	reader, err := b.MemoryBroker.Reader(topic, partition, offset)
+	return &flakyReader{MessageReader: reader, failures: b.readFailures}, err
+}
+
+type flakyReader struct {
+	MessageReader
+	failures int
+}
+
+func (r *flakyReader) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	if r.failures > 0 {
+		r.failures--
+		return kafka.Message{}, errors.New("connection reset")
+	}
+	return r.MessageReader.ReadMessage(ctx)
+}
+
+type recordingGeneration struct {
+	committed chan int64
+}
+
+func (g *recordingGeneration) Assignments() []Assignment          { return nil }
+func (g *recordingGeneration) Start(fn func(ctx context.Context)) {}
+func (g *recordingGeneration) CommitOffsets(offsets map[string]map[int]int64) error {
+	g.committed <- offsets[OrdersTopic][0]
+	return nil
+}
+
+func TestConsumerRetriesFailingPartition(t *testing.T) {
+	f := newConsumerFixture(t)
+	broker := &flakyBroker{MemoryBroker: NewMemoryBroker(1), readerFailures: 2, readFailures: 2}
+	f.consumer.broker = broker
+	f.consumer.Retry.InitialBackoff = time.Millisecond
+
+	order, msg := f.createOrder(t, domain.NewMoney(45000, "INR"), 0)
+	if err := broker.Writer().WriteMessages(context.Background(), msg); err != nil {
+		t.Fatalf("WriteMessages: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gen := &recordingGeneration{committed: make(chan int64, 1)}
+	done := make(chan struct{})
+	go func() {
+		f.consumer.consumePartition(ctx, gen, OrdersTopic, 0, kafka.FirstOffset)
+		close(done)
+	}()
+
+	select {
+	case offset := <-gen.committed:
+		if offset != 1 {
+			t.Errorf("committed offset %d, want 1", offset)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("partition stalled after failing to open and read")
+	}
+	cancel()
+	<-done
+
+	if got := f.order(t, order.ID).Status; got != domain.OrderStatusConfirmed {
+		t.Errorf("order is %s, want CONFIRMED", got)
+	}
+	if got := testutil.ToFloat64(f.consumer.Metrics.KafkaErrors); got != 4 {
+		t.Errorf("kafka errors = %v, want 4", got)
+	}
+}
@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/segmentio/kafka-go"
+)
+
+// Delivery is a message being handled, with the store transaction that its
+// changes and its offset are committed in. Envelope is set once the message
+// has been decoded.
+type Delivery struct {
+	Message  kafka.Message
+	Tx       repository.OrderStore
+	Envelope *envelope.Envelope
+
+	afterCommit []func()
+}
+
+// AfterCommit registers fn to run once the transaction has committed, for
+// effects that must not happen for a rolled back attempt.
+func (d *Delivery) AfterCommit(fn func()) {
+	d.afterCommit = append(d.afterCommit, fn)
+}
+
+// Handler reacts to one decoded event. Errors are retried, except those
+// marked permanent by Permanent.
+type Handler func(ctx context.Context, d *Delivery, event domain.Event) error
+
+// Dispatcher routes messages to the handler registered for their event type.
+// Handlers are registered with On before the consumer starts. Data is read by
+// the serializer of its content type, JSON unless others are accepted.
+type Dispatcher struct {
+	handlers    map[domain.EventType]Handler
+	serializers map[string]envelope.Serializer
+}
+
+func NewDispatcher() *Dispatcher {
+	d := &Dispatcher{
+		handlers:    make(map[domain.EventType]Handler),
+		serializers: make(map[string]envelope.Serializer),
+	}
+	d.Accept(envelope.JSON{})
+	return d
+}
+
+// Accept has d read data of the content type of s with s, replacing any
+// earlier serializer for it.
+func (d *Dispatcher) Accept(s envelope.Serializer) {
+	d.serializers[s.ContentType()] = s
+}
+
+// On registers fn for events of type E, replacing any earlier handler.
+func On[E domain.Event](d *Dispatcher, fn func(ctx context.Context, delivery *Delivery, event E) error) {
+	var zero E
+	d.handlers[zero.EventType()] = func(ctx context.Context, delivery *Delivery, event domain.Event) error {
+		return fn(ctx, delivery, event.(E))
+	}
+}
+
+// Handles reports whether a handler is registered for eventType.
+func (d *Dispatcher) Handles(eventType domain.EventType) bool {
+	_, ok := d.handlers[eventType]
+	return ok
+}
+
+// Dispatch decodes the delivered message and calls the handler of its event
+// type. eventType is the type of bare payloads published before envelopes.
+// Known types without a handler are ignored; types unknown to this version
+// fail with an error matching domain.ErrUnknownEventType, and payloads that
+// do not decode or upcast with a permanent error.
+func (d *Dispatcher) Dispatch(ctx context.Context, delivery *Delivery, eventType domain.EventType) error {
+	env, err := openMessage(delivery.Message, eventType)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	handler, ok := d.handlers[env.Type]
+	if !ok {
+		if !env.Type.Known() {
+			return fmt.Errorf("%w %q", domain.ErrUnknownEventType, env.Type)
+		}
+		return nil
+	}
+
+	serializer, ok := d.serializers[env.DataContentType]
+	if !ok {
+		return Permanent(fmt.Errorf("no serializer for %s data", env.DataContentType))
+	}
+	event, err := serializer.Deserialize(env)
+	if err != nil {
+		return Permanent(err)
+	}
+	delivery.Envelope = env
+	return handler(ctx, delivery, event)
+}
+
+// Permanent marks err as a failure that retrying cannot fix, so the message
+// is dead-lettered at once.
+func Permanent(err error) error {
+	return &permanentError{err}
+}
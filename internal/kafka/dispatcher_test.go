@@ -0,0 +1,103 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
+	"github.com/segmentio/kafka-go"
+)
+
+func delivery(t *testing.T, event domain.Event) *Delivery {
+	t.Helper()
+	env, err := envelope.New(envelope.JSON{}, event, "test", "")
+	if err != nil {
+		t.Fatalf("wrap event: %v", err)
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("marshal envelope: %v", err)
+	}
+	return &Delivery{Message: newMessage(OrderStatusTopic, event.EventType(), event.AggregateID(), payload)}
+}
+
+func TestDispatcherRoutesByEventType(t *testing.T) {
+	d := NewDispatcher()
+	var confirmed []string
+	On(d, func(_ context.Context, _ *Delivery, event domain.OrderConfirmedEvent) error {
+		confirmed = append(confirmed, event.OrderID)
+		return nil
+	})
+
+	if err := d.Dispatch(context.Background(), delivery(t, domain.NewOrderConfirmedEvent("order-1")), domain.OrderConfirmedEventType); err != nil {
+		t.Fatalf("Dispatch confirmed: %v", err)
+	}
+	if len(confirmed) != 1 || confirmed[0] != "order-1" {
+		t.Errorf("handled %v, want order-1", confirmed)
+	}
+
+	// Known types without a handler are none of this consumer's business
+	if err := d.Dispatch(context.Background(), delivery(t, domain.NewOrderFailedEvent("order-2", "too small")), domain.OrderFailedEventType); err != nil {
+		t.Errorf("Dispatch failed event = %v, want it ignored", err)
+	}
+}
+
+func TestDispatcherReportsUnknownAndUndecodableEvents(t *testing.T) {
+	d := NewDispatcher()
+	On(d, func(context.Context, *Delivery, domain.OrderConfirmedEvent) error { return nil })
+
+	err := d.Dispatch(context.Background(), &Delivery{Message: kafka.Message{Value: []byte(`{}`)}}, "OrderTeleported")
+	if !errors.Is(err, domain.ErrUnknownEventType) {
+		t.Errorf("Dispatch unknown type = %v, want ErrUnknownEventType", err)
+	}
+
+	var permanent *permanentError
+	err = d.Dispatch(context.Background(), &Delivery{Message: kafka.Message{Value: []byte(`{`)}}, domain.OrderConfirmedEventType)
+	if !errors.As(err, &permanent) {
+		t.Errorf("Dispatch undecodable payload = %v, want a permanent error", err)
+	}
+}
+
+func TestDispatcherUpcastsLegacyPayloads(t *testing.T) {
+	d := NewDispatcher()
+	var got domain.OrderCreatedEvent
+	var version int
+	On(d, func(_ context.Context, delivery *Delivery, event domain.OrderCreatedEvent) error {
+		got, version = event, delivery.Envelope.SchemaVersion
+		return nil
+	})
+
+	// Published before envelopes and currencies, typed by its header only
+	bare := []byte(`{"event_id":"e-1","order_id":"order-1","total_amount":"249.50","created_at":"2024-01-01T00:00:00Z"}`)
+	msg := newMessage(OrdersTopic, domain.OrderCreatedEventType, "order-1", bare)
+	if err := d.Dispatch(context.Background(), &Delivery{Message: msg}, domain.OrderCreatedEventType); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if want := domain.NewMoney(24950, domain.DefaultCurrency); got.TotalAmount != want {
+		t.Errorf("total = %+v, want %+v", got.TotalAmount, want)
+	}
+	if version != envelope.Default.Version(domain.OrderCreatedEventType) {
+		t.Errorf("handler saw version %d, want the current one", version)
+	}
+}
+
+func TestDispatcherRejectsNewerSchemaVersions(t *testing.T) {
+	d := NewDispatcher()
+	On(d, func(context.Context, *Delivery, domain.OrderConfirmedEvent) error {
+		t.Error("handler called for an unreadable schema version")
+		return nil
+	})
+
+	delivery := delivery(t, domain.NewOrderConfirmedEvent("order-1"))
+	delivery.Message.Value = bytes.Replace(delivery.Message.Value, []byte(`"schema_version":1`), []byte(`"schema_version":9`), 1)
+
+	var permanent *permanentError
+	err := d.Dispatch(context.Background(), delivery, domain.OrderConfirmedEventType)
+	if !errors.As(err, &permanent) || !errors.Is(err, envelope.ErrNewerSchema) {
+		t.Errorf("Dispatch = %v, want a permanent ErrNewerSchema", err)
+	}
+}
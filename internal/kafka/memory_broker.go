@@ -0,0 +1,354 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+var ErrBrokerClosed = errors.New("broker closed")
+
+// MemoryBroker is an in-process Broker for local runs and tests. Topics are
+// created on first use with a fixed number of partitions, messages are placed
+// by key hash like the Kafka writer, and consumer groups split partitions
+// between members and keep committed offsets. Nothing is persisted.
+type MemoryBroker struct {
+	mu         sync.Mutex
+	partitions int
+	topics     map[string]*memoryTopic
+	groups     map[string]*memoryGroup
+	nextMember int
+}
+
+type memoryTopic struct {
+	partitions [][]kafka.Message
+	// written is closed and replaced whenever a message is appended
+	written chan struct{}
+}
+
+func NewMemoryBroker(partitions int) *MemoryBroker {
+	return &MemoryBroker{
+		partitions: max(partitions, 1),
+		topics:     make(map[string]*memoryTopic),
+		groups:     make(map[string]*memoryGroup),
+	}
+}
+
+// topic returns the named topic, creating it if needed. b.mu must be held.
+func (b *MemoryBroker) topic(name string) *memoryTopic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &memoryTopic{
+			partitions: make([][]kafka.Message, b.partitions),
+			written:    make(chan struct{}),
+		}
+		b.topics[name] = t
+	}
+	return t
+}
+
+func (b *MemoryBroker) Writer() MessageWriter {
+	return &memoryWriter{broker: b}
+}
+
+func (b *MemoryBroker) Reader(topic string, partition int, offset int64) (MessageReader, error) {
+	if partition < 0 || partition >= b.partitions {
+		return nil, fmt.Errorf("topic %s has no partition %d", topic, partition)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch offset {
+	case kafka.FirstOffset:
+		offset = 0
+	case kafka.LastOffset:
+		offset = int64(len(b.topic(topic).partitions[partition]))
+	}
+	return &memoryReader{broker: b, topic: topic, partition: partition, offset: offset}, nil
+}
+
+// Messages returns a copy of everything written to a topic, partition by
+// partition.
+func (b *MemoryBroker) Messages(topic string) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var msgs []kafka.Message
+	for _, partition := range b.topic(topic).partitions {
+		msgs = append(msgs, partition...)
+	}
+	return msgs
+}
+
+type memoryWriter struct {
+	broker   *MemoryBroker
+	balancer kafka.Hash
+}
+
+func (w *memoryWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b := w.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	partitions := make([]int, b.partitions)
+	for i := range partitions {
+		partitions[i] = i
+	}
+
+	for _, msg := range msgs {
+		t := b.topic(msg.Topic)
+		p := w.balancer.Balance(msg, partitions...)
+
+		msg.Partition = p
+		msg.Offset = int64(len(t.partitions[p]))
+		msg.Time = time.Now()
+		t.partitions[p] = append(t.partitions[p], msg)
+
+		close(t.written)
+		t.written = make(chan struct{})
+	}
+	return nil
+}
+
+func (w *memoryWriter) Close() error {
+	return nil
+}
+
+type memoryReader struct {
+	broker    *MemoryBroker
+	topic     string
+	partition int
+	offset    int64
+}
+
+// ReadMessage returns the next message of the partition, waiting for one to
+// be written if the reader is caught up.
+func (r *memoryReader) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		r.broker.mu.Lock()
+		t := r.broker.topic(r.topic)
+		partition := t.partitions[r.partition]
+		if r.offset < int64(len(partition)) {
+			msg := partition[r.offset]
+			msg.HighWaterMark = int64(len(partition))
+			r.offset++
+			r.broker.mu.Unlock()
+			return msg, nil
+		}
+		written := t.written
+		r.broker.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-written:
+		}
+	}
+}
+
+func (r *memoryReader) Close() error {
+	return nil
+}
+
+// memoryGroup rebalances whenever a member joins or leaves: the current
+// generation ends and every member gets a new one from Next.
+type memoryGroup struct {
+	topics    []string
+	members   []string
+	committed map[string]map[int]int64
+	current   *memoryGeneration
+	// rebalanced is closed and replaced when a new generation is created
+	rebalanced chan struct{}
+}
+
+func (b *MemoryBroker) JoinGroup(groupID string, topics []string) (Group, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g, ok := b.groups[groupID]
+	if !ok {
+		g = &memoryGroup{
+			topics:     topics,
+			committed:  make(map[string]map[int]int64),
+			rebalanced: make(chan struct{}),
+		}
+		b.groups[groupID] = g
+	}
+	if !slices.Equal(g.topics, topics) {
+		return nil, fmt.Errorf("group %s already reads topics %v", groupID, g.topics)
+	}
+
+	b.nextMember++
+	member := fmt.Sprintf("%s-%d", groupID, b.nextMember)
+	g.members = append(g.members, member)
+	b.rebalance(groupID, g)
+
+	return &memoryMember{broker: b, groupID: groupID, group: g, id: member}, nil
+}
+
+// rebalance ends the current generation of g and starts the next one with
+// partitions dealt out round-robin over the members. b.mu must be held.
+func (b *MemoryBroker) rebalance(groupID string, g *memoryGroup) {
+	var id int32
+	if g.current != nil {
+		id = g.current.id + 1
+		g.current.end()
+	}
+
+	gen := &memoryGeneration{
+		id:          id,
+		broker:      b,
+		groupID:     groupID,
+		assignments: make(map[string][]Assignment),
+		done:        make(chan struct{}),
+	}
+
+	members := slices.Clone(g.members)
+	sort.Strings(members)
+
+	i := 0
+	for _, topic := range g.topics {
+		for p := range b.partitions {
+			offset, ok := g.committed[topic][p]
+			if !ok {
+				offset = kafka.FirstOffset
+			}
+			if len(members) > 0 {
+				member := members[i%len(members)]
+				gen.assignments[member] = append(gen.assignments[member], Assignment{Topic: topic, Partition: p, Offset: offset})
+			}
+			i++
+		}
+	}
+
+	g.current = gen
+	close(g.rebalanced)
+	g.rebalanced = make(chan struct{})
+}
+
+type memoryMember struct {
+	broker  *MemoryBroker
+	groupID string
+	group   *memoryGroup
+	id      string
+	seen    *memoryGeneration
+	closed  bool
+}
+
+func (m *memoryMember) Next(ctx context.Context) (Generation, error) {
+	for {
+		m.broker.mu.Lock()
+		if m.closed {
+			m.broker.mu.Unlock()
+			return nil, ErrBrokerClosed
+		}
+		current, rebalanced := m.group.current, m.group.rebalanced
+		m.broker.mu.Unlock()
+
+		if current != m.seen {
+			if m.seen != nil {
+				m.seen.wait()
+			}
+			m.seen = current
+			return &memberGeneration{memoryGeneration: current, member: m.id}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-rebalanced:
+		}
+	}
+}
+
+// Close leaves the group, which rebalances it for the remaining members.
+func (m *memoryMember) Close() error {
+	m.broker.mu.Lock()
+	defer m.broker.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	m.group.members = slices.DeleteFunc(m.group.members, func(id string) bool { return id == m.id })
+	m.broker.rebalance(m.groupID, m.group)
+	return nil
+}
+
+type memoryGeneration struct {
+	id          int32
+	broker      *MemoryBroker
+	groupID     string
+	assignments map[string][]Assignment
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// end cancels the functions started on the generation. b.mu must be held.
+func (g *memoryGeneration) end() {
+	close(g.done)
+}
+
+func (g *memoryGeneration) wait() {
+	g.wg.Wait()
+}
+
+// memberGeneration is a generation as seen by one member.
+type memberGeneration struct {
+	*memoryGeneration
+	member string
+}
+
+func (g *memberGeneration) Assignments() []Assignment {
+	return g.assignments[g.member]
+}
+
+func (g *memberGeneration) Start(fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer cancel()
+
+		go func() {
+			<-g.done
+			cancel()
+		}()
+		fn(ctx)
+	}()
+}
+
+func (g *memberGeneration) CommitOffsets(offsets map[string]map[int]int64) error {
+	b := g.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	select {
+	case <-g.done:
+		return fmt.Errorf("generation %d of group %s has ended", g.id, g.groupID)
+	default:
+	}
+
+	group := b.groups[g.groupID]
+	for topic, partitions := range offsets {
+		if group.committed[topic] == nil {
+			group.committed[topic] = make(map[int]int64)
+		}
+		for partition, offset := range partitions {
+			group.committed[topic][partition] = offset
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func writeMessages(t *testing.T, b *MemoryBroker, msgs ...kafka.Message) {
+	t.Helper()
+	if err := b.Writer().WriteMessages(context.Background(), msgs...); err != nil {
+		t.Fatalf("WriteMessages: %v", err)
+	}
+}
+
+func nextGeneration(t *testing.T, g Group) Generation {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	gen, err := g.Next(ctx)
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	return gen
+}
+
+func TestMemoryBrokerKeepsKeysOnOnePartition(t *testing.T) {
+	b := NewMemoryBroker(4)
+	for i := range 20 {
+		writeMessages(t, b, kafka.Message{Topic: "t", Key: []byte(fmt.Sprintf("key-%d", i%5)), Value: []byte{byte(i)}})
+	}
+
+	partitions := make(map[string]int)
+	offsets := make(map[int]int64)
+	for _, msg := range b.Messages("t") {
+		key := string(msg.Key)
+		if p, ok := partitions[key]; ok && p != msg.Partition {
+			t.Errorf("key %s written to partitions %d and %d", key, p, msg.Partition)
+		}
+		partitions[key] = msg.Partition
+
+		if msg.Offset != offsets[msg.Partition] {
+			t.Errorf("partition %d offset %d, want %d", msg.Partition, msg.Offset, offsets[msg.Partition])
+		}
+		offsets[msg.Partition]++
+	}
+}
+
+func TestMemoryReaderWaitsForMessages(t *testing.T) {
+	b := NewMemoryBroker(1)
+	writeMessages(t, b, kafka.Message{Topic: "t", Value: []byte("first")})
+
+	reader, err := b.Reader("t", 0, kafka.LastOffset)
+	if err != nil {
+		t.Fatalf("Reader: %v", err)
+	}
+
+	go b.Writer().WriteMessages(context.Background(), kafka.Message{Topic: "t", Value: []byte("second")})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := reader.ReadMessage(ctx)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if string(msg.Value) != "second" || msg.Offset != 1 {
+		t.Errorf("read %q at %d, want \"second\" at 1", msg.Value, msg.Offset)
+	}
+
+	if _, err := reader.ReadMessage(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ReadMessage on a caught up partition = %v, want deadline exceeded", err)
+	}
+}
+
+func TestMemoryGroupSplitsPartitionsAndKeepsOffsets(t *testing.T) {
+	b := NewMemoryBroker(3)
+
+	first, err := b.JoinGroup("g", []string{"t"})
+	if err != nil {
+		t.Fatalf("JoinGroup: %v", err)
+	}
+	gen := nextGeneration(t, first)
+	if n := len(gen.Assignments()); n != 3 {
+		t.Fatalf("single member assigned %d partitions, want 3", n)
+	}
+	for _, a := range gen.Assignments() {
+		if a.Offset != kafka.FirstOffset {
+			t.Errorf("partition %d starts at %d, want FirstOffset", a.Partition, a.Offset)
+		}
+	}
+	if err := gen.CommitOffsets(map[string]map[int]int64{"t": {1: 5}}); err != nil {
+		t.Fatalf("CommitOffsets: %v", err)
+	}
+
+	stopped := make(chan struct{})
+	gen.Start(func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+
+	second, err := b.JoinGroup("g", []string{"t"})
+	if err != nil {
+		t.Fatalf("JoinGroup: %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("rebalance did not stop the previous generation")
+	}
+
+	if err := gen.CommitOffsets(map[string]map[int]int64{"t": {1: 9}}); err == nil {
+		t.Error("commit on an ended generation succeeded")
+	}
+
+	assigned := make(map[int]Assignment)
+	for _, g := range []Group{first, second} {
+		for _, a := range nextGeneration(t, g).Assignments() {
+			if _, dup := assigned[a.Partition]; dup {
+				t.Errorf("partition %d assigned twice", a.Partition)
+			}
+			assigned[a.Partition] = a
+		}
+	}
+	if len(assigned) != 3 {
+		t.Errorf("%d partitions assigned, want 3", len(assigned))
+	}
+	if got := assigned[1].Offset; got != 5 {
+		t.Errorf("partition 1 resumes at %d, want committed offset 5", got)
+	}
+
+	if err := second.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if n := len(nextGeneration(t, first).Assignments()); n != 3 {
+		t.Errorf("remaining member assigned %d partitions, want 3", n)
+	}
+}
@@ -2,60 +2,101 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
 	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/requestid"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	OrdersTopic      = "orders"
 	OrderStatusTopic = "order-status"
+	DeadLetterTopic  = "orders.dlq"
 )
 
-type Producer struct {
-	writer *kafka.Writer
-	logger *logger.Logger
+// Topics names the topics events are published to and read from.
+type Topics struct {
+	Orders      string `yaml:"orders"`
+	OrderStatus string `yaml:"order_status"`
+	DeadLetter  string `yaml:"dead_letter"`
 }
 
-func NewProducer(brokers string, l *logger.Logger) *Producer {
-	writer := &kafka.Writer{
-		Addr:         kafka.TCP(brokers),
-		RequiredAcks: kafka.RequireAll,
-		Balancer:     &kafka.Hash{},
-		Compression:  kafka.Snappy,
+func DefaultTopics() Topics {
+	return Topics{
+		Orders:      OrdersTopic,
+		OrderStatus: OrderStatusTopic,
+		DeadLetter:  DeadLetterTopic,
 	}
+}
+
+// Headers describing why a message was dead-lettered
+const (
+	DeadLetterErrorHeader     = "dlq-error"
+	DeadLetterAttemptsHeader  = "dlq-attempts"
+	DeadLetterTopicHeader     = "dlq-source-topic"
+	DeadLetterPartitionHeader = "dlq-source-partition"
+	DeadLetterOffsetHeader    = "dlq-source-offset"
+	DeadLetterFailedAtHeader  = "dlq-failed-at"
+)
 
+// EventTypeHeader carries the domain.EventType of every published message, so
+// readers of a topic shared by several event types can tell them apart.
+const EventTypeHeader = "event-type"
+
+// RequestIDHeader carries the ID of the request that led to a message.
+const RequestIDHeader = "request-id"
+
+// DefaultSource is the envelope source of producers whose Source is not set.
+const DefaultSource = "order-management-platform"
+
+// Producer publishes to DefaultTopics unless Topics is changed before use.
+// Source names the publishing service in the envelope of every event,
+// Encoding is one of Encodings and Serializer writes the event data.
+type Producer struct {
+	Topics     Topics
+	Source     string
+	Encoding   string
+	Serializer envelope.Serializer
+
+	writer MessageWriter
+	logger *logger.Logger
+}
+
+func NewProducer(broker Broker, l *logger.Logger) *Producer {
 	return &Producer{
-		writer: writer,
-		logger: l,
+		Topics:     DefaultTopics(),
+		Source:     DefaultSource,
+		Encoding:   EncodingEnvelope,
+		Serializer: envelope.JSON{},
+		writer:     broker.Writer(),
+		logger:     l,
 	}
 }
 
 func (p *Producer) PublishOrderCreated(ctx context.Context, event domain.OrderCreatedEvent) error {
-	payload, err := json.Marshal(event)
+	msg, err := p.encode(ctx, p.Topics.Orders, event)
 	if err != nil {
-		p.logger.Error("Failed to marshal OrderCreatedEvent", map[string]any{"error": err})
+		p.logger.WithContext(ctx).Error("Failed to encode OrderCreatedEvent", map[string]any{"error": err})
 
 		return err
 	}
 
-	msg := kafka.Message{
-		Topic: OrdersTopic,
-		Key:   []byte(event.OrderID),
-		Value: payload,
-	}
-
-	if err := p.writer.WriteMessages(ctx, msg); err != nil {
-		p.logger.Error("Failed to publish OrderCreatedEvent", map[string]any{
+	if err := p.write(ctx, msg); err != nil {
+		p.logger.WithContext(ctx).Error("Failed to publish OrderCreatedEvent", map[string]any{
 			"error":    err,
 			"order_id": event.OrderID,
 		})
 		return err
 	}
 
-	p.logger.Info("OrderCreatedEvent published", map[string]any{
+	p.logger.WithContext(ctx).Info("OrderCreatedEvent published", map[string]any{
 		"order_id": event.OrderID,
 		"user_id":  event.UserID,
 	})
@@ -64,26 +105,20 @@ func (p *Producer) PublishOrderCreated(ctx context.Context, event domain.OrderCr
 }
 
 func (p *Producer) PublishOrderConfirmed(ctx context.Context, event domain.OrderConfirmedEvent) error {
-	payload, err := json.Marshal(event)
+	msg, err := p.encode(ctx, p.Topics.OrderStatus, event)
 	if err != nil {
 		return err
 	}
 
-	msg := kafka.Message{
-		Topic: OrderStatusTopic,
-		Key:   []byte(event.OrderID),
-		Value: payload,
-	}
-
-	if err := p.writer.WriteMessages(ctx, msg); err != nil {
-		p.logger.Error("Failed to publish OrderConfirmedEvent", map[string]any{
+	if err := p.write(ctx, msg); err != nil {
+		p.logger.WithContext(ctx).Error("Failed to publish OrderConfirmedEvent", map[string]any{
 			"error":    err,
 			"order_id": event.OrderID,
 		})
 		return err
 	}
 
-	p.logger.Info("OrderConfirmedEvent published", map[string]any{
+	p.logger.WithContext(ctx).Info("OrderConfirmedEvent published", map[string]any{
 		"order_id": event.OrderID,
 	})
 
@@ -91,29 +126,188 @@ func (p *Producer) PublishOrderConfirmed(ctx context.Context, event domain.Order
 }
 
 func (p *Producer) PublishedOrderFailed(ctx context.Context, event domain.OrderFailedEvent) error {
-	payload, err := json.Marshal(event)
+	msg, err := p.encode(ctx, p.Topics.OrderStatus, event)
 	if err != nil {
 		return err
 	}
 
-	msg := kafka.Message{
-		Topic: OrderStatusTopic,
-		Key:   []byte(event.OrderID),
-		Value: payload,
+	if err := p.write(ctx, msg); err != nil {
+		p.logger.WithContext(ctx).Error("Failed to publish OrderFailedEvent", map[string]any{
+			"error":    err,
+			"order_id": event.OrderID,
+		})
+
+		return err
 	}
 
-	if err := p.writer.WriteMessages(ctx, msg); err != nil {
-		p.logger.Error("Failed to publish OrderFailedEvent", map[string]any{
+	return nil
+}
+
+func (p *Producer) PublishOrderCancelled(ctx context.Context, event domain.OrderCancelledEvent) error {
+	msg, err := p.encode(ctx, p.Topics.OrderStatus, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.write(ctx, msg); err != nil {
+		p.logger.WithContext(ctx).Error("Failed to publish OrderCancelledEvent", map[string]any{
 			"error":    err,
 			"order_id": event.OrderID,
 		})
+		return err
+	}
+
+	p.logger.WithContext(ctx).Info("OrderCancelledEvent published", map[string]any{
+		"order_id": event.OrderID,
+	})
+
+	return nil
+}
+
+func (p *Producer) PublishOrderAccepted(ctx context.Context, event domain.OrderAcceptedEvent) error {
+	return p.publishStatus(ctx, event)
+}
+
+func (p *Producer) PublishOrderPreparing(ctx context.Context, event domain.OrderPreparingEvent) error {
+	return p.publishStatus(ctx, event)
+}
 
+func (p *Producer) PublishOrderReady(ctx context.Context, event domain.OrderReadyEvent) error {
+	return p.publishStatus(ctx, event)
+}
+
+func (p *Producer) PublishOrderPickedUp(ctx context.Context, event domain.OrderPickedUpEvent) error {
+	return p.publishStatus(ctx, event)
+}
+
+func (p *Producer) PublishOrderOutForDelivery(ctx context.Context, event domain.OrderOutForDeliveryEvent) error {
+	return p.publishStatus(ctx, event)
+}
+
+func (p *Producer) PublishOrderDelivered(ctx context.Context, event domain.OrderDeliveredEvent) error {
+	return p.publishStatus(ctx, event)
+}
+
+// publishStatus writes a fulfilment event to the order status topic.
+func (p *Producer) publishStatus(ctx context.Context, event domain.Event) error {
+	msg, err := p.encode(ctx, p.Topics.OrderStatus, event)
+	if err != nil {
+		return err
+	}
+
+	if err := p.write(ctx, msg); err != nil {
+		p.logger.WithContext(ctx).Error("Failed to publish order status event", map[string]any{
+			"error":      err,
+			"order_id":   event.AggregateID(),
+			"event_type": event.EventType(),
+		})
+		return err
+	}
+
+	p.logger.WithContext(ctx).Info("Order status event published", map[string]any{
+		"order_id":   event.AggregateID(),
+		"event_type": event.EventType(),
+	})
+
+	return nil
+}
+
+// PublishDeadLetter copies a message that could not be processed to the
+// dead-letter topic. Key, payload and headers are kept as they were; the
+// cause, attempt count and source position are added as headers.
+func (p *Producer) PublishDeadLetter(ctx context.Context, msg kafka.Message, cause error, attempts int) error {
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: DeadLetterErrorHeader, Value: []byte(cause.Error())},
+		kafka.Header{Key: DeadLetterAttemptsHeader, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: DeadLetterTopicHeader, Value: []byte(msg.Topic)},
+		kafka.Header{Key: DeadLetterPartitionHeader, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: DeadLetterOffsetHeader, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: DeadLetterFailedAtHeader, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	dlq := kafka.Message{
+		Topic:   p.Topics.DeadLetter,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+
+	if err := p.write(ctx, dlq); err != nil {
+		p.logger.WithContext(ctx).Error("Failed to publish dead letter", map[string]any{
+			"error":     err,
+			"topic":     msg.Topic,
+			"partition": msg.Partition,
+			"offset":    msg.Offset,
+		})
 		return err
 	}
 
+	p.logger.WithContext(ctx).Warn("Message dead-lettered", map[string]any{
+		"topic":     msg.Topic,
+		"partition": msg.Partition,
+		"offset":    msg.Offset,
+		"attempts":  attempts,
+	})
+
 	return nil
 }
 
+// write sends msgs, adding the request ID carried by ctx to those that do not
+// have one yet. Each message is sent in its own producer span, whose trace
+// context replaces any the message carried.
+func (p *Producer) write(ctx context.Context, msgs ...kafka.Message) error {
+	id := requestid.FromContext(ctx)
+	spans := make([]trace.Span, len(msgs))
+	for i := range msgs {
+		if id != "" && header(msgs[i], RequestIDHeader) == "" {
+			msgs[i].Headers = append(msgs[i].Headers, kafka.Header{Key: RequestIDHeader, Value: []byte(id)})
+		}
+		_, spans[i] = startSend(ctx, &msgs[i])
+	}
+
+	err := p.writer.WriteMessages(ctx, msgs...)
+	for _, span := range spans {
+		tracing.End(span, err)
+	}
+	return err
+}
+
 func (p *Producer) Close() error {
 	return p.writer.Close()
 }
+
+func newMessage(topic string, eventType domain.EventType, key string, payload []byte) kafka.Message {
+	return kafka.Message{
+		Topic: topic,
+		Key:   []byte(key),
+		Value: payload,
+		Headers: []kafka.Header{
+			{Key: EventTypeHeader, Value: []byte(eventType)},
+		},
+	}
+}
+
+// eventType returns the value of the EventTypeHeader of msg, or the type of a
+// binary-mode CloudEvent published without it.
+func eventType(msg kafka.Message) domain.EventType {
+	if t := header(msg, EventTypeHeader); t != "" {
+		return domain.EventType(t)
+	}
+	return domain.EventType(header(msg, ceTypeHeader))
+}
+
+// header returns the value of the first header of msg named key, if any.
+func header(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// brokerList splits a comma separated broker list such as "host1:9092,host2:9092".
+func brokerList(brokers string) []string {
+	return strings.Split(brokers, ",")
+}
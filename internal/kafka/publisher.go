@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/segmentio/kafka-go"
+)
+
+// EventPublisher sends domain events and dead letters to the broker. Producer
+// implements it on Kafka and MemoryPublisher in memory.
+type EventPublisher interface {
+	Publish(ctx context.Context, event domain.Event) error
+	PublishDeadLetter(ctx context.Context, msg kafka.Message, cause error, attempts int) error
+}
+
+var (
+	_ EventPublisher = (*Producer)(nil)
+	_ EventPublisher = (*MemoryPublisher)(nil)
+)
+
+// Publish sends event to the topic of its type.
+func (p *Producer) Publish(ctx context.Context, event domain.Event) error {
+	switch e := event.(type) {
+	case domain.OrderCreatedEvent:
+		return p.PublishOrderCreated(ctx, e)
+	case domain.OrderConfirmedEvent:
+		return p.PublishOrderConfirmed(ctx, e)
+	case domain.OrderFailedEvent:
+		return p.PublishedOrderFailed(ctx, e)
+	case domain.OrderCancelledEvent:
+		return p.PublishOrderCancelled(ctx, e)
+	case domain.OrderAcceptedEvent:
+		return p.PublishOrderAccepted(ctx, e)
+	case domain.OrderPreparingEvent:
+		return p.PublishOrderPreparing(ctx, e)
+	case domain.OrderReadyEvent:
+		return p.PublishOrderReady(ctx, e)
+	case domain.OrderPickedUpEvent:
+		return p.PublishOrderPickedUp(ctx, e)
+	case domain.OrderOutForDeliveryEvent:
+		return p.PublishOrderOutForDelivery(ctx, e)
+	case domain.OrderDeliveredEvent:
+		return p.PublishOrderDelivered(ctx, e)
+	default:
+		return fmt.Errorf("no publisher for event type %s", event.EventType())
+	}
+}
+
+// DeadLetter is a message handed to MemoryPublisher.PublishDeadLetter.
+type DeadLetter struct {
+	Message  kafka.Message
+	Cause    error
+	Attempts int
+}
+
+// MemoryPublisher records what it is asked to publish. It is safe for
+// concurrent use. Set Err to make every publish fail with it.
+type MemoryPublisher struct {
+	mu          sync.Mutex
+	events      []domain.Event
+	deadLetters []DeadLetter
+
+	Err error
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(_ context.Context, event domain.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Err != nil {
+		return p.Err
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *MemoryPublisher) PublishDeadLetter(_ context.Context, msg kafka.Message, cause error, attempts int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Err != nil {
+		return p.Err
+	}
+	p.deadLetters = append(p.deadLetters, DeadLetter{Message: msg, Cause: cause, Attempts: attempts})
+	return nil
+}
+
+// Events returns the published events in publish order.
+func (p *MemoryPublisher) Events() []domain.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]domain.Event(nil), p.events...)
+}
+
+// DeadLetters returns the dead-lettered messages in publish order.
+func (p *MemoryPublisher) DeadLetters() []DeadLetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]DeadLetter(nil), p.deadLetters...)
+}
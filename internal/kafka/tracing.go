@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+	"github.com/segmentio/kafka-go"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceParentHeader carries the W3C trace context of the span that published
+// a message.
+const TraceParentHeader = tracing.TraceParentHeader
+
+var tracer = tracing.Tracer("github.com/dmehra2102/order-management-platform/internal/kafka")
+
+// headerCarrier adapts message headers to propagation.TextMapCarrier.
+type headerCarrier struct {
+	headers *[]kafka.Header
+}
+
+func (c headerCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces every header named key, so republished messages such as dead
+// letters carry the context of the span that sent them.
+func (c headerCarrier) Set(key, value string) {
+	headers := (*c.headers)[:0:0]
+	for _, h := range *c.headers {
+		if h.Key != key {
+			headers = append(headers, h)
+		}
+	}
+	*c.headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, len(*c.headers))
+	for i, h := range *c.headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
+
+// startSend starts the producer span for publishing msg and writes its trace
+// context to the message headers.
+func startSend(ctx context.Context, msg *kafka.Message) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, "send "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeSend,
+			semconv.MessagingDestinationName(msg.Topic),
+			semconv.MessagingKafkaMessageKey(string(msg.Key)),
+		),
+	)
+	tracing.Inject(ctx, headerCarrier{&msg.Headers})
+	return ctx, span
+}
+
+// startProcess starts the consumer span for msg as a child of the span that
+// published it.
+func startProcess(ctx context.Context, msg kafka.Message) (context.Context, trace.Span) {
+	ctx = tracing.Extract(ctx, headerCarrier{&msg.Headers})
+	return tracer.Start(ctx, "process "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeProcess,
+			semconv.MessagingDestinationName(msg.Topic),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(msg.Partition)),
+			semconv.MessagingKafkaOffset(int(msg.Offset)),
+			semconv.MessagingKafkaMessageKey(string(msg.Key)),
+		),
+	)
+}
@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	spansOnce sync.Once
+	spans     *tracetest.InMemoryExporter
+)
+
+// recordSpans installs a global tracer provider recording every span. Tracers
+// are bound to the first provider installed, so it is shared by all tests.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	spansOnce.Do(func() {
+		spans = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+	})
+	spans.Reset()
+	return spans
+}
+
+func findSpan(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range exporter.GetSpans() {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("no %q span among %d recorded", name, len(exporter.GetSpans()))
+	return tracetest.SpanStub{}
+}
+
+func TestProducerInjectsTraceParent(t *testing.T) {
+	exporter := recordSpans(t)
+	broker := NewMemoryBroker(1)
+	producer := NewProducer(broker, logger.New("ERROR"))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	if err := producer.Publish(ctx, domain.NewOrderConfirmedEvent("order-1")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	parent.End()
+
+	send := findSpan(t, exporter, "send "+OrderStatusTopic)
+	if send.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("send span parent = %s, want the caller's span", send.Parent.SpanID())
+	}
+	if send.SpanKind != trace.SpanKindProducer {
+		t.Errorf("send span kind = %s, want producer", send.SpanKind)
+	}
+
+	msg := broker.Messages(OrderStatusTopic)[0]
+	remote := trace.SpanContextFromContext(tracing.WithTraceParent(context.Background(), header(msg, TraceParentHeader)))
+	if remote.TraceID() != parent.SpanContext().TraceID() || remote.SpanID() != send.SpanContext.SpanID() {
+		t.Errorf("traceparent header %q does not name the send span", header(msg, TraceParentHeader))
+	}
+}
+
+func TestConsumerContinuesPublishedTrace(t *testing.T) {
+	exporter := recordSpans(t)
+	f := newConsumerFixture(t)
+	order, msg := f.createOrder(t, domain.NewMoney(45000, "INR"), 0)
+
+	ctx, publisher := otel.Tracer("test").Start(context.Background(), "publish")
+	tracing.Inject(ctx, headerCarrier{&msg.Headers})
+	publisher.End()
+
+	if err := f.consumer.process(context.Background(), msg); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	process := findSpan(t, exporter, "process "+OrdersTopic)
+	if process.Parent.SpanID() != publisher.SpanContext().SpanID() || !process.Parent.IsRemote() {
+		t.Errorf("process span parent = %s, want the remote publishing span", process.Parent.SpanID())
+	}
+	handle := findSpan(t, exporter, "Consumer.handleOrderCreated")
+	if handle.Parent.SpanID() != process.SpanContext.SpanID() {
+		t.Errorf("handleOrderCreated span parent = %s, want the process span", handle.Parent.SpanID())
+	}
+
+	// The confirmation continues the trace once the relay publishes it
+	var traceParent string
+	_, err := f.store.PublishPendingEvents(context.Background(), 10, func(_ context.Context, event repository.OutboxEvent) error {
+		if event.AggregateID == order.ID && event.EventType == domain.OrderConfirmedEventType {
+			traceParent = event.TraceParent
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("PublishPendingEvents: %v", err)
+	}
+	stored := trace.SpanContextFromContext(tracing.WithTraceParent(context.Background(), traceParent))
+	if stored.TraceID() != publisher.SpanContext().TraceID() {
+		t.Errorf("confirmed event trace parent %q is not in the published trace", traceParent)
+	}
+}
@@ -1,9 +1,13 @@
 package logger
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/requestid"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Level string
@@ -16,20 +20,38 @@ const (
 )
 
 type Logger struct {
-	level Level
+	level     Level
+	requestID string
+	traceID   string
 }
 
 type entry struct {
-	Level   string `json:"level"`
-	Time    string `json:"time"`
-	Message string `json:"message"`
-	Fields  any    `json:"fields,omitempty"`
+	Level     string `json:"level"`
+	Time      string `json:"time"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+	Fields    any    `json:"fields,omitempty"`
 }
 
 func New(level string) *Logger {
 	return &Logger{level: Level(level)}
 }
 
+// WithContext returns a logger that tags entries with the request ID and
+// trace ID carried by ctx, if any.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	id := requestid.FromContext(ctx)
+	var traceID string
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		traceID = sc.TraceID().String()
+	}
+	if (id == "" || id == l.requestID) && (traceID == "" || traceID == l.traceID) {
+		return l
+	}
+	return &Logger{level: l.level, requestID: id, traceID: traceID}
+}
+
 func (l *Logger) Debug(msg string, fields ...any) {
 	if l.level == Debug {
 		l.log(Debug, msg, fields...)
@@ -50,9 +72,11 @@ func (l *Logger) Error(msg string, fields ...any) {
 
 func (l *Logger) log(level Level, msg string, fields ...any) {
 	e := entry{
-		Level:   string(level),
-		Time:    time.Now().UTC().Format(time.RFC3339),
-		Message: msg,
+		Level:     string(level),
+		Time:      time.Now().UTC().Format(time.RFC3339),
+		Message:   msg,
+		RequestID: l.requestID,
+		TraceID:   l.traceID,
 	}
 
 	if len(fields) > 0 {
@@ -9,6 +9,15 @@ type Metrics struct {
 	OrderProcessTime prometheus.Histogram
 	KafkaErrors      prometheus.Counter
 	DBErrors         prometheus.Counter
+
+	RestaurantOpenOrders *prometheus.GaugeVec
+
+	HTTPRequests        *prometheus.CounterVec
+	HTTPRequestDuration *prometheus.HistogramVec
+	ConsumerLag         *prometheus.GaugeVec
+	UnknownEvents       *prometheus.CounterVec
+
+	WebhookAttempts *prometheus.CounterVec
 }
 
 func New() *Metrics {
@@ -38,6 +47,31 @@ func New() *Metrics {
 			Name: "db_errors_total",
 			Help: "Total database errors",
 		}),
+		RestaurantOpenOrders: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "restaurant_open_orders",
+			Help: "Orders waiting on each restaurant, from confirmation to pickup; every replica reports the full count, so aggregate with max",
+		}, []string{"restaurant_id"}),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by method, route pattern and status code",
+		}, []string{"method", "route", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by method, route pattern and status code",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		ConsumerLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kafka_consumer_lag",
+			Help: "Messages behind the end of each partition after the last one processed",
+		}, []string{"group", "topic", "partition"}),
+		UnknownEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kafka_unknown_events_total",
+			Help: "Messages skipped because their event type is unknown",
+		}, []string{"topic", "event_type"}),
+		WebhookAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "webhook_delivery_attempts_total",
+			Help: "Webhook delivery attempts, by event type and outcome: delivered, retry or failed",
+		}, []string{"event_type", "outcome"}),
 	}
 }
 
@@ -60,5 +94,23 @@ func (m *Metrics) Register() error {
 	if err := prometheus.Register(m.DBErrors); err != nil {
 		return err
 	}
+	if err := prometheus.Register(m.RestaurantOpenOrders); err != nil {
+		return err
+	}
+	if err := prometheus.Register(m.HTTPRequests); err != nil {
+		return err
+	}
+	if err := prometheus.Register(m.HTTPRequestDuration); err != nil {
+		return err
+	}
+	if err := prometheus.Register(m.ConsumerLag); err != nil {
+		return err
+	}
+	if err := prometheus.Register(m.UnknownEvents); err != nil {
+		return err
+	}
+	if err := prometheus.Register(m.WebhookAttempts); err != nil {
+		return err
+	}
 	return nil
 }
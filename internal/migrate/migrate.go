@@ -0,0 +1,351 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// lockKey is the advisory lock held while migrating, so that concurrent runs
+// wait for each other instead of applying the same migration twice.
+const lockKey int64 = 72100002
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrChecksumMismatch is returned when an applied migration's up file has
+// been edited since it was applied.
+var ErrChecksumMismatch = errors.New("applied migration was modified")
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status pairs a migration with when it was applied, nil if it is pending.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	version   int
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Load reads NNN_name.up.sql and NNN_name.down.sql pairs from the root of
+// fsys, ordered by version. Every version needs both files, and versions run
+// from 1 without gaps.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	type file struct {
+		version   int
+		direction string
+	}
+	seen := make(map[file]string)
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files named %s and %s", version, m.Name, match[2])
+		}
+		key := file{version, match[3]}
+		if other, ok := seen[key]; ok {
+			return nil, fmt.Errorf("migration %d has two %s files: %s and %s", version, match[3], other, entry.Name())
+		}
+		seen[key] = entry.Name()
+
+		if match[3] == "up" {
+			sum := sha256.Sum256(data)
+			m.Up, m.Checksum = string(data), hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %03d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %03d_%s leaves a gap, expected version %d", m.Version, m.Name, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations, recording applied versions and
+// their checksums in schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+
+	// DryRun reports what Up and Down would do without changing the database
+	DryRun bool
+	// Logf is told about each migration as it is applied or reverted
+	Logf func(format string, args ...any)
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		Logf:       func(string, ...any) {},
+	}
+}
+
+// Status lists every known migration with when it was applied. It fails if an
+// applied migration has been edited or is missing from the files.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if a, ok := applied[migration.Version]; ok {
+				at := a.appliedAt
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Up applies up to n pending migrations in version order, all of them when n
+// is 0. Each migration runs in its own transaction. It returns the migrations
+// applied, or that would be applied in dry-run mode.
+func (m *Migrator) Up(ctx context.Context, n int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if !m.DryRun {
+			if _, err := conn.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS schema_migrations (
+					version INT PRIMARY KEY,
+					name VARCHAR(255) NOT NULL,
+					checksum CHAR(64) NOT NULL,
+					applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+				)
+			`); err != nil {
+				return fmt.Errorf("create schema_migrations: %w", err)
+			}
+		}
+
+		for _, migration := range m.pending(applied, n) {
+			m.Logf("-> Applying %03d_%s", migration.Version, migration.Name)
+			if !m.DryRun {
+				err := inTx(ctx, conn, func(tx *sql.Tx) error {
+					if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+						return err
+					}
+					_, err := tx.ExecContext(ctx, `
+						INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)
+					`, migration.Version, migration.Name, migration.Checksum)
+					return err
+				})
+				if err != nil {
+					return fmt.Errorf("apply migration %03d_%s: %w", migration.Version, migration.Name, err)
+				}
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the n most recently applied migrations, newest first. It
+// returns the migrations reverted, or that would be reverted in dry-run mode.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.latest(applied, n) {
+			m.Logf("-> Reverting %03d_%s", migration.Version, migration.Name)
+			if !m.DryRun {
+				err := inTx(ctx, conn, func(tx *sql.Tx) error {
+					if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+						return err
+					}
+					_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+					return err
+				})
+				if err != nil {
+					return fmt.Errorf("revert migration %03d_%s: %w", migration.Version, migration.Name, err)
+				}
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// pending returns up to n migrations not yet applied, in version order, all
+// of them when n is 0.
+func (m *Migrator) pending(applied map[int]appliedMigration, n int) []Migration {
+	var selected []Migration
+	for _, migration := range m.migrations {
+		if n > 0 && len(selected) == n {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			selected = append(selected, migration)
+		}
+	}
+	return selected
+}
+
+// latest returns the n most recently applied migrations, newest first.
+func (m *Migrator) latest(applied map[int]appliedMigration, n int) []Migration {
+	var selected []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(selected) < n; i-- {
+		if _, ok := applied[m.migrations[i].Version]; ok {
+			selected = append(selected, m.migrations[i])
+		}
+	}
+	return selected
+}
+
+// verify reads the applied migrations and checks them against the files.
+// Before the first migration nothing has been applied.
+func (m *Migrator) verify(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check schema_migrations: %w", err)
+	}
+	if !exists {
+		return map[int]appliedMigration{}, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := m.check(applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// check verifies that every applied migration still exists with the checksum
+// it was applied with.
+func (m *Migrator) check(applied map[int]appliedMigration) error {
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, a := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("applied migration %03d_%s has no file", version, a.name)
+		}
+		if migration.Checksum != a.checksum {
+			return fmt.Errorf("%w: %03d_%s", ErrChecksumMismatch, version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// withLock runs fn on a single connection holding the migration lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+	}()
+
+	return fn(conn)
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("start tx: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
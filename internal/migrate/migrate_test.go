@@ -0,0 +1,165 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func files(names ...string) fstest.MapFS {
+	fsys := make(fstest.MapFS)
+	for _, name := range names {
+		fsys[name] = &fstest.MapFile{Data: []byte("-- " + name)}
+	}
+	return fsys
+}
+
+func TestLoadPairsFilesInVersionOrder(t *testing.T) {
+	fsys := files(
+		"002_orders_index.down.sql", "001_initial.up.sql", "002_orders_index.up.sql", "001_initial.down.sql",
+		"README.md", "003_notes.txt",
+	)
+	fsys["backup/003_old.up.sql"] = &fstest.MapFile{Data: []byte("-- ignored")}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("loaded %d migrations, want 2", len(migrations))
+	}
+	for i, want := range []struct {
+		version int
+		name    string
+	}{{1, "initial"}, {2, "orders_index"}} {
+		m := migrations[i]
+		if m.Version != want.version || m.Name != want.name {
+			t.Errorf("migration %d = %03d_%s, want %03d_%s", i, m.Version, m.Name, want.version, want.name)
+		}
+		if m.Up != fmt.Sprintf("-- %03d_%s.up.sql", m.Version, m.Name) || m.Down != fmt.Sprintf("-- %03d_%s.down.sql", m.Version, m.Name) {
+			t.Errorf("migration %03d_%s has up %q and down %q", m.Version, m.Name, m.Up, m.Down)
+		}
+		if len(m.Checksum) != 64 {
+			t.Errorf("migration %03d_%s checksum = %q, want a sha256", m.Version, m.Name, m.Checksum)
+		}
+	}
+	if migrations[0].Checksum == migrations[1].Checksum {
+		t.Errorf("different up files share checksum %s", migrations[0].Checksum)
+	}
+}
+
+func TestLoadRejectsBrokenSets(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"missing down", files("001_initial.up.sql"), "needs both"},
+		{"missing up", files("001_initial.down.sql"), "needs both"},
+		{"gap", files("001_initial.up.sql", "001_initial.down.sql", "003_late.up.sql", "003_late.down.sql"), "gap"},
+		{"not starting at one", files("002_initial.up.sql", "002_initial.down.sql"), "gap"},
+		{"duplicate version", files("001_initial.up.sql", "001_initial.down.sql", "001_other.up.sql", "001_other.down.sql"), "named"},
+		{"duplicate file", files("001_initial.up.sql", "001_initial.down.sql", "1_initial.up.sql"), "two up files"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Load(tt.fsys); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Load = %v, want an error mentioning %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func testMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	migrations, err := Load(files(
+		"001_a.up.sql", "001_a.down.sql", "002_b.up.sql", "002_b.down.sql",
+		"003_c.up.sql", "003_c.down.sql", "004_d.up.sql", "004_d.down.sql",
+	))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return NewMigrator(nil, migrations)
+}
+
+// appliedThrough records the first n migrations as applied unchanged.
+func appliedThrough(m *Migrator, n int) map[int]appliedMigration {
+	applied := make(map[int]appliedMigration)
+	for _, migration := range m.migrations[:n] {
+		applied[migration.Version] = appliedMigration{
+			version:   migration.Version,
+			name:      migration.Name,
+			checksum:  migration.Checksum,
+			appliedAt: time.Now(),
+		}
+	}
+	return applied
+}
+
+func versions(migrations []Migration) []int {
+	var out []int
+	for _, m := range migrations {
+		out = append(out, m.Version)
+	}
+	return out
+}
+
+func TestUpAndDownSelectMigrations(t *testing.T) {
+	m := testMigrator(t)
+
+	tests := []struct {
+		name    string
+		applied int
+		n       int
+		up      bool
+		want    []int
+	}{
+		{"up all from scratch", 0, 0, true, []int{1, 2, 3, 4}},
+		{"up all pending", 2, 0, true, []int{3, 4}},
+		{"up 1", 2, 1, true, []int{3}},
+		{"up more than pending", 3, 5, true, []int{4}},
+		{"up when current", 4, 0, true, nil},
+		{"down 1", 3, 1, false, []int{3}},
+		{"down 2", 3, 2, false, []int{3, 2}},
+		{"down more than applied", 2, 5, false, []int{2, 1}},
+		{"down with nothing applied", 0, 1, false, nil},
+	}
+
+	for _, tt := range tests {
+		applied := appliedThrough(m, tt.applied)
+		var got []Migration
+		if tt.up {
+			got = m.pending(applied, tt.n)
+		} else {
+			got = m.latest(applied, tt.n)
+		}
+		if g := versions(got); !slices.Equal(g, tt.want) {
+			t.Errorf("%s: selected %v, want %v", tt.name, g, tt.want)
+		}
+	}
+}
+
+func TestCheckRejectsModifiedOrMissingMigrations(t *testing.T) {
+	m := testMigrator(t)
+
+	if err := m.check(appliedThrough(m, 3)); err != nil {
+		t.Errorf("check of unchanged migrations: %v", err)
+	}
+
+	edited := appliedThrough(m, 3)
+	a := edited[2]
+	a.checksum = strings.Repeat("0", 64)
+	edited[2] = a
+	if err := m.check(edited); !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "002_b") {
+		t.Errorf("check of an edited migration = %v, want ErrChecksumMismatch naming 002_b", err)
+	}
+
+	removed := appliedThrough(m, 4)
+	removed[9] = appliedMigration{version: 9, name: "dropped", checksum: strings.Repeat("0", 64)}
+	if err := m.check(removed); err == nil || !strings.Contains(err.Error(), "009_dropped has no file") {
+		t.Errorf("check of a migration without a file = %v, want it named", err)
+	}
+}
@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
+	"github.com/dmehra2102/order-management-platform/internal/kafka"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/requestid"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("github.com/dmehra2102/order-management-platform/internal/outbox")
+
+// Relay polls the events table for rows that have not been sent yet and
+// publishes them through the publisher.
+type Relay struct {
+	repo      repository.OrderStore
+	publisher kafka.EventPublisher
+	logger    *logger.Logger
+	interval  time.Duration
+	batchSize int
+}
+
+func NewRelay(repo repository.OrderStore, publisher kafka.EventPublisher, l *logger.Logger, interval time.Duration, batchSize int) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		logger:    l,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+func (r *Relay) Start(ctx context.Context) error {
+	r.logger.Info("Outbox relay started", map[string]any{
+		"interval":   r.interval.String(),
+		"batch_size": r.batchSize,
+	})
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		r.drain(ctx)
+	}
+}
+
+// drain publishes pending events batch by batch until the backlog is empty or
+// publishing fails.
+func (r *Relay) drain(ctx context.Context) {
+	for {
+		published, err := r.repo.PublishPendingEvents(ctx, r.batchSize, r.publish)
+		if err != nil {
+			r.logger.Error("Failed to relay outbox events", map[string]any{
+				"error":     err,
+				"published": published,
+			})
+			return
+		}
+		if published < r.batchSize {
+			return
+		}
+	}
+}
+
+// publish sends event under the request ID and trace it was written in. Its
+// span starts when the event was written, so traces show how long it waited
+// in the outbox.
+func (r *Relay) publish(ctx context.Context, event repository.OutboxEvent) (err error) {
+	ctx = requestid.NewContext(ctx, event.CorrelationID)
+	ctx, span := tracer.Start(tracing.WithTraceParent(ctx, event.TraceParent), "outbox "+string(event.EventType),
+		trace.WithTimestamp(event.CreatedAt),
+		trace.WithAttributes(
+			attribute.Int64("outbox.event_id", event.ID),
+			attribute.String("order.id", event.AggregateID),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	decoded, err := envelope.DecodeStored(event.EventType, event.Payload)
+	if err != nil {
+		return err
+	}
+	return r.publisher.Publish(ctx, decoded)
+}
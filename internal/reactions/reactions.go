@@ -0,0 +1,124 @@
+// Package reactions holds what happens downstream of order status changes.
+// The status consumer of order-processor drives them: customers and
+// restaurants are told about every change that concerns them.
+package reactions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/kafka"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+)
+
+type Notification struct {
+	OrderID     string
+	EventType   domain.EventType
+	Recipient   domain.ActorRole
+	RecipientID string
+	Message     string
+}
+
+// Notifier delivers notifications. A notification may be delivered again
+// when the message behind it is retried.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier writes notifications to the log, standing in for a messaging
+// service.
+type LogNotifier struct {
+	logger *logger.Logger
+}
+
+func NewLogNotifier(l *logger.Logger) *LogNotifier {
+	return &LogNotifier{logger: l}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.logger.WithContext(ctx).Info("Notification sent", map[string]any{
+		"order_id":     notification.OrderID,
+		"event_type":   notification.EventType,
+		"recipient":    notification.Recipient,
+		"recipient_id": notification.RecipientID,
+		"message":      notification.Message,
+	})
+	return nil
+}
+
+// Register adds the reactions to every order status event to d.
+func Register(d *kafka.Dispatcher, notifier Notifier) {
+	notify(d, notifier, func(e domain.OrderConfirmedEvent, o *domain.Order) []Notification {
+		return []Notification{
+			toCustomer(o, "Your order has been placed"),
+			toRestaurant(o, "New order waiting for you to accept it"),
+		}
+	})
+	notify(d, notifier, func(e domain.OrderFailedEvent, o *domain.Order) []Notification {
+		return []Notification{toCustomer(o, fmt.Sprintf("Your order could not be placed: %s", e.Reason))}
+	})
+	notify(d, notifier, func(e domain.OrderCancelledEvent, o *domain.Order) []Notification {
+		message := "Order cancelled"
+		if e.Reason != "" {
+			message += ": " + e.Reason
+		}
+		// Whoever cancelled knows already
+		var out []Notification
+		if e.CancelledByRole != domain.ActorCustomer {
+			out = append(out, toCustomer(o, message))
+		}
+		if e.CancelledByRole != domain.ActorRestaurant {
+			out = append(out, toRestaurant(o, message))
+		}
+		return out
+	})
+	notify(d, notifier, func(_ domain.OrderAcceptedEvent, o *domain.Order) []Notification {
+		return []Notification{toCustomer(o, "The restaurant accepted your order")}
+	})
+	notify(d, notifier, func(_ domain.OrderPreparingEvent, o *domain.Order) []Notification {
+		return []Notification{toCustomer(o, "Your order is being prepared")}
+	})
+	notify(d, notifier, func(_ domain.OrderReadyEvent, o *domain.Order) []Notification {
+		return []Notification{toCustomer(o, "Your order is ready for pickup")}
+	})
+	notify(d, notifier, func(_ domain.OrderPickedUpEvent, o *domain.Order) []Notification {
+		return []Notification{toCustomer(o, "Your order has been picked up")}
+	})
+	notify(d, notifier, func(_ domain.OrderOutForDeliveryEvent, o *domain.Order) []Notification {
+		return []Notification{toCustomer(o, "Your order is on its way")}
+	})
+	notify(d, notifier, func(_ domain.OrderDeliveredEvent, o *domain.Order) []Notification {
+		return []Notification{
+			toCustomer(o, "Your order has been delivered"),
+			toRestaurant(o, "Order delivered"),
+		}
+	})
+}
+
+// notify registers a handler for events of type E that loads the order and
+// sends the notifications build returns for it.
+func notify[E domain.Event](d *kafka.Dispatcher, notifier Notifier, build func(event E, order *domain.Order) []Notification) {
+	kafka.On(d, func(ctx context.Context, delivery *kafka.Delivery, event E) error {
+		order, err := delivery.Tx.GetOrder(ctx, event.AggregateID())
+		if err != nil {
+			return fmt.Errorf("load order %s: %w", event.AggregateID(), err)
+		}
+
+		for _, n := range build(event, order) {
+			n.EventType = event.EventType()
+			if err := notifier.Notify(ctx, n); err != nil {
+				return fmt.Errorf("notify %s of order %s: %w", n.Recipient, n.OrderID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func toCustomer(o *domain.Order, message string) Notification {
+	return Notification{OrderID: o.ID, Recipient: domain.ActorCustomer, RecipientID: o.UserID, Message: message}
+}
+
+func toRestaurant(o *domain.Order, message string) Notification {
+	return Notification{OrderID: o.ID, Recipient: domain.ActorRestaurant, RecipientID: o.RestaurantID, Message: message}
+}
@@ -0,0 +1,89 @@
+package reactions
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/kafka"
+	"github.com/dmehra2102/order-management-platform/internal/repository/memstore"
+	segmentio "github.com/segmentio/kafka-go"
+)
+
+type recordingNotifier struct {
+	sent []Notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, notification Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func dispatch(t *testing.T, store *memstore.Store, notifier Notifier, event domain.Event) {
+	t.Helper()
+
+	d := kafka.NewDispatcher()
+	Register(d, notifier)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	delivery := &kafka.Delivery{Message: segmentio.Message{Value: payload}, Tx: store}
+	if err := d.Dispatch(context.Background(), delivery, event.EventType()); err != nil {
+		t.Fatalf("Dispatch %s: %v", event.EventType(), err)
+	}
+}
+
+func newOrder(t *testing.T, store *memstore.Store) *domain.Order {
+	t.Helper()
+	order, err := domain.NewOrder("user-1", "rest-1", []domain.OrderItem{
+		{ID: "line-1", ItemID: "item-1", Name: "Thali", Price: domain.NewMoney(45000, "INR"), Quantity: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewOrder: %v", err)
+	}
+	if err := store.CreateOrder(context.Background(), order, domain.NewOrderCreatedEvent(order)); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	return order
+}
+
+func TestConfirmationNotifiesCustomerAndRestaurant(t *testing.T) {
+	store := memstore.New()
+	order := newOrder(t, store)
+	notifier := &recordingNotifier{}
+
+	dispatch(t, store, notifier, domain.NewOrderConfirmedEvent(order.ID))
+
+	if len(notifier.sent) != 2 {
+		t.Fatalf("sent %d notifications, want 2", len(notifier.sent))
+	}
+	if n := notifier.sent[0]; n.Recipient != domain.ActorCustomer || n.RecipientID != "user-1" || n.EventType != domain.OrderConfirmedEventType {
+		t.Errorf("first notification = %+v, want the customer told of the confirmation", n)
+	}
+	if n := notifier.sent[1]; n.Recipient != domain.ActorRestaurant || n.RecipientID != "rest-1" {
+		t.Errorf("second notification = %+v, want the restaurant", n)
+	}
+}
+
+func TestCancellationSkipsWhoeverCancelled(t *testing.T) {
+	store := memstore.New()
+	order := newOrder(t, store)
+	notifier := &recordingNotifier{}
+
+	dispatch(t, store, notifier, domain.OrderCancelledEvent{
+		OrderID:         order.ID,
+		Reason:          "changed my mind",
+		CancelledBy:     "user-1",
+		CancelledByRole: domain.ActorCustomer,
+	})
+
+	if len(notifier.sent) != 1 || notifier.sent[0].Recipient != domain.ActorRestaurant {
+		t.Fatalf("sent %+v, want only the restaurant told", notifier.sent)
+	}
+	if want := "Order cancelled: changed my mind"; notifier.sent[0].Message != want {
+		t.Errorf("message = %q, want %q", notifier.sent[0].Message, want)
+	}
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ConsumerOffset returns the last offset of a partition applied by the consumer
+// group. Inside a transaction the row stays locked until commit.
+func (r *OrderRepository) ConsumerOffset(ctx context.Context, group, topic string, partition int) (int64, bool, error) {
+	var offset int64
+	err := r.q.QueryRowContext(ctx, `
+		SELECT "offset" FROM consumer_offsets
+		WHERE consumer_group = $1 AND topic = $2 AND partition = $3
+		FOR UPDATE
+	`, group, topic, partition).Scan(&offset)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("query consumer offset: %w", err)
+	}
+
+	return offset, true, nil
+}
+
+// SaveConsumerOffset records offset as the last one applied by the consumer group.
+func (r *OrderRepository) SaveConsumerOffset(ctx context.Context, group, topic string, partition int, offset int64) error {
+	_, err := r.q.ExecContext(ctx, `
+		INSERT INTO consumer_offsets (consumer_group, topic, partition, "offset", updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (consumer_group, topic, partition)
+		DO UPDATE SET "offset" = EXCLUDED."offset", updated_at = EXCLUDED.updated_at
+	`, group, topic, partition, offset)
+	if err != nil {
+		return fmt.Errorf("save consumer offset: %w", err)
+	}
+
+	return nil
+}
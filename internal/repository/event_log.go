@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
+)
+
+// OrderEvents returns the recorded events of an order, oldest first.
+func (r *OrderRepository) OrderEvents(ctx context.Context, orderID string) ([]domain.RecordedEvent, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT event_type, event_data, version
+		FROM events WHERE aggregate_id = $1 ORDER BY version
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("query order events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []domain.RecordedEvent
+	for rows.Next() {
+		var (
+			eventType domain.EventType
+			data      []byte
+			version   int
+		)
+		if err := rows.Scan(&eventType, &data, &version); err != nil {
+			return nil, err
+		}
+
+		event, err := envelope.DecodeStored(eventType, data)
+		if err != nil {
+			return nil, fmt.Errorf("order %s version %d: %w", orderID, version, err)
+		}
+		events = append(events, domain.RecordedEvent{Event: event, Version: version})
+	}
+
+	return events, rows.Err()
+}
+
+// ReplayOrder reconstructs an order by folding its recorded events, without
+// reading the orders table.
+func (r *OrderRepository) ReplayOrder(ctx context.Context, orderID string) (*domain.Order, error) {
+	events, err := r.OrderEvents(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := domain.ReplayOrder(events)
+	if err != nil {
+		return nil, fmt.Errorf("replay order %s: %w", orderID, err)
+	}
+
+	return order, nil
+}
+
+// OrderIDs pages through all order IDs in ascending order, starting after the
+// given ID. Pass an empty afterID for the first page.
+func (r *OrderRepository) OrderIDs(ctx context.Context, afterID string, limit int) ([]string, error) {
+	query := `SELECT id FROM orders ORDER BY id LIMIT $1`
+	args := []any{limit}
+	if afterID != "" {
+		query = `SELECT id FROM orders WHERE id > $2 ORDER BY id LIMIT $1`
+		args = append(args, afterID)
+	}
+
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query order ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
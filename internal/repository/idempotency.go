@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+// ErrIdempotencyKeyReused is returned when a key is presented again with a
+// request that differs from the one that first used it.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+// IdempotencyKey identifies a client request that must take effect at most
+// once. RequestHash fingerprints the request body the key was sent with.
+type IdempotencyKey struct {
+	Key         string
+	RequestHash string
+}
+
+// ClaimIdempotencyKey reserves key for the current transaction. If an
+// unexpired order was already stored under the key, it returns that order as it
+// was when created. Concurrent claims of the same key wait on each other, so
+// the second one sees the first one's order once it commits.
+func (r *OrderRepository) ClaimIdempotencyKey(ctx context.Context, key IdempotencyKey, ttl time.Duration) (*domain.Order, error) {
+	if r.tx == nil {
+		return nil, fmt.Errorf("claim idempotency key: must run in a transaction")
+	}
+
+	// An expired key is taken over; the placeholder row is filled in by
+	// SaveIdempotentOrder before the transaction commits.
+	var claimed string
+	err := r.q.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (idempotency_key, request_hash, order_id, response, created_at, expires_at)
+		VALUES ($1, $2, '', '{}', NOW(), NOW() + $3 * INTERVAL '1 second')
+		ON CONFLICT (idempotency_key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash,
+				order_id = EXCLUDED.order_id,
+				response = EXCLUDED.response,
+				created_at = EXCLUDED.created_at,
+				expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= NOW()
+		RETURNING idempotency_key
+	`, key.Key, key.RequestHash, int64(ttl/time.Second)).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("claim idempotency key: %w", err)
+	}
+
+	var (
+		requestHash string
+		response    []byte
+	)
+	if err := r.q.QueryRowContext(ctx, `
+		SELECT request_hash, response FROM idempotency_keys WHERE idempotency_key = $1
+	`, key.Key).Scan(&requestHash, &response); err != nil {
+		return nil, fmt.Errorf("read idempotency key: %w", err)
+	}
+
+	if requestHash != key.RequestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	var order domain.Order
+	if err := json.Unmarshal(response, &order); err != nil {
+		return nil, fmt.Errorf("unmarshal idempotent response: %w", err)
+	}
+	return &order, nil
+}
+
+// SaveIdempotentOrder stores order as the response to a key claimed in the
+// same transaction.
+func (r *OrderRepository) SaveIdempotentOrder(ctx context.Context, key IdempotencyKey, order *domain.Order) error {
+	response, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("marshal idempotent response: %w", err)
+	}
+
+	if _, err := r.q.ExecContext(ctx, `
+		UPDATE idempotency_keys SET order_id = $2, response = $3 WHERE idempotency_key = $1
+	`, key.Key, order.ID, response); err != nil {
+		return fmt.Errorf("save idempotent response: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes keys past their TTL.
+func (r *OrderRepository) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,478 @@
+// Package memstore keeps orders, their events, consumer offsets and webhooks
+// in memory for tests and local runs without Postgres.
+package memstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/envelope"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/requestid"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+)
+
+type offsetKey struct {
+	group     string
+	topic     string
+	partition int
+}
+
+type storedEvent struct {
+	repository.OutboxEvent
+	published bool
+}
+
+type idempotencyRecord struct {
+	requestHash string
+	response    []byte
+	expiresAt   time.Time
+}
+
+type state struct {
+	orders      map[string]domain.Order
+	events      []storedEvent
+	offsets     map[offsetKey]int64
+	idempotency map[string]idempotencyRecord
+	nextEventID int64
+
+	webhooks       map[string]repository.WebhookSubscription
+	deliveries     []repository.WebhookDelivery
+	nextDeliveryID int64
+}
+
+func (s *state) clone() *state {
+	c := &state{
+		orders:      make(map[string]domain.Order, len(s.orders)),
+		events:      slices.Clone(s.events),
+		offsets:     maps.Clone(s.offsets),
+		idempotency: maps.Clone(s.idempotency),
+		nextEventID: s.nextEventID,
+
+		webhooks:       make(map[string]repository.WebhookSubscription, len(s.webhooks)),
+		deliveries:     slices.Clone(s.deliveries),
+		nextDeliveryID: s.nextDeliveryID,
+	}
+	for id, order := range s.orders {
+		c.orders[id] = copyOrder(order)
+	}
+	for id, sub := range s.webhooks {
+		c.webhooks[id] = copyWebhookSubscription(sub)
+	}
+	return c
+}
+
+type db struct {
+	mu    sync.RWMutex
+	state *state
+}
+
+// Store is an in-memory repository.OrderStore. It is safe for concurrent use.
+// A transaction holds the whole store until it ends and works on a copy that
+// replaces the committed state only if the transaction succeeds, so
+// transactions are serializable and failed ones leave no trace. Reads outside
+// a transaction share the store with each other but not with transactions.
+// Inside RunInTx, only use the store passed to fn.
+type Store struct {
+	db *db
+	tx *state
+}
+
+var _ repository.OrderStore = (*Store)(nil)
+
+func New() *Store {
+	return &Store{db: &db{state: &state{
+		orders:      make(map[string]domain.Order),
+		offsets:     make(map[offsetKey]int64),
+		idempotency: make(map[string]idempotencyRecord),
+		nextEventID: 1,
+
+		webhooks:       make(map[string]repository.WebhookSubscription),
+		nextDeliveryID: 1,
+	}}}
+}
+
+func (s *Store) RunInTx(ctx context.Context, fn func(tx repository.OrderStore) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("start tx: %w", err)
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	tx := &Store{db: s.db, tx: s.db.state.clone()}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	s.db.state = tx.tx
+	return nil
+}
+
+// update runs fn against the transaction's state, or as a transaction of its
+// own outside one.
+func (s *Store) update(ctx context.Context, fn func(st *state) error) error {
+	return s.RunInTx(ctx, func(tx repository.OrderStore) error {
+		return fn(tx.(*Store).tx)
+	})
+}
+
+// view runs fn against the transaction's state, or against the committed
+// state without copying it outside one. fn must not modify st.
+func (s *Store) view(ctx context.Context, fn func(st *state) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+	return fn(s.db.state)
+}
+
+func (s *Store) CreateOrder(ctx context.Context, order *domain.Order, event domain.Event) error {
+	return s.update(ctx, func(st *state) error {
+		if _, ok := st.orders[order.ID]; ok {
+			return fmt.Errorf("insert order: order %s already exists", order.ID)
+		}
+
+		st.orders[order.ID] = copyOrder(*order)
+		return st.insertEvent(ctx, order.Version, event)
+	})
+}
+
+func (s *Store) GetOrder(ctx context.Context, orderID string) (*domain.Order, error) {
+	var order *domain.Order
+	err := s.view(ctx, func(st *state) error {
+		stored, ok := st.orders[orderID]
+		if !ok {
+			return repository.ErrOrderNotFound
+		}
+
+		o := copyOrder(stored)
+		order = &o
+		return nil
+	})
+	return order, err
+}
+
+// GetOrderForUpdate is GetOrder; a transaction already holds the whole store.
+func (s *Store) GetOrderForUpdate(ctx context.Context, orderID string) (*domain.Order, error) {
+	return s.GetOrder(ctx, orderID)
+}
+
+// UpdateOrderStatus applies the same checks as the conditional UPDATE of
+// repository.OrderRepository and fails with the same errors.
+func (s *Store) UpdateOrderStatus(ctx context.Context, orderID string, expectedVersion int, status domain.OrderStatus, event domain.Event) error {
+	return s.update(ctx, func(st *state) error {
+		order, ok := st.orders[orderID]
+		if !ok {
+			return repository.ErrOrderNotFound
+		}
+		if order.Version != expectedVersion {
+			return &repository.VersionConflictError{OrderID: orderID, Expected: expectedVersion, Actual: order.Version}
+		}
+		if !slices.Contains(domain.SourceStatuses(status), order.Status) {
+			return &domain.InvalidTransitionError{OrderID: orderID, From: order.Status, To: status}
+		}
+
+		at := event.Timestamp()
+		order.Status = status
+		order.UpdatedAt = at
+		order.Version++
+		if stamp := statusTimestamp(&order, status); stamp != nil {
+			*stamp = &at
+		}
+
+		st.orders[orderID] = order
+		return st.insertEvent(ctx, order.Version, event)
+	})
+}
+
+func (s *Store) ListOrders(ctx context.Context, filter repository.OrderFilter, cursor *repository.OrderCursor, limit int) (*repository.OrderPage, error) {
+	var page *repository.OrderPage
+	err := s.view(ctx, func(st *state) error {
+		backward := cursor != nil && cursor.Backward
+		ascending := filter.OldestFirst != backward
+
+		var orders []domain.Order
+		for _, order := range st.orders {
+			if matches(order, filter) && (cursor == nil || after(order, cursor, ascending)) {
+				orders = append(orders, copyOrder(order))
+			}
+		}
+
+		sort.Slice(orders, func(i, j int) bool {
+			return less(orders[i], orders[j]) == ascending
+		})
+		if len(orders) > limit+1 {
+			orders = orders[:limit+1]
+		}
+
+		page = repository.NewOrderPage(orders, cursor, limit)
+		return nil
+	})
+	return page, err
+}
+
+func (s *Store) CountUserOrdersBetween(ctx context.Context, userID string, from, to time.Time, excludeOrderID string) (int, error) {
+	var count int
+	err := s.view(ctx, func(st *state) error {
+		for _, order := range st.orders {
+			if order.UserID == userID && !order.CreatedAt.Before(from) && !order.CreatedAt.After(to) && order.ID != excludeOrderID &&
+				order.Status != domain.OrderStatusFailed && order.Status != domain.OrderStatusCancelled {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (s *Store) CountOpenOrdersByRestaurant(ctx context.Context) (map[string]int, error) {
+	counts := make(map[string]int)
+	err := s.view(ctx, func(st *state) error {
+		for _, order := range st.orders {
+			if slices.Contains(domain.OpenStatuses, order.Status) {
+				counts[order.RestaurantID]++
+			}
+		}
+		return nil
+	})
+	return counts, err
+}
+
+func (s *Store) ClaimIdempotencyKey(ctx context.Context, key repository.IdempotencyKey, ttl time.Duration) (*domain.Order, error) {
+	if s.tx == nil {
+		return nil, fmt.Errorf("claim idempotency key: must run in a transaction")
+	}
+
+	record, ok := s.tx.idempotency[key.Key]
+	if !ok || !record.expiresAt.After(time.Now()) {
+		s.tx.idempotency[key.Key] = idempotencyRecord{
+			requestHash: key.RequestHash,
+			expiresAt:   time.Now().Add(ttl),
+		}
+		return nil, nil
+	}
+
+	if record.requestHash != key.RequestHash {
+		return nil, repository.ErrIdempotencyKeyReused
+	}
+
+	var order domain.Order
+	if err := json.Unmarshal(record.response, &order); err != nil {
+		return nil, fmt.Errorf("unmarshal idempotent response: %w", err)
+	}
+	return &order, nil
+}
+
+func (s *Store) SaveIdempotentOrder(ctx context.Context, key repository.IdempotencyKey, order *domain.Order) error {
+	response, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("marshal idempotent response: %w", err)
+	}
+
+	return s.update(ctx, func(st *state) error {
+		record, ok := st.idempotency[key.Key]
+		if ok {
+			record.response = response
+			st.idempotency[key.Key] = record
+		}
+		return nil
+	})
+}
+
+func (s *Store) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	var deleted int64
+	err := s.update(ctx, func(st *state) error {
+		now := time.Now()
+		for key, record := range st.idempotency {
+			if !record.expiresAt.After(now) {
+				delete(st.idempotency, key)
+				deleted++
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func (s *Store) ConsumerOffset(ctx context.Context, group, topic string, partition int) (int64, bool, error) {
+	var (
+		offset int64
+		found  bool
+	)
+	err := s.view(ctx, func(st *state) error {
+		offset, found = st.offsets[offsetKey{group, topic, partition}]
+		return nil
+	})
+	return offset, found, err
+}
+
+func (s *Store) SaveConsumerOffset(ctx context.Context, group, topic string, partition int, offset int64) error {
+	return s.update(ctx, func(st *state) error {
+		st.offsets[offsetKey{group, topic, partition}] = offset
+		return nil
+	})
+}
+
+func (s *Store) OrderEvents(ctx context.Context, orderID string) ([]domain.RecordedEvent, error) {
+	var events []domain.RecordedEvent
+	err := s.view(ctx, func(st *state) error {
+		for _, stored := range st.events {
+			if stored.AggregateID != orderID {
+				continue
+			}
+
+			event, err := envelope.DecodeStored(stored.EventType, stored.Payload)
+			if err != nil {
+				return fmt.Errorf("order %s version %d: %w", orderID, stored.Version, err)
+			}
+			events = append(events, domain.RecordedEvent{Event: event, Version: stored.Version})
+		}
+		return nil
+	})
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Version < events[j].Version
+	})
+	return events, err
+}
+
+// PublishPendingEvents behaves like its Postgres counterpart: events are handed
+// to publish in the order they were written and stay marked once published,
+// even when a later one fails.
+func (s *Store) PublishPendingEvents(ctx context.Context, limit int, publish func(ctx context.Context, event repository.OutboxEvent) error) (int, error) {
+	var (
+		published  int
+		publishErr error
+	)
+	err := s.update(ctx, func(st *state) error {
+		for i := range st.events {
+			if published == limit {
+				break
+			}
+			if st.events[i].published {
+				continue
+			}
+
+			if err := publish(ctx, st.events[i].OutboxEvent); err != nil {
+				publishErr = fmt.Errorf("publish event %d: %w", st.events[i].ID, err)
+				break
+			}
+			st.events[i].published = true
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return published, publishErr
+}
+
+// insertEvent appends event as the given order version, refusing a second
+// event for the same version like the unique index on the events table.
+func (st *state) insertEvent(ctx context.Context, version int, event domain.Event) error {
+	for _, stored := range st.events {
+		if stored.AggregateID == event.AggregateID() && stored.Version == version {
+			return fmt.Errorf("insert %s event: order %s already has version %d", event.EventType(), event.AggregateID(), version)
+		}
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", event.EventType(), err)
+	}
+
+	st.events = append(st.events, storedEvent{OutboxEvent: repository.OutboxEvent{
+		ID:          st.nextEventID,
+		AggregateID: event.AggregateID(),
+		EventType:   event.EventType(),
+		Payload:     payload,
+		Version:     version,
+		CreatedAt:   event.Timestamp(),
+
+		CorrelationID: requestid.FromContext(ctx),
+		TraceParent:   tracing.TraceParent(ctx),
+	}})
+	st.nextEventID++
+	return nil
+}
+
+func statusTimestamp(order *domain.Order, status domain.OrderStatus) **time.Time {
+	switch status {
+	case domain.OrderStatusAccepted:
+		return &order.AcceptedAt
+	case domain.OrderStatusPreparing:
+		return &order.PreparingAt
+	case domain.OrderStatusReady:
+		return &order.ReadyAt
+	case domain.OrderStatusPickedUp:
+		return &order.PickedUpAt
+	case domain.OrderStatusOutForDelivery:
+		return &order.OutForDeliveryAt
+	case domain.OrderStatusDelivered:
+		return &order.DeliveredAt
+	default:
+		return nil
+	}
+}
+
+func matches(order domain.Order, filter repository.OrderFilter) bool {
+	switch {
+	case filter.UserID != "" && order.UserID != filter.UserID:
+		return false
+	case filter.RestaurantID != "" && order.RestaurantID != filter.RestaurantID:
+		return false
+	case len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, order.Status):
+		return false
+	case !filter.CreatedFrom.IsZero() && order.CreatedAt.Before(filter.CreatedFrom):
+		return false
+	case !filter.CreatedTo.IsZero() && !order.CreatedAt.Before(filter.CreatedTo):
+		return false
+	}
+	return true
+}
+
+// less orders by (created_at, id) ascending.
+func less(a, b domain.Order) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// after reports whether order comes after the cursor in the direction read.
+func after(order domain.Order, cursor *repository.OrderCursor, ascending bool) bool {
+	at := domain.Order{ID: cursor.ID, CreatedAt: cursor.CreatedAt}
+	if ascending {
+		return less(at, order)
+	}
+	return less(order, at)
+}
+
+func copyOrder(o domain.Order) domain.Order {
+	o.Items = slices.Clone(o.Items)
+	for _, stamp := range []**time.Time{&o.AcceptedAt, &o.PreparingAt, &o.ReadyAt, &o.PickedUpAt, &o.OutForDeliveryAt, &o.DeliveredAt} {
+		if *stamp != nil {
+			t := **stamp
+			*stamp = &t
+		}
+	}
+	return o
+}
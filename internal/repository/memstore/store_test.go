@@ -0,0 +1,193 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+)
+
+func newTestOrder(t *testing.T) (*domain.Order, domain.Event) {
+	t.Helper()
+	order, err := domain.NewOrder("user-1", "rest-1", []domain.OrderItem{
+		{ID: "line-1", ItemID: "item-1", Price: domain.NewMoney(1000, "INR"), Quantity: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewOrder: %v", err)
+	}
+	return order, domain.NewOrderCreatedEvent(order)
+}
+
+func TestRunInTxRollsBackOnError(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+	order, event := newTestOrder(t)
+	errAbort := errors.New("abort")
+
+	err := store.RunInTx(ctx, func(tx repository.OrderStore) error {
+		if err := tx.CreateOrder(ctx, order, event); err != nil {
+			return err
+		}
+		if _, err := tx.GetOrder(ctx, order.ID); err != nil {
+			t.Errorf("order not visible inside its transaction: %v", err)
+		}
+		return tx.RunInTx(ctx, func(inner repository.OrderStore) error {
+			if err := inner.SaveConsumerOffset(ctx, "group", "orders", 0, 9); err != nil {
+				return err
+			}
+			return errAbort
+		})
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("RunInTx err = %v, want errAbort", err)
+	}
+
+	if _, err := store.GetOrder(ctx, order.ID); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("GetOrder after rollback err = %v, want ErrOrderNotFound", err)
+	}
+	if _, found, _ := store.ConsumerOffset(ctx, "group", "orders", 0); found {
+		t.Error("offset saved in a nested transaction survived the rollback")
+	}
+}
+
+func TestReadsReturnCopies(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+	order, event := newTestOrder(t)
+	if err := store.CreateOrder(ctx, order, event); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	read, err := store.GetOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	read.Status, read.Items[0].Quantity = domain.OrderStatusCancelled, 7
+
+	page, err := store.ListOrders(ctx, repository.OrderFilter{}, nil, 10)
+	if err != nil {
+		t.Fatalf("ListOrders: %v", err)
+	}
+	page.Orders[0].Items[0].Quantity = 9
+
+	stored, err := store.GetOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != domain.OrderStatusPending || stored.Items[0].Quantity != 1 {
+		t.Errorf("stored order = %s with quantity %d, want it unchanged by its readers", stored.Status, stored.Items[0].Quantity)
+	}
+}
+
+func TestUpdateOrderStatusChecksVersionAndTransition(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+	order, event := newTestOrder(t)
+	if err := store.CreateOrder(ctx, order, event); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	confirmed := domain.NewOrderConfirmedEvent(order.ID)
+	if err := store.UpdateOrderStatus(ctx, order.ID, 2, domain.OrderStatusConfirmed, confirmed); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("wrong version err = %v, want ErrVersionConflict", err)
+	}
+	if err := store.UpdateOrderStatus(ctx, order.ID, 1, domain.OrderStatusDelivered, confirmed); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("invalid transition err = %v, want ErrInvalidTransition", err)
+	}
+	if err := store.UpdateOrderStatus(ctx, "missing", 1, domain.OrderStatusConfirmed, confirmed); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("missing order err = %v, want ErrOrderNotFound", err)
+	}
+	if err := store.UpdateOrderStatus(ctx, order.ID, 1, domain.OrderStatusConfirmed, confirmed); err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+
+	stored, err := store.GetOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != domain.OrderStatusConfirmed || stored.Version != 2 {
+		t.Errorf("order is %s at version %d, want CONFIRMED at 2", stored.Status, stored.Version)
+	}
+}
+
+func TestPublishPendingEventsKeepsProgressOnError(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+
+	var ids []string
+	for range 3 {
+		order, event := newTestOrder(t)
+		if err := store.CreateOrder(ctx, order, event); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+		ids = append(ids, order.ID)
+	}
+
+	errBroker := errors.New("broker down")
+	var seen []string
+	published, err := store.PublishPendingEvents(ctx, 10, func(_ context.Context, event repository.OutboxEvent) error {
+		if len(seen) == 2 {
+			return errBroker
+		}
+		seen = append(seen, event.AggregateID)
+		return nil
+	})
+	if !errors.Is(err, errBroker) || published != 2 {
+		t.Fatalf("PublishPendingEvents = %d, %v; want 2, errBroker", published, err)
+	}
+
+	var rest []string
+	published, err = store.PublishPendingEvents(ctx, 10, func(_ context.Context, event repository.OutboxEvent) error {
+		rest = append(rest, event.AggregateID)
+		return nil
+	})
+	if err != nil || published != 1 || rest[0] != ids[2] {
+		t.Errorf("second run published %v, %v; want only %s", rest, err, ids[2])
+	}
+	if seen[0] != ids[0] || seen[1] != ids[1] {
+		t.Errorf("first run published %v, want %v in order", seen, ids[:2])
+	}
+}
+
+func TestClaimedWebhookDeliveriesWaitForTheirLease(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+
+	err := store.CreateWebhookSubscription(ctx, &repository.WebhookSubscription{
+		ID:           "hook",
+		RestaurantID: "rest-1",
+		URL:          "https://partner.example/hooks",
+		Secret:       "secret",
+		EventTypes:   []domain.EventType{domain.OrderCreatedEventType},
+		Active:       true,
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhookSubscription: %v", err)
+	}
+	queued, err := store.EnqueueWebhookDeliveries(ctx, repository.WebhookEvent{
+		ID:           "event-1",
+		Type:         domain.OrderCreatedEventType,
+		OrderID:      "order-1",
+		RestaurantID: "rest-1",
+		Payload:      []byte(`{}`),
+	})
+	if err != nil || queued != 1 {
+		t.Fatalf("EnqueueWebhookDeliveries = %d, %v; want 1", queued, err)
+	}
+
+	claimed, err := store.ClaimWebhookDeliveries(ctx, 10, 20*time.Millisecond)
+	if err != nil || len(claimed) != 1 || claimed[0].URL != "https://partner.example/hooks" || claimed[0].Secret != "secret" {
+		t.Fatalf("ClaimWebhookDeliveries = %+v, %v; want the delivery with its endpoint", claimed, err)
+	}
+	if again, _ := store.ClaimWebhookDeliveries(ctx, 10, time.Minute); len(again) != 0 {
+		t.Errorf("claimed %d deliveries during the lease, want none", len(again))
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if again, _ := store.ClaimWebhookDeliveries(ctx, 10, time.Minute); len(again) != 1 {
+		t.Errorf("claimed %d deliveries after the lease lapsed, want 1", len(again))
+	}
+}
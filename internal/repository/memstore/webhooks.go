@@ -0,0 +1,205 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+)
+
+func (s *Store) CreateWebhookSubscription(ctx context.Context, sub *repository.WebhookSubscription) error {
+	return s.update(ctx, func(st *state) error {
+		if _, ok := st.webhooks[sub.ID]; ok {
+			return fmt.Errorf("insert webhook subscription: subscription %s already exists", sub.ID)
+		}
+		st.webhooks[sub.ID] = copyWebhookSubscription(*sub)
+		return nil
+	})
+}
+
+func (s *Store) GetWebhookSubscription(ctx context.Context, id string) (*repository.WebhookSubscription, error) {
+	var sub repository.WebhookSubscription
+	err := s.view(ctx, func(st *state) error {
+		stored, ok := st.webhooks[id]
+		if !ok {
+			return fmt.Errorf("%w: %s", repository.ErrWebhookNotFound, id)
+		}
+		sub = copyWebhookSubscription(stored)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (s *Store) ListWebhookSubscriptions(ctx context.Context, restaurantID string) ([]repository.WebhookSubscription, error) {
+	var subs []repository.WebhookSubscription
+	err := s.view(ctx, func(st *state) error {
+		for _, sub := range st.webhooks {
+			if sub.RestaurantID == restaurantID {
+				subs = append(subs, copyWebhookSubscription(sub))
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(subs, func(i, j int) bool {
+		if !subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+		}
+		return subs[i].ID < subs[j].ID
+	})
+	return subs, err
+}
+
+func (s *Store) UpdateWebhookSubscription(ctx context.Context, sub *repository.WebhookSubscription) error {
+	return s.update(ctx, func(st *state) error {
+		stored, ok := st.webhooks[sub.ID]
+		if !ok {
+			return fmt.Errorf("%w: %s", repository.ErrWebhookNotFound, sub.ID)
+		}
+
+		updated := copyWebhookSubscription(*sub)
+		updated.RestaurantID, updated.Secret, updated.CreatedAt = stored.RestaurantID, stored.Secret, stored.CreatedAt
+		st.webhooks[sub.ID] = updated
+		return nil
+	})
+}
+
+// DeleteWebhookSubscription removes the subscription and its deliveries, as
+// the foreign key cascades in Postgres.
+func (s *Store) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	return s.update(ctx, func(st *state) error {
+		if _, ok := st.webhooks[id]; !ok {
+			return fmt.Errorf("%w: %s", repository.ErrWebhookNotFound, id)
+		}
+		delete(st.webhooks, id)
+		st.deliveries = slices.DeleteFunc(st.deliveries, func(d repository.WebhookDelivery) bool {
+			return d.SubscriptionID == id
+		})
+		return nil
+	})
+}
+
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, event repository.WebhookEvent) (int, error) {
+	var queued int
+	err := s.update(ctx, func(st *state) error {
+		now := time.Now().UTC()
+		for _, sub := range st.webhooks {
+			if sub.RestaurantID != event.RestaurantID || !sub.Active || !slices.Contains(sub.EventTypes, event.Type) {
+				continue
+			}
+			if slices.ContainsFunc(st.deliveries, func(d repository.WebhookDelivery) bool {
+				return d.SubscriptionID == sub.ID && d.EventID == event.ID
+			}) {
+				continue
+			}
+
+			st.deliveries = append(st.deliveries, repository.WebhookDelivery{
+				ID:             st.nextDeliveryID,
+				SubscriptionID: sub.ID,
+				EventID:        event.ID,
+				EventType:      event.Type,
+				OrderID:        event.OrderID,
+				Payload:        slices.Clone(event.Payload),
+				Status:         repository.WebhookDeliveryPending,
+				NextAttemptAt:  now,
+				CreatedAt:      now,
+			})
+			st.nextDeliveryID++
+			queued++
+		}
+		return nil
+	})
+	return queued, err
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, subscriptionID string, status repository.WebhookDeliveryStatus, limit int) ([]repository.WebhookDelivery, error) {
+	var deliveries []repository.WebhookDelivery
+	err := s.view(ctx, func(st *state) error {
+		for i := len(st.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+			d := st.deliveries[i]
+			if d.SubscriptionID == subscriptionID && (status == "" || d.Status == status) {
+				deliveries = append(deliveries, d)
+			}
+		}
+		return nil
+	})
+	return deliveries, err
+}
+
+// ClaimWebhookDeliveries behaves like its Postgres counterpart: the claimed
+// deliveries are not due again until lease has passed.
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]repository.PendingWebhook, error) {
+	var claimed []repository.PendingWebhook
+	err := s.update(ctx, func(st *state) error {
+		now := time.Now().UTC()
+
+		var due []int
+		for i, d := range st.deliveries {
+			if d.Status == repository.WebhookDeliveryPending && !d.NextAttemptAt.After(now) && st.webhooks[d.SubscriptionID].Active {
+				due = append(due, i)
+			}
+		}
+		sort.SliceStable(due, func(a, b int) bool {
+			return st.deliveries[due[a]].NextAttemptAt.Before(st.deliveries[due[b]].NextAttemptAt)
+		})
+
+		for _, i := range due[:min(limit, len(due))] {
+			st.deliveries[i].NextAttemptAt = now.Add(lease)
+			sub := st.webhooks[st.deliveries[i].SubscriptionID]
+			claimed = append(claimed, repository.PendingWebhook{WebhookDelivery: st.deliveries[i], URL: sub.URL, Secret: sub.Secret})
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+func (s *Store) RecordWebhookAttempt(ctx context.Context, attempt repository.WebhookAttempt, disableAfter int) (bool, error) {
+	var disabled bool
+	err := s.update(ctx, func(st *state) error {
+		i := slices.IndexFunc(st.deliveries, func(d repository.WebhookDelivery) bool { return d.ID == attempt.DeliveryID })
+		if i < 0 {
+			return fmt.Errorf("record webhook delivery %d attempt: no such delivery", attempt.DeliveryID)
+		}
+
+		now := time.Now().UTC()
+		d := &st.deliveries[i]
+		d.Status = attempt.Status
+		d.Attempts++
+		d.LastAttemptAt = &now
+		d.NextAttemptAt = now.Add(attempt.RetryIn)
+		d.ResponseStatus = attempt.ResponseStatus
+		d.LastError = attempt.Error
+
+		sub := st.webhooks[d.SubscriptionID]
+		if attempt.Status == repository.WebhookDeliveryDelivered {
+			sub.ConsecutiveFailures = 0
+		} else {
+			sub.ConsecutiveFailures++
+			if sub.Active && sub.ConsecutiveFailures >= disableAfter {
+				sub.Active = false
+				sub.DisabledAt = &now
+				sub.DisabledReason = repository.WebhookDisabledReason(sub.ConsecutiveFailures, attempt.Error)
+				sub.UpdatedAt = now
+				disabled = true
+			}
+		}
+		st.webhooks[d.SubscriptionID] = sub
+		return nil
+	})
+	return disabled, err
+}
+
+func copyWebhookSubscription(sub repository.WebhookSubscription) repository.WebhookSubscription {
+	sub.EventTypes = slices.Clone(sub.EventTypes)
+	if sub.DisabledAt != nil {
+		t := *sub.DisabledAt
+		sub.DisabledAt = &t
+	}
+	return sub
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// OrderFilter narrows a listing of orders. Empty fields match everything;
+// CreatedFrom is inclusive and CreatedTo exclusive. OldestFirst lists orders
+// in ascending rather than descending (created_at, id) order.
+type OrderFilter struct {
+	UserID       string
+	RestaurantID string
+	Statuses     []domain.OrderStatus
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+	OldestFirst  bool
+}
+
+// OrderCursor marks a position in the (created_at, id) ordering of orders.
+// Backward cursors fetch the page before the position rather than after it.
+type OrderCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c OrderCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeOrderCursor(token string) (*OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c OrderCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == "" || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// OrderPage is one page of orders, in the order the filter asked for, with
+// cursors for the pages either side of it. A nil cursor means there is no
+// such page.
+type OrderPage struct {
+	Orders []domain.Order
+	Next   *OrderCursor
+	Prev   *OrderCursor
+}
+
+// ListOrders returns up to limit orders matching filter, newest first unless
+// filter.OldestFirst is set, on the page identified by cursor, or the first
+// page when cursor is nil. Paging uses the (created_at, id) key rather than
+// offsets, so it stays cheap however deep it goes and does not skip or repeat
+// orders inserted while paging.
+func (r *OrderRepository) ListOrders(ctx context.Context, filter OrderFilter, cursor *OrderCursor, limit int) (*OrderPage, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.UserID != "" {
+		conds = append(conds, "user_id = "+arg(filter.UserID))
+	}
+	if filter.RestaurantID != "" {
+		conds = append(conds, "restaurant_id = "+arg(filter.RestaurantID))
+	}
+	if len(filter.Statuses) > 0 {
+		placeholders := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			placeholders[i] = arg(status)
+		}
+		conds = append(conds, "status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if !filter.CreatedFrom.IsZero() {
+		conds = append(conds, "created_at >= "+arg(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		conds = append(conds, "created_at < "+arg(filter.CreatedTo))
+	}
+
+	// Backward pages are read in reverse from the cursor and flipped afterwards
+	backward := cursor != nil && cursor.Backward
+	op, order := "<", "created_at DESC, id DESC"
+	if filter.OldestFirst != backward {
+		op, order = ">", "created_at ASC, id ASC"
+	}
+	if cursor != nil {
+		conds = append(conds, fmt.Sprintf("(created_at, id) %s (%s, %s)", op, arg(cursor.CreatedAt), arg(cursor.ID)))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	// One extra row tells whether there is another page in this direction
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT `+orderColumns+`
+		FROM orders `+where+` ORDER BY `+order+` LIMIT `+arg(limit+1),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []domain.Order
+	for rows.Next() {
+		var order domain.Order
+		if err := scanOrder(rows, &order); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewOrderPage(orders, cursor, limit), nil
+}
+
+// NewOrderPage builds the page at cursor from up to limit+1 orders read in
+// query order, i.e. reversed for backward cursors. The extra order only
+// signals that another page follows in the direction read.
+func NewOrderPage(orders []domain.Order, cursor *OrderCursor, limit int) *OrderPage {
+	backward := cursor != nil && cursor.Backward
+
+	more := len(orders) > limit
+	if more {
+		orders = orders[:limit]
+	}
+	if backward {
+		slices.Reverse(orders)
+	}
+
+	page := &OrderPage{Orders: orders}
+	if len(orders) == 0 {
+		return page
+	}
+
+	first, last := orders[0], orders[len(orders)-1]
+	if (backward && more) || (!backward && cursor != nil) {
+		page.Prev = &OrderCursor{CreatedAt: first.CreatedAt, ID: first.ID, Backward: true}
+	}
+	if backward || more {
+		page.Next = &OrderCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	return page
+}
+
+// CountOpenOrdersByRestaurant counts, per restaurant, the orders in one of
+// domain.OpenStatuses. Restaurants without open orders are left out.
+func (r *OrderRepository) CountOpenOrdersByRestaurant(ctx context.Context) (map[string]int, error) {
+	args := make([]any, len(domain.OpenStatuses))
+	placeholders := make([]string, len(domain.OpenStatuses))
+	for i, status := range domain.OpenStatuses {
+		args[i] = status
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT restaurant_id, COUNT(*) FROM orders
+		WHERE status IN (`+strings.Join(placeholders, ", ")+`)
+		GROUP BY restaurant_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("count open orders: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			restaurantID string
+			count        int
+		)
+		if err := rows.Scan(&restaurantID, &count); err != nil {
+			return nil, err
+		}
+		counts[restaurantID] = count
+	}
+
+	return counts, rows.Err()
+}
@@ -3,20 +3,80 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/dmehra2102/order-management-platform/internal/domain"
 )
 
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrVersionConflict matches every *VersionConflictError with errors.Is.
+var ErrVersionConflict = errors.New("order version conflict")
+
+// VersionConflictError reports that an order changed since the caller read it.
+type VersionConflictError struct {
+	OrderID  string
+	Expected int
+	Actual   int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("order %s is at version %d, expected %d", e.OrderID, e.Actual, e.Expected)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+const orderColumns = `id, user_id, restaurant_id, total_amount, currency, status, created_at, updated_at, version,
+	accepted_at, preparing_at, ready_at, picked_up_at, out_for_delivery_at, delivered_at`
+
+// statusTimestampColumns maps fulfilment statuses to the column recording when
+// an order reached them.
+var statusTimestampColumns = map[domain.OrderStatus]string{
+	domain.OrderStatusAccepted:       "accepted_at",
+	domain.OrderStatusPreparing:      "preparing_at",
+	domain.OrderStatusReady:          "ready_at",
+	domain.OrderStatusPickedUp:       "picked_up_at",
+	domain.OrderStatusOutForDelivery: "out_for_delivery_at",
+	domain.OrderStatusDelivered:      "delivered_at",
+}
+
+// querier is the subset of *sql.DB and *sql.Tx used by the repository. Every
+// statement goes through a tracedQuerier.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type OrderRepository struct {
 	db *sql.DB
+	q  querier
+	tx *sql.Tx
 }
 
 func NewOrderRepository(db *sql.DB) *OrderRepository {
-	return &OrderRepository{db: db}
+	return &OrderRepository{db: db, q: tracedQuerier{db}}
+}
+
+// RunInTx calls fn with a store bound to a single transaction and commits it
+// if fn succeeds. On a repository that is already transactional fn joins the
+// outer transaction.
+func (r *OrderRepository) RunInTx(ctx context.Context, fn func(tx OrderStore) error) error {
+	return r.runInTx(ctx, func(tx *OrderRepository) error {
+		return fn(tx)
+	})
 }
 
-func (r *OrderRepository) CreateOrder(ctx context.Context, order *domain.Order) error {
+func (r *OrderRepository) runInTx(ctx context.Context, fn func(tx *OrderRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("start tx: %w", err)
@@ -26,18 +86,39 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *domain.Order)
 		_ = tx.Rollback()
 	}()
 
+	if err := fn(&OrderRepository{db: r.db, q: tracedQuerier{tx}, tx: tx}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOrder stores the order, its items and the event that created it
+// atomically.
+func (r *OrderRepository) CreateOrder(ctx context.Context, order *domain.Order, event domain.Event) error {
+	return r.runInTx(ctx, func(tx *OrderRepository) error {
+		return tx.insertOrder(ctx, order, event)
+	})
+}
+
+func (r *OrderRepository) insertOrder(ctx context.Context, order *domain.Order, event domain.Event) error {
 	// Insert order
-	_, err = tx.ExecContext(ctx, `
+	_, err := r.q.ExecContext(ctx, `
 		INSERT INTO orders (
-			id, user_id, restaurant_id, total_amount, status,
+			id, user_id, restaurant_id, total_amount, currency, status,
 			created_at, updated_at, version
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`,
 		order.ID,
 		order.UserID,
 		order.RestaurantID,
 		order.TotalAmount,
+		order.TotalAmount.Currency,
 		order.Status,
 		order.CreatedAt,
 		order.UpdatedAt,
@@ -49,7 +130,7 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *domain.Order)
 
 	// Insert items
 	for _, item := range order.Items {
-		_, err = tx.ExecContext(ctx, `
+		_, err = r.q.ExecContext(ctx, `
 			INSERT INTO order_items (
 				id, order_id, item_id, name, price, quantity, created_at
 			)
@@ -68,30 +149,40 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *domain.Order)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit tx: %w", err)
+	// Insert the event for the event log and the outbox relay
+	if err := r.insertEvent(ctx, order.Version, event); err != nil {
+		return err
 	}
 
 	return nil
 }
 
 func (r *OrderRepository) GetOrder(ctx context.Context, orderID string) (*domain.Order, error) {
+	return r.getOrder(ctx, orderID, "")
+}
+
+// GetOrderForUpdate loads the order and locks its row until the surrounding
+// transaction ends. Use it inside RunInTx.
+func (r *OrderRepository) GetOrderForUpdate(ctx context.Context, orderID string) (*domain.Order, error) {
+	return r.getOrder(ctx, orderID, "FOR UPDATE")
+}
+
+func (r *OrderRepository) getOrder(ctx context.Context, orderID, lock string) (*domain.Order, error) {
 	order := &domain.Order{}
 
-	err := r.db.QueryRowContext(ctx, `
-		SELECT id, user_id, restaurant_id, total_amount, status, created_at, updated_at, version
-		FROM orders WHERE id = $1
-	`, orderID).Scan(&order.ID, &order.UserID, &order.RestaurantID, &order.TotalAmount, &order.Status, &order.CreatedAt, &order.UpdatedAt, &order.Version)
+	err := scanOrder(r.q.QueryRowContext(ctx, `
+		SELECT `+orderColumns+`
+		FROM orders WHERE id = $1 `+lock, orderID), order)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("order not found")
+			return nil, ErrOrderNotFound
 		}
 		return nil, err
 	}
 
 	// Get items
-	rows, err := r.db.QueryContext(ctx, `
+	rows, err := r.q.QueryContext(ctx, `
 		SELECT id, item_id, name, price, quantity
 		FROM order_items WHERE order_id = $1
 	`, orderID)
@@ -106,39 +197,111 @@ func (r *OrderRepository) GetOrder(ctx context.Context, orderID string) (*domain
 		if err := rows.Scan(&item.ID, &item.ItemID, &item.Name, &item.Price, &item.Quantity); err != nil {
 			return nil, err
 		}
+		item.Price.Currency = order.TotalAmount.Currency
 		order.Items = append(order.Items, item)
 	}
 
 	return order, rows.Err()
 }
 
-func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID string, status domain.OrderStatus) error {
-	_, err := r.db.ExecContext(ctx, `
-		UPDATE orders SET status = $1, updated_at = NOW(), version = version + 1
-		WHERE id = $2
-	`, status, orderID)
-	return err
-}
+// UpdateOrderStatus changes the status of the order at expectedVersion and
+// stores the event describing the change with the new version in the same
+// transaction; the event time becomes the order's updated_at. The UPDATE only
+// matches an order still at expectedVersion and in a status allowed to move to
+// status, so concurrent writers cannot clobber each other or bypass the state
+// machine. A refused change returns a *VersionConflictError or a
+// *domain.InvalidTransitionError.
+func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID string, expectedVersion int, status domain.OrderStatus, event domain.Event) error {
+	sources := domain.SourceStatuses(status)
 
-func (r *OrderRepository) ListOrders(ctx context.Context, userID string, limit int) ([]domain.Order, error) {
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, user_id, restaurant_id, total_amount, status, created_at, updated_at, version
-		FROM orders WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2
-	`, userID, limit)
+	return r.runInTx(ctx, func(tx *OrderRepository) error {
+		if len(sources) == 0 {
+			return tx.updateError(ctx, orderID, expectedVersion, status)
+		}
+
+		args := []any{status, orderID, event.Timestamp(), expectedVersion}
+		placeholders := make([]string, len(sources))
+		for i, source := range sources {
+			args = append(args, source)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
 
+		set := "status = $1, updated_at = $3, version = version + 1"
+		if column, ok := statusTimestampColumns[status]; ok {
+			set += ", " + column + " = $3"
+		}
+
+		var version int
+		err := tx.q.QueryRowContext(ctx, `
+			UPDATE orders SET `+set+`
+			WHERE id = $2 AND version = $4 AND status IN (`+strings.Join(placeholders, ", ")+`)
+			RETURNING version
+		`, args...).Scan(&version)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return tx.updateError(ctx, orderID, expectedVersion, status)
+			}
+			return err
+		}
+
+		return tx.insertEvent(ctx, version, event)
+	})
+}
+
+// updateError explains why an order could not be moved to status.
+func (r *OrderRepository) updateError(ctx context.Context, orderID string, expectedVersion int, status domain.OrderStatus) error {
+	var (
+		current domain.OrderStatus
+		version int
+	)
+	err := r.q.QueryRowContext(ctx, `SELECT status, version FROM orders WHERE id = $1`, orderID).Scan(&current, &version)
 	if err != nil {
-		return nil, err
+		if err == sql.ErrNoRows {
+			return ErrOrderNotFound
+		}
+		return err
 	}
-	defer rows.Close()
 
-	var orders []domain.Order
-	for rows.Next() {
-		var order domain.Order
-		if err := rows.Scan(&order.ID, &order.UserID, &order.RestaurantID, &order.TotalAmount, &order.Status, &order.CreatedAt, &order.UpdatedAt, &order.Version); err != nil {
-			return nil, err
-		}
-		orders = append(orders, order)
+	if version != expectedVersion {
+		return &VersionConflictError{OrderID: orderID, Expected: expectedVersion, Actual: version}
 	}
 
-	return orders, rows.Err()
+	return &domain.InvalidTransitionError{OrderID: orderID, From: current, To: status}
+}
+
+// CountUserOrdersBetween counts the orders userID created from one time up
+// to and including another, leaving out failed and cancelled orders and
+// excludeOrderID.
+func (r *OrderRepository) CountUserOrdersBetween(ctx context.Context, userID string, from, to time.Time, excludeOrderID string) (int, error) {
+	var count int
+	err := r.q.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM orders
+		WHERE user_id = $1 AND created_at >= $2 AND created_at <= $3 AND id <> $4 AND status NOT IN ($5, $6)
+	`, userID, from, to, excludeOrderID, domain.OrderStatusFailed, domain.OrderStatusCancelled).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count user orders: %w", err)
+	}
+
+	return count, nil
+}
+
+// scanOrder reads a row selected with orderColumns into order.
+func scanOrder(row interface{ Scan(dest ...any) error }, order *domain.Order) error {
+	return row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.RestaurantID,
+		&order.TotalAmount,
+		&order.TotalAmount.Currency,
+		&order.Status,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.Version,
+		&order.AcceptedAt,
+		&order.PreparingAt,
+		&order.ReadyAt,
+		&order.PickedUpAt,
+		&order.OutForDeliveryAt,
+		&order.DeliveredAt,
+	)
 }
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/requestid"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+)
+
+// outboxLockKey is the advisory lock held by the relay draining the outbox, so
+// that only one relay publishes at a time and events keep their order.
+const outboxLockKey int64 = 72100001
+
+type OutboxEvent struct {
+	ID          int64
+	AggregateID string
+	EventType   domain.EventType
+	Payload     []byte
+	Version     int
+	CreatedAt   time.Time
+	// Request ID of the work that produced the event, if known
+	CorrelationID string
+	// W3C traceparent of the span that produced the event, if any
+	TraceParent string
+}
+
+// insertEvent appends event to the events table as the given order version,
+// tagged with the request ID and trace context carried by ctx. The table is
+// both the order's event log and the outbox read by the relay.
+func (r *OrderRepository) insertEvent(ctx context.Context, version int, event domain.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", event.EventType(), err)
+	}
+
+	_, err = r.q.ExecContext(ctx, `
+		INSERT INTO events (aggregate_id, event_type, event_data, created_at, version, correlation_id, trace_parent)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+	`,
+		event.AggregateID(),
+		event.EventType(),
+		payload,
+		event.Timestamp(),
+		version,
+		requestid.FromContext(ctx),
+		tracing.TraceParent(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("insert %s event: %w", event.EventType(), err)
+	}
+
+	return nil
+}
+
+// PublishPendingEvents hands up to limit unpublished events to publish in the
+// order they were written and marks each one published once publish returns.
+// It stops at the first publish error; events published before it stay marked.
+// If another relay holds the outbox lock nothing is published.
+func (r *OrderRepository) PublishPendingEvents(ctx context.Context, limit int, publish func(ctx context.Context, event OutboxEvent) error) (int, error) {
+	var (
+		published  int
+		publishErr error
+	)
+
+	err := r.runInTx(ctx, func(tx *OrderRepository) error {
+		var locked bool
+		if err := tx.q.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockKey).Scan(&locked); err != nil {
+			return fmt.Errorf("acquire outbox lock: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+
+		events, err := tx.pendingEvents(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if err := publish(ctx, event); err != nil {
+				publishErr = fmt.Errorf("publish event %d: %w", event.ID, err)
+				break
+			}
+
+			if _, err := tx.q.ExecContext(ctx, `
+				UPDATE events SET published_at = NOW() WHERE id = $1
+			`, event.ID); err != nil {
+				return fmt.Errorf("mark event %d published: %w", event.ID, err)
+			}
+			published++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return published, publishErr
+}
+
+func (r *OrderRepository) pendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT id, aggregate_id, event_type, event_data, version, created_at, COALESCE(correlation_id, ''), COALESCE(trace_parent, '')
+		FROM events WHERE published_at IS NULL ORDER BY id LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query pending events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var event OutboxEvent
+		if err := rows.Scan(&event.ID, &event.AggregateID, &event.EventType, &event.Payload, &event.Version, &event.CreatedAt, &event.CorrelationID, &event.TraceParent); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
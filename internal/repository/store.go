@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+// OrderStore is the order persistence used by the order and webhook services,
+// the consumers, the outbox relay and the webhook worker. OrderRepository
+// implements it on Postgres and memstore.Store in memory.
+type OrderStore interface {
+	// RunInTx calls fn with a store whose changes commit together if fn
+	// succeeds. A store that is already transactional joins the outer
+	// transaction.
+	RunInTx(ctx context.Context, fn func(tx OrderStore) error) error
+
+	CreateOrder(ctx context.Context, order *domain.Order, event domain.Event) error
+	GetOrder(ctx context.Context, orderID string) (*domain.Order, error)
+	GetOrderForUpdate(ctx context.Context, orderID string) (*domain.Order, error)
+	UpdateOrderStatus(ctx context.Context, orderID string, expectedVersion int, status domain.OrderStatus, event domain.Event) error
+	ListOrders(ctx context.Context, filter OrderFilter, cursor *OrderCursor, limit int) (*OrderPage, error)
+	CountUserOrdersBetween(ctx context.Context, userID string, from, to time.Time, excludeOrderID string) (int, error)
+	CountOpenOrdersByRestaurant(ctx context.Context) (map[string]int, error)
+
+	ClaimIdempotencyKey(ctx context.Context, key IdempotencyKey, ttl time.Duration) (*domain.Order, error)
+	SaveIdempotentOrder(ctx context.Context, key IdempotencyKey, order *domain.Order) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+
+	ConsumerOffset(ctx context.Context, group, topic string, partition int) (int64, bool, error)
+	SaveConsumerOffset(ctx context.Context, group, topic string, partition int, offset int64) error
+
+	OrderEvents(ctx context.Context, orderID string) ([]domain.RecordedEvent, error)
+	PublishPendingEvents(ctx context.Context, limit int, publish func(ctx context.Context, event OutboxEvent) error) (int, error)
+
+	CreateWebhookSubscription(ctx context.Context, sub *WebhookSubscription) error
+	GetWebhookSubscription(ctx context.Context, id string) (*WebhookSubscription, error)
+	ListWebhookSubscriptions(ctx context.Context, restaurantID string) ([]WebhookSubscription, error)
+	UpdateWebhookSubscription(ctx context.Context, sub *WebhookSubscription) error
+	DeleteWebhookSubscription(ctx context.Context, id string) error
+	EnqueueWebhookDeliveries(ctx context.Context, event WebhookEvent) (int, error)
+	ListWebhookDeliveries(ctx context.Context, subscriptionID string, status WebhookDeliveryStatus, limit int) ([]WebhookDelivery, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingWebhook, error)
+	RecordWebhookAttempt(ctx context.Context, attempt WebhookAttempt, disableAfter int) (disabled bool, err error)
+}
+
+var _ OrderStore = (*OrderRepository)(nil)
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("github.com/dmehra2102/order-management-platform/internal/repository")
+
+// tracedQuerier records a client span for every statement run through q.
+type tracedQuerier struct {
+	q querier
+}
+
+func (t tracedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startStatement(ctx, query)
+	result, err := t.q.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+// QueryContext spans end once the first rows arrive, not when they have
+// been read.
+func (t tracedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startStatement(ctx, query)
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+// QueryRowContext spans carry no error, as *sql.Row reports it on Scan.
+func (t tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startStatement(ctx, query)
+	row := t.q.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+// startStatement starts a span named after the statement's SQL operation,
+// such as "SELECT" or "INSERT".
+func startStatement(ctx context.Context, query string) (context.Context, trace.Span) {
+	query = strings.TrimSpace(query)
+	operation, _, _ := strings.Cut(query, " ")
+	operation = strings.ToUpper(operation)
+
+	return tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(query),
+		),
+	)
+}
@@ -0,0 +1,348 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/lib/pq"
+)
+
+var ErrWebhookNotFound = errors.New("webhook subscription not found")
+
+// WebhookSubscription is a partner endpoint called with the events of
+// EventTypes for the orders of a restaurant. Secret keys the signature of
+// every delivery. A subscription that is not Active receives nothing; it was
+// disabled by the partner, or automatically after ConsecutiveFailures failed
+// attempts, as DisabledReason tells.
+type WebhookSubscription struct {
+	ID                  string
+	RestaurantID        string
+	URL                 string
+	Secret              string
+	EventTypes          []domain.EventType
+	Active              bool
+	ConsecutiveFailures int
+	DisabledAt          *time.Time
+	DisabledReason      string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+// WebhookEvent is an event to deliver to the subscriptions of the restaurant
+// the order belongs to. Payload is the body sent.
+type WebhookEvent struct {
+	ID           string
+	Type         domain.EventType
+	OrderID      string
+	RestaurantID string
+	Payload      []byte
+}
+
+// WebhookDelivery is one event on its way to one subscription, as recorded in
+// the delivery log.
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID string
+	EventID        string
+	EventType      domain.EventType
+	OrderID        string
+	Payload        []byte
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastAttemptAt  *time.Time
+	ResponseStatus int
+	LastError      string
+	CreatedAt      time.Time
+}
+
+// PendingWebhook is a delivery claimed for sending, with the endpoint it goes
+// to.
+type PendingWebhook struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// WebhookAttempt is the outcome of sending a delivery: Status is
+// WebhookDeliveryPending when it is to be retried after RetryIn.
+// ResponseStatus is 0 when the endpoint did not answer.
+type WebhookAttempt struct {
+	DeliveryID     int64
+	Status         WebhookDeliveryStatus
+	RetryIn        time.Duration
+	ResponseStatus int
+	Error          string
+}
+
+const webhookSubscriptionColumns = `id, restaurant_id, url, secret, event_types, active, consecutive_failures,
+	disabled_at, COALESCE(disabled_reason, ''), created_at, updated_at`
+
+const webhookDeliveryColumns = `d.id, d.subscription_id, d.event_id, d.event_type, d.order_id, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_attempt_at, COALESCE(d.response_status, 0), COALESCE(d.last_error, ''), d.created_at`
+
+func (r *OrderRepository) CreateWebhookSubscription(ctx context.Context, sub *WebhookSubscription) error {
+	_, err := r.q.ExecContext(ctx, `
+		INSERT INTO webhook_subscriptions (id, restaurant_id, url, secret, event_types, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, sub.ID, sub.RestaurantID, sub.URL, sub.Secret, eventTypeArray(sub.EventTypes), sub.Active, sub.CreatedAt, sub.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("insert webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookSubscription returns the subscription. Inside a transaction the
+// row stays locked until commit.
+func (r *OrderRepository) GetWebhookSubscription(ctx context.Context, id string) (*WebhookSubscription, error) {
+	sub, err := scanWebhookSubscription(r.q.QueryRowContext(ctx, `
+		SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE id = $1 FOR UPDATE
+	`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrWebhookNotFound, id)
+		}
+		return nil, fmt.Errorf("query webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// ListWebhookSubscriptions returns the restaurant's subscriptions, oldest
+// first.
+func (r *OrderRepository) ListWebhookSubscriptions(ctx context.Context, restaurantID string) ([]WebhookSubscription, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions
+		WHERE restaurant_id = $1 ORDER BY created_at, id
+	`, restaurantID)
+	if err != nil {
+		return nil, fmt.Errorf("query webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []WebhookSubscription
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// UpdateWebhookSubscription saves every field of sub but its restaurant,
+// secret and creation time.
+func (r *OrderRepository) UpdateWebhookSubscription(ctx context.Context, sub *WebhookSubscription) error {
+	res, err := r.q.ExecContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET url = $2, event_types = $3, active = $4, consecutive_failures = $5,
+			disabled_at = $6, disabled_reason = NULLIF($7, ''), updated_at = $8
+		WHERE id = $1
+	`, sub.ID, sub.URL, eventTypeArray(sub.EventTypes), sub.Active, sub.ConsecutiveFailures,
+		sub.DisabledAt, sub.DisabledReason, sub.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("update webhook subscription: %w", err)
+	}
+	return requireRow(res, sub.ID)
+}
+
+// DeleteWebhookSubscription removes the subscription and its delivery log.
+func (r *OrderRepository) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook subscription: %w", err)
+	}
+	return requireRow(res, id)
+}
+
+func requireRow(res sql.Result, id string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrWebhookNotFound, id)
+	}
+	return nil
+}
+
+// EnqueueWebhookDeliveries queues event for every active subscription of its
+// restaurant that filters it in, and returns how many were queued. An event
+// is queued once per subscription however often it is enqueued.
+func (r *OrderRepository) EnqueueWebhookDeliveries(ctx context.Context, event WebhookEvent) (int, error) {
+	res, err := r.q.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, order_id, payload, status, next_attempt_at, created_at)
+		SELECT id, $2, $3, $4, $5::jsonb, 'PENDING', NOW(), NOW() FROM webhook_subscriptions
+		WHERE restaurant_id = $1 AND active AND $3::text = ANY(event_types)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`, event.RestaurantID, event.ID, event.Type, event.OrderID, event.Payload)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue %s webhooks: %w", event.Type, err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// ListWebhookDeliveries returns up to limit deliveries of the subscription,
+// newest first, only those in status unless it is empty.
+func (r *OrderRepository) ListWebhookDeliveries(ctx context.Context, subscriptionID string, status WebhookDeliveryStatus, limit int) ([]WebhookDelivery, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries d
+		WHERE d.subscription_id = $1 AND ($2::text = '' OR d.status = $2)
+		ORDER BY d.id DESC LIMIT $3
+	`, subscriptionID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(deliveryFields(&d)...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries that are due,
+// to active subscriptions, oldest due first. Their next attempt moves lease
+// ahead, so that other workers leave them alone while they are sent and pick
+// them up again if the claimer dies before recording the attempt.
+func (r *OrderRepository) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingWebhook, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT due.id FROM webhook_deliveries due
+			JOIN webhook_subscriptions sub ON sub.id = due.subscription_id
+			WHERE due.status = 'PENDING' AND due.next_attempt_at <= NOW() AND sub.active
+			ORDER BY due.next_attempt_at, due.id
+			LIMIT $1
+			FOR UPDATE OF due SKIP LOCKED
+		)
+		RETURNING `+webhookDeliveryColumns+`, s.url, s.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var claimed []PendingWebhook
+	for rows.Next() {
+		var p PendingWebhook
+		if err := rows.Scan(append(deliveryFields(&p.WebhookDelivery), &p.URL, &p.Secret)...); err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, p)
+	}
+	return claimed, rows.Err()
+}
+
+// RecordWebhookAttempt logs the attempt on its delivery and counts it for or
+// against the subscription. A success resets the subscription's consecutive
+// failures; the failure that makes them reach disableAfter deactivates it,
+// which is reported as disabled.
+func (r *OrderRepository) RecordWebhookAttempt(ctx context.Context, attempt WebhookAttempt, disableAfter int) (disabled bool, err error) {
+	err = r.runInTx(ctx, func(tx *OrderRepository) error {
+		var subscriptionID string
+		if err := tx.q.QueryRowContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $2, attempts = attempts + 1, last_attempt_at = NOW(),
+				next_attempt_at = NOW() + $3 * INTERVAL '1 second',
+				response_status = NULLIF($4, 0), last_error = NULLIF($5, '')
+			WHERE id = $1
+			RETURNING subscription_id
+		`, attempt.DeliveryID, attempt.Status, attempt.RetryIn.Seconds(), attempt.ResponseStatus, attempt.Error).Scan(&subscriptionID); err != nil {
+			return fmt.Errorf("record webhook delivery %d attempt: %w", attempt.DeliveryID, err)
+		}
+
+		if attempt.Status == WebhookDeliveryDelivered {
+			if _, err := tx.q.ExecContext(ctx, `
+				UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = $1
+			`, subscriptionID); err != nil {
+				return fmt.Errorf("reset webhook subscription failures: %w", err)
+			}
+			return nil
+		}
+
+		var (
+			active   bool
+			failures int
+		)
+		if err := tx.q.QueryRowContext(ctx, `
+			UPDATE webhook_subscriptions SET consecutive_failures = consecutive_failures + 1
+			WHERE id = $1
+			RETURNING active, consecutive_failures
+		`, subscriptionID).Scan(&active, &failures); err != nil {
+			return fmt.Errorf("count webhook subscription failure: %w", err)
+		}
+		if !active || failures < disableAfter {
+			return nil
+		}
+
+		if _, err := tx.q.ExecContext(ctx, `
+			UPDATE webhook_subscriptions
+			SET active = FALSE, disabled_at = NOW(), disabled_reason = $2, updated_at = NOW()
+			WHERE id = $1
+		`, subscriptionID, WebhookDisabledReason(failures, attempt.Error)); err != nil {
+			return fmt.Errorf("disable webhook subscription: %w", err)
+		}
+		disabled = true
+		return nil
+	})
+	return disabled, err
+}
+
+// WebhookDisabledReason explains why a subscription was disabled after
+// failures attempts in a row, the last failing with lastError.
+func WebhookDisabledReason(failures int, lastError string) string {
+	return fmt.Sprintf("disabled after %d consecutive failed attempts, the last: %s", failures, lastError)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhookSubscription(row rowScanner) (*WebhookSubscription, error) {
+	var (
+		sub        WebhookSubscription
+		eventTypes pq.StringArray
+	)
+	if err := row.Scan(&sub.ID, &sub.RestaurantID, &sub.URL, &sub.Secret, &eventTypes, &sub.Active, &sub.ConsecutiveFailures,
+		&sub.DisabledAt, &sub.DisabledReason, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		return nil, err
+	}
+	for _, t := range eventTypes {
+		sub.EventTypes = append(sub.EventTypes, domain.EventType(t))
+	}
+	return &sub, nil
+}
+
+func deliveryFields(d *WebhookDelivery) []any {
+	return []any{&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.OrderID, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastAttemptAt, &d.ResponseStatus, &d.LastError, &d.CreatedAt}
+}
+
+func eventTypeArray(types []domain.EventType) pq.StringArray {
+	a := make(pq.StringArray, len(types))
+	for i, t := range types {
+		a[i] = string(t)
+	}
+	return a
+}
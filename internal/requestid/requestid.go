@@ -0,0 +1,48 @@
+// Package requestid carries the ID of the request that started a piece of
+// work through contexts, so that logs written for it in any service can be
+// tied together.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type contextKey struct{}
+
+// MaxLength bounds IDs accepted from callers.
+const MaxLength = 128
+
+// New returns a fresh request ID.
+func New() string {
+	return uuid.New().String()
+}
+
+// Valid reports whether id can be used as a request ID: 1 to MaxLength
+// printable ASCII characters without spaces.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns ctx carrying id. An empty id leaves ctx unchanged.
+func NewContext(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or "" if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
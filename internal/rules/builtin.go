@@ -0,0 +1,130 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+// Bounds is the accepted range of an order total in one currency.
+type Bounds struct {
+	Min domain.Money
+	Max domain.Money
+}
+
+// AmountBounds rejects totals outside the bounds configured for their
+// currency, and orders in currencies without bounds.
+type AmountBounds struct {
+	Bounds map[string]Bounds
+}
+
+func (r AmountBounds) Name() string { return "amount_bounds" }
+
+func (r AmountBounds) Evaluate(_ context.Context, _ UserOrderCounter, order domain.OrderCreatedEvent) (*Violation, error) {
+	total := order.TotalAmount
+	bounds, ok := r.Bounds[total.Currency]
+	if !ok {
+		return &Violation{
+			Code:    CodeCurrencyNotSupported,
+			Message: fmt.Sprintf("currency %q is not accepted", total.Currency),
+		}, nil
+	}
+
+	if cmp, err := total.Cmp(bounds.Min); err != nil {
+		return nil, err
+	} else if cmp < 0 {
+		return &Violation{
+			Code:    CodeAmountBelowMinimum,
+			Message: fmt.Sprintf("total %s %s is below the minimum of %s", total, total.Currency, bounds.Min),
+		}, nil
+	}
+
+	if cmp, err := total.Cmp(bounds.Max); err != nil {
+		return nil, err
+	} else if cmp > 0 {
+		return &Violation{
+			Code:    CodeAmountAboveMaximum,
+			Message: fmt.Sprintf("total %s %s is above the maximum of %s", total, total.Currency, bounds.Max),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// MaxItemQuantity rejects orders with any line above Max units.
+type MaxItemQuantity struct {
+	Max int
+}
+
+func (r MaxItemQuantity) Name() string { return "max_item_quantity" }
+
+func (r MaxItemQuantity) Evaluate(_ context.Context, _ UserOrderCounter, order domain.OrderCreatedEvent) (*Violation, error) {
+	for _, item := range order.Items {
+		if item.Quantity > r.Max {
+			return &Violation{
+				Code:    CodeItemQuantityExceeded,
+				Message: fmt.Sprintf("item %s has quantity %d, the maximum is %d", item.ItemID, item.Quantity, r.Max),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// RestaurantBlocklist rejects orders placed with blocked restaurants.
+type RestaurantBlocklist struct {
+	Blocked map[string]bool
+}
+
+func NewRestaurantBlocklist(restaurantIDs []string) RestaurantBlocklist {
+	blocked := make(map[string]bool, len(restaurantIDs))
+	for _, id := range restaurantIDs {
+		blocked[id] = true
+	}
+	return RestaurantBlocklist{Blocked: blocked}
+}
+
+func (r RestaurantBlocklist) Name() string { return "restaurant_blocklist" }
+
+func (r RestaurantBlocklist) Evaluate(_ context.Context, _ UserOrderCounter, order domain.OrderCreatedEvent) (*Violation, error) {
+	if r.Blocked[order.RestaurantID] {
+		return &Violation{
+			Code:    CodeRestaurantBlocked,
+			Message: fmt.Sprintf("restaurant %s is not accepting orders", order.RestaurantID),
+		}, nil
+	}
+	return nil, nil
+}
+
+// UserOrderCounter counts the orders a user created in a span of time,
+// leaving out failed and cancelled orders and the order being validated.
+type UserOrderCounter interface {
+	CountUserOrdersBetween(ctx context.Context, userID string, from, to time.Time, excludeOrderID string) (int, error)
+}
+
+// UserDailyCap rejects an order once the user already placed Max orders
+// earlier on the same UTC day. Orders placed after it do not count, so the
+// outcome does not depend on when the order is validated.
+type UserDailyCap struct {
+	Max int
+}
+
+func (r UserDailyCap) Name() string { return "user_daily_cap" }
+
+func (r UserDailyCap) Evaluate(ctx context.Context, orders UserOrderCounter, order domain.OrderCreatedEvent) (*Violation, error) {
+	dayStart := order.CreatedAt.UTC().Truncate(24 * time.Hour)
+
+	count, err := orders.CountUserOrdersBetween(ctx, order.UserID, dayStart, order.CreatedAt, order.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if count >= r.Max {
+		return &Violation{
+			Code:    CodeUserDailyCapExceeded,
+			Message: fmt.Sprintf("user %s already placed %d orders today, the maximum is %d", order.UserID, count, r.Max),
+		}, nil
+	}
+	return nil, nil
+}
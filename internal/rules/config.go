@@ -0,0 +1,100 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+// Config is the JSON rules file, also accepted inline in the YAML service
+// configuration. A rule is enabled by setting its key; zero values leave it
+// out.
+//
+//	{
+//	  "amount_bounds": {"INR": {"min": "100.00", "max": "50000.00"}},
+//	  "max_item_quantity": 50,
+//	  "restaurant_blocklist": ["rest-42"],
+//	  "user_daily_order_cap": 20
+//	}
+type Config struct {
+	AmountBounds        map[string]BoundsConfig `json:"amount_bounds" yaml:"amount_bounds"`
+	MaxItemQuantity     int                     `json:"max_item_quantity" yaml:"max_item_quantity"`
+	RestaurantBlocklist []string                `json:"restaurant_blocklist" yaml:"restaurant_blocklist"`
+	UserDailyOrderCap   int                     `json:"user_daily_order_cap" yaml:"user_daily_order_cap"`
+}
+
+type BoundsConfig struct {
+	Min string `json:"min" yaml:"min"`
+	Max string `json:"max" yaml:"max"`
+}
+
+// DefaultConfig keeps the amount limits the processor has always applied.
+func DefaultConfig() Config {
+	return Config{
+		AmountBounds: map[string]BoundsConfig{
+			domain.DefaultCurrency: {Min: "100.00", Max: "50000.00"},
+		},
+	}
+}
+
+// LoadConfig reads a rules file, or returns DefaultConfig when path is empty.
+func LoadConfig(path string) (Config, error) {
+	if path == "" {
+		return DefaultConfig(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read rules file: %w", err)
+	}
+
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("parse rules file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Build creates an Engine from the enabled rules.
+func (c Config) Build() (*Engine, error) {
+	var rules []OrderRule
+
+	if len(c.AmountBounds) > 0 {
+		bounds := make(map[string]Bounds, len(c.AmountBounds))
+		for currency, b := range c.AmountBounds {
+			lower, err := domain.ParseMoney(b.Min, currency)
+			if err != nil {
+				return nil, fmt.Errorf("amount_bounds %s min: %w", currency, err)
+			}
+			upper, err := domain.ParseMoney(b.Max, currency)
+			if err != nil {
+				return nil, fmt.Errorf("amount_bounds %s max: %w", currency, err)
+			}
+			if cmp, _ := lower.Cmp(upper); cmp > 0 {
+				return nil, fmt.Errorf("amount_bounds %s: min %s is above max %s", currency, lower, upper)
+			}
+			bounds[currency] = Bounds{Min: lower, Max: upper}
+		}
+		rules = append(rules, AmountBounds{Bounds: bounds})
+	}
+
+	if c.MaxItemQuantity > 0 {
+		rules = append(rules, MaxItemQuantity{Max: c.MaxItemQuantity})
+	}
+
+	if len(c.RestaurantBlocklist) > 0 {
+		rules = append(rules, NewRestaurantBlocklist(c.RestaurantBlocklist))
+	}
+
+	if c.UserDailyOrderCap > 0 {
+		rules = append(rules, UserDailyCap{Max: c.UserDailyOrderCap})
+	}
+
+	return NewEngine(rules...), nil
+}
@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+)
+
+// Violation codes reported in OrderFailedEvent.Reason
+const (
+	CodeCurrencyNotSupported = "CURRENCY_NOT_SUPPORTED"
+	CodeAmountBelowMinimum   = "AMOUNT_BELOW_MINIMUM"
+	CodeAmountAboveMaximum   = "AMOUNT_ABOVE_MAXIMUM"
+	CodeItemQuantityExceeded = "ITEM_QUANTITY_EXCEEDED"
+	CodeRestaurantBlocked    = "RESTAURANT_BLOCKED"
+	CodeUserDailyCapExceeded = "USER_DAILY_CAP_EXCEEDED"
+)
+
+// Violation describes why an order failed a rule. Code is stable and meant for
+// machines; Message is for people.
+type Violation struct {
+	Rule    string
+	Code    string
+	Message string
+}
+
+// OrderRule checks one aspect of a newly created order. Rules that look at
+// other orders count them with orders, which should be the transaction the
+// order is validated in. Evaluate returns nil when the order passes, and an
+// error only when the rule could not be checked.
+type OrderRule interface {
+	Name() string
+	Evaluate(ctx context.Context, orders UserOrderCounter, order domain.OrderCreatedEvent) (*Violation, error)
+}
+
+// Engine runs every configured rule against an order.
+type Engine struct {
+	rules []OrderRule
+}
+
+func NewEngine(rules ...OrderRule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Evaluate returns the violations of all failing rules, in rule order.
+func (e *Engine) Evaluate(ctx context.Context, orders UserOrderCounter, order domain.OrderCreatedEvent) ([]Violation, error) {
+	var violations []Violation
+	for _, rule := range e.rules {
+		violation, err := rule.Evaluate(ctx, orders, order)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name(), err)
+		}
+		if violation != nil {
+			violation.Rule = rule.Name()
+			violations = append(violations, *violation)
+		}
+	}
+	return violations, nil
+}
+
+// Reason joins the codes of violations into the comma separated form used by
+// OrderFailedEvent.Reason, e.g. "AMOUNT_BELOW_MINIMUM,RESTAURANT_BLOCKED".
+func Reason(violations []Violation) string {
+	codes := make([]string, len(violations))
+	for i, v := range violations {
+		codes[i] = v.Code
+	}
+	return strings.Join(codes, ",")
+}
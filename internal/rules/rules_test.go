@@ -0,0 +1,157 @@
+package rules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/repository/memstore"
+)
+
+func newOrder(t *testing.T, userID, restaurantID string, price int64, quantity int, createdAt time.Time) *domain.Order {
+	t.Helper()
+	order, err := domain.NewOrder(userID, restaurantID, []domain.OrderItem{
+		{ID: "line-1", ItemID: "item-1", Name: "Thali", Price: domain.NewMoney(price, "INR"), Quantity: quantity},
+	})
+	if err != nil {
+		t.Fatalf("NewOrder: %v", err)
+	}
+	order.CreatedAt = createdAt
+	return order
+}
+
+func TestEngineReportsEveryViolationInRuleOrder(t *testing.T) {
+	engine, err := Config{
+		AmountBounds:        map[string]BoundsConfig{"INR": {Min: "100.00", Max: "500.00"}},
+		MaxItemQuantity:     5,
+		RestaurantBlocklist: []string{"rest-42"},
+	}.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	order := newOrder(t, "user-1", "rest-42", 15000, 10, time.Now())
+	violations, err := engine.Evaluate(context.Background(), memstore.New(), domain.NewOrderCreatedEvent(order))
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if got, want := Reason(violations), "AMOUNT_ABOVE_MAXIMUM,ITEM_QUANTITY_EXCEEDED,RESTAURANT_BLOCKED"; got != want {
+		t.Errorf("reason = %q, want %q", got, want)
+	}
+	if violations[0].Rule != "amount_bounds" {
+		t.Errorf("first violation names rule %q, want amount_bounds", violations[0].Rule)
+	}
+
+	order = newOrder(t, "user-1", "rest-1", 15000, 2, time.Now())
+	if violations, err := engine.Evaluate(context.Background(), memstore.New(), domain.NewOrderCreatedEvent(order)); err != nil || len(violations) != 0 {
+		t.Errorf("Evaluate = %v, %v; want a passing order", violations, err)
+	}
+}
+
+func TestAmountBoundsRejectsUnboundedCurrency(t *testing.T) {
+	rule := AmountBounds{Bounds: map[string]Bounds{}}
+	order := newOrder(t, "user-1", "rest-1", 15000, 1, time.Now())
+
+	violation, err := rule.Evaluate(context.Background(), nil, domain.NewOrderCreatedEvent(order))
+	if err != nil || violation == nil || violation.Code != CodeCurrencyNotSupported {
+		t.Errorf("Evaluate = %+v, %v; want %s", violation, err, CodeCurrencyNotSupported)
+	}
+}
+
+func TestBuildRejectsInvalidBounds(t *testing.T) {
+	for name, bounds := range map[string]map[string]BoundsConfig{
+		"min above max":         {"INR": {Min: "500.00", Max: "100.00"}},
+		"malformed amount":      {"INR": {Min: "cheap", Max: "100.00"}},
+		"unsupported currency":  {"JPY": {Min: "100", Max: "5000"}},
+		"currency without code": {"": {Min: "1.00", Max: "2.00"}},
+	} {
+		if _, err := (Config{AmountBounds: bounds}).Build(); err == nil {
+			t.Errorf("%s: Build succeeded, want an error", name)
+		}
+	}
+}
+
+func TestUserDailyCapCountsEarlierOrdersOfTheDay(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	day := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	create := func(userID string, at time.Time) *domain.Order {
+		order := newOrder(t, userID, "rest-1", 15000, 1, at)
+		if err := store.CreateOrder(ctx, order, domain.NewOrderCreatedEvent(order)); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+		return order
+	}
+	create("user-1", day.Add(-time.Hour))
+	create("user-1", day.Add(9*time.Hour))
+	create("user-2", day.Add(10*time.Hour))
+	failed := create("user-1", day.Add(11*time.Hour))
+	if err := store.UpdateOrderStatus(ctx, failed.ID, failed.Version, domain.OrderStatusFailed, domain.NewOrderFailedEvent(failed.ID, "test")); err != nil {
+		t.Fatalf("fail order: %v", err)
+	}
+	validated := create("user-1", day.Add(12*time.Hour))
+	create("user-1", day.Add(13*time.Hour))
+
+	engine, err := Config{UserDailyOrderCap: 2}.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	// The consumer validates inside its transaction and counts through it
+	evaluate := func() []Violation {
+		var violations []Violation
+		err := store.RunInTx(ctx, func(tx repository.OrderStore) error {
+			var err error
+			violations, err = engine.Evaluate(ctx, tx, domain.NewOrderCreatedEvent(validated))
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Evaluate: %v", err)
+		}
+		return violations
+	}
+
+	// Only the 09:00 order counts: yesterday's, other users', failed and later
+	// orders do not
+	if violations := evaluate(); len(violations) != 0 {
+		t.Fatalf("violations = %+v, want none with one earlier order", violations)
+	}
+
+	create("user-1", day.Add(8*time.Hour))
+	violations := evaluate()
+	if len(violations) != 1 || violations[0].Code != CodeUserDailyCapExceeded {
+		t.Errorf("violations = %+v, want %s with two earlier orders", violations, CodeUserDailyCapExceeded)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write rules: %v", err)
+		}
+		return path
+	}
+
+	cfg, err := LoadConfig(write(`{"max_item_quantity": 50, "user_daily_order_cap": 20}`))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.MaxItemQuantity != 50 || cfg.UserDailyOrderCap != 20 || cfg.AmountBounds != nil {
+		t.Errorf("config = %+v, want only the rules in the file", cfg)
+	}
+
+	if _, err := LoadConfig(write(`{"user_daily_cap": 20}`)); err == nil || !strings.Contains(err.Error(), "user_daily_cap") {
+		t.Errorf("LoadConfig = %v, want an error naming the unknown key", err)
+	}
+
+	if cfg, err := LoadConfig(""); err != nil || len(cfg.AmountBounds) != 1 {
+		t.Errorf("LoadConfig(\"\") = %+v, %v; want DefaultConfig", cfg, err)
+	}
+}
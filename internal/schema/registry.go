@@ -0,0 +1,226 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Registered is a schema as the registry stores it. IDs are unique across
+// subjects; versions count the schemas of one subject.
+type Registered struct {
+	ID      uint32 `json:"id"`
+	Version int    `json:"version"`
+	Schema
+}
+
+// Registry assigns IDs to schemas. A registry opened on a file shares it with
+// every process opening the same file: schemas are read from it again before
+// each registration and for IDs not seen yet.
+type Registry struct {
+	path string
+
+	mu      sync.Mutex
+	schemas []Registered
+}
+
+type registryFile struct {
+	Schemas []Registered `json:"schemas"`
+}
+
+// NewRegistry returns a registry that keeps its schemas in memory.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// OpenRegistry returns a registry kept in the JSON file at path. The file is
+// created by the first registration.
+func OpenRegistry(path string) (*Registry, error) {
+	r := &Registry{path: path}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register returns the ID of s, registering it as the next version of its
+// subject if it differs from the latest one. Schemas that break readers of
+// the latest version are rejected with an error matching ErrIncompatible.
+func (r *Registry) Register(s Schema) (uint32, error) {
+	if err := s.Validate(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.path != "" {
+		unlock, err := lockFile(r.path + ".lock")
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+
+		if err := r.load(); err != nil {
+			return 0, err
+		}
+	}
+
+	var latest *Registered
+	for i := range r.schemas {
+		if r.schemas[i].Subject == s.Subject {
+			latest = &r.schemas[i]
+		}
+	}
+
+	next := Registered{ID: uint32(len(r.schemas) + 1), Version: 1, Schema: s}
+	if latest != nil {
+		if sameSchema(latest.Schema, s) {
+			return latest.ID, nil
+		}
+		if err := CheckCompatible(latest.Schema, s); err != nil {
+			return 0, fmt.Errorf("register %s version %d: %w", s.Subject, latest.Version+1, err)
+		}
+		next.Version = latest.Version + 1
+	}
+
+	r.schemas = append(r.schemas, next)
+	if err := r.save(); err != nil {
+		r.schemas = r.schemas[:len(r.schemas)-1]
+		return 0, err
+	}
+	return next.ID, nil
+}
+
+// Lookup returns the schema registered under id.
+func (r *Registry) Lookup(id uint32) (Registered, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.find(id); ok {
+		return s, nil
+	}
+	if r.path != "" {
+		if err := r.load(); err != nil {
+			return Registered{}, err
+		}
+		if s, ok := r.find(id); ok {
+			return s, nil
+		}
+	}
+	return Registered{}, fmt.Errorf("%w %d", ErrUnknownSchema, id)
+}
+
+func (r *Registry) find(id uint32) (Registered, bool) {
+	if id == 0 || int(id) > len(r.schemas) {
+		return Registered{}, false
+	}
+	return r.schemas[id-1], true
+}
+
+func (r *Registry) load() error {
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read schema registry: %w", err)
+	}
+
+	var f registryFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("parse schema registry %s: %w", r.path, err)
+	}
+	for i, s := range f.Schemas {
+		if s.ID != uint32(i+1) {
+			return fmt.Errorf("parse schema registry %s: schema %d has ID %d", r.path, i+1, s.ID)
+		}
+	}
+	r.schemas = f.Schemas
+	return nil
+}
+
+func (r *Registry) save() error {
+	if r.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(registryFile{Schemas: r.schemas}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return fmt.Errorf("write schema registry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write schema registry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write schema registry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("write schema registry: %w", err)
+	}
+	return nil
+}
+
+// sameSchema compares schemas as they are stored, so that an empty list of
+// reserved numbers equals a missing one.
+func sameSchema(a, b Schema) bool {
+	var ca, cb Schema
+	if err := roundTrip(a, &ca); err != nil {
+		return false
+	}
+	if err := roundTrip(b, &cb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(ca, cb)
+}
+
+func roundTrip(in Schema, out *Schema) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Locks older than staleLock were left by a process that died holding them.
+const (
+	lockTimeout = 5 * time.Second
+	staleLock   = 30 * time.Second
+)
+
+// lockFile takes the lock file at path, waiting for other processes to release
+// it, and returns the function releasing it.
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("lock schema registry: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("lock schema registry: %s held for over %s", path, lockTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func orderSchema(fields ...Field) Schema {
+	return Schema{Subject: "test.Order", Messages: []Message{{Name: "test.Order", Fields: fields}}}
+}
+
+var (
+	orderID = Field{Number: 1, Name: "order_id", Type: "string"}
+	amount  = Field{Number: 2, Name: "amount", Type: "int64"}
+	note    = Field{Number: 3, Name: "note", Type: "string"}
+)
+
+func TestRegistryVersionsCompatibleSchemas(t *testing.T) {
+	r := NewRegistry()
+
+	v1, err := r.Register(orderSchema(orderID, amount))
+	if err != nil {
+		t.Fatalf("Register v1: %v", err)
+	}
+	if again, err := r.Register(orderSchema(orderID, amount)); err != nil || again != v1 {
+		t.Errorf("Register same schema = %d, %v; want ID %d", again, err, v1)
+	}
+
+	v2, err := r.Register(orderSchema(orderID, amount, note))
+	if err != nil {
+		t.Fatalf("Register with an added field: %v", err)
+	}
+	registered, err := r.Lookup(v2)
+	if err != nil || v2 == v1 || registered.Version != 2 {
+		t.Errorf("Lookup(%d) = %+v, %v; want version 2 under a new ID", v2, registered, err)
+	}
+
+	if _, err := r.Lookup(99); !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("Lookup unknown ID = %v, want ErrUnknownSchema", err)
+	}
+}
+
+func TestRegistryRejectsBreakingChanges(t *testing.T) {
+	base := orderSchema(orderID, amount)
+	withReserved := func(reserved []int, fields ...Field) Schema {
+		s := orderSchema(fields...)
+		s.Messages[0].Reserved = reserved
+		return s
+	}
+
+	for name, c := range map[string]struct{ base, next Schema }{
+		"type changed":    {base, orderSchema(orderID, Field{Number: 2, Name: "amount", Type: "string"})},
+		"number reused":   {base, orderSchema(orderID, Field{Number: 2, Name: "total", Type: "int64"})},
+		"field removed":   {base, orderSchema(orderID)},
+		"made repeated":   {base, orderSchema(orderID, Field{Number: 2, Name: "amount", Type: "int64", Repeated: true})},
+		"reserved reused": {withReserved([]int{2, 4}, orderID), withReserved([]int{2}, orderID, Field{Number: 4, Name: "extra", Type: "string"})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := NewRegistry()
+			if _, err := r.Register(c.base); err != nil {
+				t.Fatalf("Register: %v", err)
+			}
+			if _, err := r.Register(c.next); !errors.Is(err, ErrIncompatible) {
+				t.Errorf("Register = %v, want ErrIncompatible", err)
+			}
+		})
+	}
+
+	// Removing a field is fine once its number is reserved
+	r := NewRegistry()
+	if _, err := r.Register(orderSchema(orderID, amount)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := r.Register(withReserved([]int{2}, orderID)); err != nil {
+		t.Errorf("Register with field 2 reserved: %v", err)
+	}
+}
+
+func TestFileRegistryIsSharedThroughTheFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	writer, err := OpenRegistry(path)
+	if err != nil {
+		t.Fatalf("OpenRegistry: %v", err)
+	}
+	reader, err := OpenRegistry(path)
+	if err != nil {
+		t.Fatalf("OpenRegistry: %v", err)
+	}
+
+	id, err := writer.Register(orderSchema(orderID, amount))
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	registered, err := reader.Lookup(id)
+	if err != nil || registered.Subject != "test.Order" {
+		t.Fatalf("Lookup from another registry = %+v, %v", registered, err)
+	}
+
+	// Registering through the reader sees the writer's version first
+	if _, err := reader.Register(orderSchema(orderID)); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Register = %v, want ErrIncompatible", err)
+	}
+
+	reopened, err := OpenRegistry(path)
+	if err != nil {
+		t.Fatalf("OpenRegistry: %v", err)
+	}
+	if again, err := reopened.Register(orderSchema(orderID, amount)); err != nil || again != id {
+		t.Errorf("Register after reopening = %d, %v; want %d", again, err, id)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	framed := Frame(258, []byte("payload"))
+	if framed[0] != 0 || len(framed) != 5+len("payload") {
+		t.Fatalf("Frame = %x", framed)
+	}
+
+	id, payload, err := Unframe(framed)
+	if err != nil || id != 258 || string(payload) != "payload" {
+		t.Errorf("Unframe = %d, %q, %v", id, payload, err)
+	}
+	if _, _, err := Unframe([]byte(`{"event_id":"e-1"}`)); !errors.Is(err, ErrNotFramed) {
+		t.Errorf("Unframe JSON = %v, want ErrNotFramed", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Package schema is a local schema registry for protobuf payloads. Each
+// registered schema gets an ID, which is written in front of the payload
+// after a magic byte, and a new schema for a subject is only accepted when
+// readers of the earlier one can still read it.
+package schema
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrIncompatible  = errors.New("incompatible schema")
+	ErrUnknownSchema = errors.New("unknown schema")
+	ErrNotFramed     = errors.New("payload has no schema prefix")
+)
+
+// Schema describes a protobuf message, named by Subject, together with the
+// messages its fields use.
+type Schema struct {
+	Subject  string    `json:"subject"`
+	Messages []Message `json:"messages"`
+}
+
+type Message struct {
+	Name   string  `json:"name"`
+	Fields []Field `json:"fields"`
+	// Numbers of removed fields, which must not be used again
+	Reserved []int `json:"reserved,omitempty"`
+}
+
+// Field is a field of a message. Type is a scalar type such as "string" or
+// "int64", or the name of a message.
+type Field struct {
+	Number   int    `json:"number"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Repeated bool   `json:"repeated,omitempty"`
+}
+
+func (s Schema) message(name string) (Message, bool) {
+	for _, m := range s.Messages {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Message{}, false
+}
+
+func (m Message) field(number int) (Field, bool) {
+	for _, f := range m.Fields {
+		if f.Number == number {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Validate checks that s describes its subject and that no message uses a
+// field number twice.
+func (s Schema) Validate() error {
+	if _, ok := s.message(s.Subject); !ok {
+		return fmt.Errorf("schema %s does not describe message %s", s.Subject, s.Subject)
+	}
+	for _, m := range s.Messages {
+		seen := make(map[int]bool)
+		for _, f := range m.Fields {
+			if f.Number < 1 || seen[f.Number] || slices.Contains(m.Reserved, f.Number) {
+				return fmt.Errorf("message %s: field %s has a duplicate or invalid number %d", m.Name, f.Name, f.Number)
+			}
+			seen[f.Number] = true
+		}
+	}
+	return nil
+}
+
+// CheckCompatible reports how next breaks readers of prev, if it does. Fields
+// may be added; a field number keeps its name and type for good, and the
+// numbers of removed fields must be reserved.
+func CheckCompatible(prev, next Schema) error {
+	for _, old := range prev.Messages {
+		m, ok := next.message(old.Name)
+		if !ok {
+			continue
+		}
+		for _, f := range old.Fields {
+			nf, ok := m.field(f.Number)
+			switch {
+			case !ok && !slices.Contains(m.Reserved, f.Number):
+				return fmt.Errorf("%w: %s.%s (%d) removed without reserving its number", ErrIncompatible, old.Name, f.Name, f.Number)
+			case ok && (nf.Name != f.Name || nf.Type != f.Type || nf.Repeated != f.Repeated):
+				return fmt.Errorf("%w: %s field %d changed from %s to %s", ErrIncompatible, old.Name, f.Number, f, nf)
+			}
+		}
+		for _, n := range old.Reserved {
+			if nf, ok := m.field(n); ok {
+				return fmt.Errorf("%w: %s.%s uses reserved number %d", ErrIncompatible, old.Name, nf.Name, n)
+			}
+		}
+	}
+	return nil
+}
+
+func (f Field) String() string {
+	if f.Repeated {
+		return fmt.Sprintf("repeated %s %s", f.Type, f.Name)
+	}
+	return f.Type + " " + f.Name
+}
+
+// magicByte starts every framed payload, as in the Confluent wire format.
+const magicByte = 0
+
+const prefixLen = 5
+
+// Frame prefixes payload with the magic byte and the big-endian schema ID.
+func Frame(id uint32, payload []byte) []byte {
+	out := make([]byte, prefixLen, prefixLen+len(payload))
+	out[0] = magicByte
+	binary.BigEndian.PutUint32(out[1:], id)
+	return append(out, payload...)
+}
+
+// Unframe splits a framed payload into its schema ID and the payload itself.
+func Unframe(data []byte) (uint32, []byte, error) {
+	if len(data) < prefixLen || data[0] != magicByte {
+		return 0, nil, ErrNotFramed
+	}
+	return binary.BigEndian.Uint32(data[1:prefixLen]), data[prefixLen:], nil
+}
@@ -2,30 +2,110 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/dmehra2102/order-management-platform/internal/domain"
-	"github.com/dmehra2102/order-management-platform/internal/kafka"
 	"github.com/dmehra2102/order-management-platform/internal/logger"
 	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+var tracer = tracing.Tracer("github.com/dmehra2102/order-management-platform/internal/service")
+
 type OrderService struct {
-	repo     *repository.OrderRepository
-	producer *kafka.Producer
-	logger   *logger.Logger
+	repo   repository.OrderStore
+	logger *logger.Logger
 }
 
-func NewOrderService(repo *repository.OrderRepository, producer *kafka.Producer, l *logger.Logger) *OrderService {
+func NewOrderService(repo repository.OrderStore, l *logger.Logger) *OrderService {
 	return &OrderService{
-		repo:     repo,
-		producer: producer,
-		logger:   l,
+		repo:   repo,
+		logger: l,
 	}
 }
 
-func (s *OrderService) CreateOrder(ctx context.Context, userID, restaurantID string, items []domain.OrderItem) (*domain.Order, error) {
+func (s *OrderService) CreateOrder(ctx context.Context, userID, restaurantID string, items []domain.OrderItem) (order *domain.Order, err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.CreateOrder")
+	defer func() { tracing.End(span, err) }()
+
+	order, err = s.newOrder(userID, restaurantID, items)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("order.id", order.ID))
+
+	// The event is stored with the order and published by the outbox relay
+	event := domain.NewOrderCreatedEvent(order)
+	if err := s.repo.CreateOrder(ctx, order, event); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save order to database", map[string]any{
+			"error":    err,
+			"order_id": order.ID,
+		})
+
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// CreateOrderOnce creates an order at most once per idempotency key. A retry
+// with the same key and request gets the order as it was first created and
+// replayed set; a retry with a different request fails with
+// repository.ErrIdempotencyKeyReused. Keys are forgotten after ttl.
+func (s *OrderService) CreateOrderOnce(ctx context.Context, key repository.IdempotencyKey, ttl time.Duration, userID, restaurantID string, items []domain.OrderItem) (order *domain.Order, replayed bool, err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.CreateOrderOnce")
+	defer func() { tracing.End(span, err) }()
+
+	err = s.repo.RunInTx(ctx, func(tx repository.OrderStore) error {
+		stored, err := tx.ClaimIdempotencyKey(ctx, key, ttl)
+		if err != nil {
+			return err
+		}
+		if stored != nil {
+			order, replayed = stored, true
+			return nil
+		}
+
+		if order, err = s.newOrder(userID, restaurantID, items); err != nil {
+			return err
+		}
+
+		if err := tx.CreateOrder(ctx, order, domain.NewOrderCreatedEvent(order)); err != nil {
+			return err
+		}
+		return tx.SaveIdempotentOrder(ctx, key, order)
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrIdempotencyKeyReused) {
+			s.logger.WithContext(ctx).Error("Failed to create order", map[string]any{
+				"error":           err,
+				"idempotency_key": key.Key,
+			})
+		}
+		return nil, false, err
+	}
+
+	span.SetAttributes(attribute.String("order.id", order.ID), attribute.Bool("idempotency.replayed", replayed))
+	if replayed {
+		s.logger.WithContext(ctx).Info("Replayed idempotent order creation", map[string]any{
+			"order_id":        order.ID,
+			"idempotency_key": key.Key,
+		})
+	}
+
+	return order, replayed, nil
+}
+
+// PurgeIdempotencyKeys deletes idempotency keys whose TTL has passed.
+func (s *OrderService) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpiredIdempotencyKeys(ctx)
+}
+
+func (s *OrderService) newOrder(userID, restaurantID string, items []domain.OrderItem) (*domain.Order, error) {
 	if userID == "" || restaurantID == "" {
 		return nil, fmt.Errorf("invalid user_id or restaurant_id")
 	}
@@ -40,7 +120,6 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID, restaurantID str
 		}
 	}
 
-	// Create Order and save in DB
 	order, err := domain.NewOrder(userID, restaurantID, items)
 	if err != nil {
 		s.logger.Error("Failed to create order", map[string]any{
@@ -48,48 +127,204 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID, restaurantID str
 		})
 		return nil, err
 	}
+	return order, nil
+}
 
-	if err := s.repo.CreateOrder(ctx, order); err != nil {
-		s.logger.Error("Failed to save order to database", map[string]any{
+func (s *OrderService) GetOrder(ctx context.Context, orderID string) (*domain.Order, error) {
+	order, err := s.repo.GetOrder(ctx, orderID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get order", map[string]any{
 			"error":    err,
-			"order_id": order.ID,
+			"order_id": orderID,
 		})
-
 		return nil, err
 	}
+	return order, nil
+}
 
-	// Publish event
-	event := domain.NewOrderCreatedEvent(order)
-	if err := s.producer.PublishOrderCreated(ctx, event); err != nil {
-		s.logger.Error("Failed to publish order creation event", map[string]any{
+// CancelOrder cancels the order on behalf of actor, provided it is still at
+// expectedVersion (0 for any), and records an OrderCancelledEvent for the
+// outbox relay.
+func (s *OrderService) CancelOrder(ctx context.Context, orderID string, expectedVersion int, actor domain.Actor, reason string) (*domain.Order, error) {
+	return s.cancelOrder(ctx, orderID, expectedVersion, actor, reason, nil)
+}
+
+// cancelOrder cancels the order once check, if set, accepts it.
+func (s *OrderService) cancelOrder(ctx context.Context, orderID string, expectedVersion int, actor domain.Actor, reason string, check func(*domain.Order) error) (*domain.Order, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("cancellation reason is required")
+	}
+
+	var order *domain.Order
+	err := s.repo.RunInTx(ctx, func(tx repository.OrderStore) error {
+		var err error
+		order, err = loadForUpdate(ctx, tx, orderID, expectedVersion)
+		if err != nil {
+			return err
+		}
+
+		if check != nil {
+			if err := check(order); err != nil {
+				return err
+			}
+		}
+
+		readVersion := order.Version
+		if err := order.Cancel(actor); err != nil {
+			return err
+		}
+
+		event := domain.NewOrderCancelledEvent(order.ID, actor, reason)
+		return tx.UpdateOrderStatus(ctx, order.ID, readVersion, order.Status, event)
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to cancel order", map[string]any{
 			"error":    err,
-			"order_id": order.ID,
+			"order_id": orderID,
+			"actor_id": actor.ID,
+			"role":     actor.Role,
 		})
+		return nil, err
 	}
 
+	s.logger.WithContext(ctx).Info("Order cancelled", map[string]any{
+		"order_id": order.ID,
+		"actor_id": actor.ID,
+		"role":     actor.Role,
+		"reason":   reason,
+	})
+
 	return order, nil
 }
 
-func (s *OrderService) GetOrder(ctx context.Context, orderID string) (*domain.Order, error) {
-	order, err := s.repo.GetOrder(ctx, orderID)
+// AcceptRestaurantOrder accepts an order on behalf of restaurantID. Orders of
+// other restaurants are reported as not found.
+func (s *OrderService) AcceptRestaurantOrder(ctx context.Context, restaurantID, orderID string, expectedVersion int) (*domain.Order, error) {
+	actor := domain.Actor{ID: restaurantID, Role: domain.ActorRestaurant}
+	accept := func(o *domain.Order, actor domain.Actor) error {
+		if o.RestaurantID != restaurantID {
+			return fmt.Errorf("restaurant %s: %w", restaurantID, repository.ErrOrderNotFound)
+		}
+		return o.Accept(actor)
+	}
+
+	return s.advanceOrder(ctx, orderID, expectedVersion, actor, accept, func(o *domain.Order) domain.Event {
+		return domain.NewOrderAcceptedEvent(o)
+	})
+}
+
+// RejectOrder cancels an order on behalf of the restaurant it was placed with.
+// Like AcceptRestaurantOrder, it reports another restaurant's order as not
+// found.
+func (s *OrderService) RejectOrder(ctx context.Context, restaurantID, orderID string, expectedVersion int, reason string) (*domain.Order, error) {
+	actor := domain.Actor{ID: restaurantID, Role: domain.ActorRestaurant}
+	return s.cancelOrder(ctx, orderID, expectedVersion, actor, reason, func(o *domain.Order) error {
+		if o.RestaurantID != restaurantID {
+			return fmt.Errorf("restaurant %s: %w", restaurantID, repository.ErrOrderNotFound)
+		}
+		return nil
+	})
+}
+
+func (s *OrderService) StartPreparingOrder(ctx context.Context, orderID string, expectedVersion int, actor domain.Actor) (*domain.Order, error) {
+	return s.advanceOrder(ctx, orderID, expectedVersion, actor, (*domain.Order).StartPreparing, func(o *domain.Order) domain.Event {
+		return domain.NewOrderPreparingEvent(o)
+	})
+}
+
+func (s *OrderService) MarkOrderReady(ctx context.Context, orderID string, expectedVersion int, actor domain.Actor) (*domain.Order, error) {
+	return s.advanceOrder(ctx, orderID, expectedVersion, actor, (*domain.Order).MarkReady, func(o *domain.Order) domain.Event {
+		return domain.NewOrderReadyEvent(o)
+	})
+}
+
+func (s *OrderService) PickUpOrder(ctx context.Context, orderID string, expectedVersion int, actor domain.Actor) (*domain.Order, error) {
+	return s.advanceOrder(ctx, orderID, expectedVersion, actor, (*domain.Order).PickUp, func(o *domain.Order) domain.Event {
+		return domain.NewOrderPickedUpEvent(o)
+	})
+}
+
+func (s *OrderService) SendOrderOutForDelivery(ctx context.Context, orderID string, expectedVersion int, actor domain.Actor) (*domain.Order, error) {
+	return s.advanceOrder(ctx, orderID, expectedVersion, actor, (*domain.Order).SendOutForDelivery, func(o *domain.Order) domain.Event {
+		return domain.NewOrderOutForDeliveryEvent(o)
+	})
+}
+
+func (s *OrderService) DeliverOrder(ctx context.Context, orderID string, expectedVersion int, actor domain.Actor) (*domain.Order, error) {
+	return s.advanceOrder(ctx, orderID, expectedVersion, actor, (*domain.Order).Deliver, func(o *domain.Order) domain.Event {
+		return domain.NewOrderDeliveredEvent(o)
+	})
+}
+
+// loadForUpdate locks the order for the rest of the transaction and checks it
+// is still at expectedVersion. An expectedVersion of 0 accepts any version.
+func loadForUpdate(ctx context.Context, tx repository.OrderStore, orderID string, expectedVersion int) (*domain.Order, error) {
+	order, err := tx.GetOrderForUpdate(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if expectedVersion != 0 && order.Version != expectedVersion {
+		return nil, &repository.VersionConflictError{OrderID: orderID, Expected: expectedVersion, Actual: order.Version}
+	}
+
+	return order, nil
+}
+
+// advanceOrder applies one fulfilment step to the order on behalf of actor and
+// records the event built for it in the same transaction.
+func (s *OrderService) advanceOrder(ctx context.Context, orderID string, expectedVersion int, actor domain.Actor, step func(*domain.Order, domain.Actor) error, newEvent func(*domain.Order) domain.Event) (*domain.Order, error) {
+	var order *domain.Order
+	err := s.repo.RunInTx(ctx, func(tx repository.OrderStore) error {
+		var err error
+		order, err = loadForUpdate(ctx, tx, orderID, expectedVersion)
+		if err != nil {
+			return err
+		}
+
+		readVersion := order.Version
+		if err := step(order, actor); err != nil {
+			return err
+		}
+
+		return tx.UpdateOrderStatus(ctx, order.ID, readVersion, order.Status, newEvent(order))
+	})
 	if err != nil {
-		s.logger.Error("Failed to get order", map[string]any{
+		s.logger.WithContext(ctx).Error("Failed to advance order", map[string]any{
 			"error":    err,
 			"order_id": orderID,
+			"actor_id": actor.ID,
+			"role":     actor.Role,
 		})
 		return nil, err
 	}
+
+	s.logger.WithContext(ctx).Info("Order advanced", map[string]any{
+		"order_id": order.ID,
+		"status":   order.Status,
+		"actor_id": actor.ID,
+		"role":     actor.Role,
+	})
+
 	return order, nil
 }
 
-func (s *OrderService) ListOrders(ctx context.Context, userID string, limit int) ([]domain.Order, error) {
-	orders, err := s.repo.ListOrders(ctx, userID, limit)
+// ListOrders returns the page of orders matching filter at cursor, or the
+// first page when cursor is nil.
+func (s *OrderService) ListOrders(ctx context.Context, filter repository.OrderFilter, cursor *repository.OrderCursor, limit int) (*repository.OrderPage, error) {
+	page, err := s.repo.ListOrders(ctx, filter, cursor, limit)
 	if err != nil {
-		s.logger.Error("Failed to list orders", map[string]interface{}{
-			"error":   err,
-			"user_id": userID,
+		s.logger.WithContext(ctx).Error("Failed to list orders", map[string]any{
+			"error":         err,
+			"user_id":       filter.UserID,
+			"restaurant_id": filter.RestaurantID,
 		})
 		return nil, err
 	}
-	return orders, nil
+	return page, nil
+}
+
+// OpenOrdersByRestaurant counts the open orders of every restaurant that has any.
+func (s *OrderService) OpenOrdersByRestaurant(ctx context.Context) (map[string]int, error) {
+	return s.repo.CountOpenOrdersByRestaurant(ctx)
 }
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/repository/memstore"
+)
+
+var (
+	restaurant = domain.Actor{ID: "rest-1", Role: domain.ActorRestaurant}
+	courier    = domain.Actor{ID: "courier-1", Role: domain.ActorCourier}
+)
+
+func newTestService(t *testing.T) (*OrderService, *memstore.Store) {
+	t.Helper()
+	store := memstore.New()
+	return NewOrderService(store, logger.New("ERROR")), store
+}
+
+func testItems() []domain.OrderItem {
+	return []domain.OrderItem{
+		{ItemID: "item-1", Name: "Paneer Tikka", Price: domain.NewMoney(24950, "INR"), Quantity: 2},
+	}
+}
+
+// createConfirmedOrder creates an order and confirms it the way the processor
+// would.
+func createConfirmedOrder(t *testing.T, svc *OrderService, store *memstore.Store) *domain.Order {
+	t.Helper()
+	ctx := context.Background()
+
+	order, err := svc.CreateOrder(ctx, "user-1", "rest-1", testItems())
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if err := store.UpdateOrderStatus(ctx, order.ID, order.Version, domain.OrderStatusConfirmed, domain.NewOrderConfirmedEvent(order.ID)); err != nil {
+		t.Fatalf("confirm order: %v", err)
+	}
+
+	confirmed, err := store.GetOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	return confirmed
+}
+
+func TestCreateOrderStoresOrderAndEvent(t *testing.T) {
+	svc, store := newTestService(t)
+	ctx := context.Background()
+
+	order, err := svc.CreateOrder(ctx, "user-1", "rest-1", testItems())
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	if want := domain.NewMoney(49900, "INR"); order.TotalAmount != want {
+		t.Errorf("total = %v, want %v", order.TotalAmount, want)
+	}
+
+	stored, err := store.GetOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != domain.OrderStatusPending || stored.Version != 1 {
+		t.Errorf("stored order is %s at version %d, want PENDING at 1", stored.Status, stored.Version)
+	}
+
+	events, err := store.OrderEvents(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("OrderEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].Event.EventType() != domain.OrderCreatedEventType || events[0].Version != 1 {
+		t.Fatalf("events = %+v, want one OrderCreated at version 1", events)
+	}
+}
+
+func TestCreateOrderRejectsInvalidInput(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		userID       string
+		restaurantID string
+		items        []domain.OrderItem
+	}{
+		{"missing user", "", "rest-1", testItems()},
+		{"missing restaurant", "user-1", "", testItems()},
+		{"no items", "user-1", "rest-1", nil},
+		{"zero quantity", "user-1", "rest-1", []domain.OrderItem{{ItemID: "item-1", Price: domain.NewMoney(100, "INR")}}},
+		{"mixed currencies", "user-1", "rest-1", []domain.OrderItem{
+			{ItemID: "item-1", Price: domain.NewMoney(100, "INR"), Quantity: 1},
+			{ItemID: "item-2", Price: domain.NewMoney(100, "USD"), Quantity: 1},
+		}},
+		{"currency without two decimals", "user-1", "rest-1", []domain.OrderItem{{ItemID: "item-1", Price: domain.NewMoney(100, "JPY"), Quantity: 1}}},
+		{"no currency", "user-1", "rest-1", []domain.OrderItem{{ItemID: "item-1", Price: domain.NewMoney(100, ""), Quantity: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.CreateOrder(ctx, tt.userID, tt.restaurantID, tt.items); err == nil {
+				t.Fatal("CreateOrder succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestCreateOrderOnce(t *testing.T) {
+	svc, store := newTestService(t)
+	ctx := context.Background()
+	key := repository.IdempotencyKey{Key: "key-1", RequestHash: "hash-1"}
+
+	first, replayed, err := svc.CreateOrderOnce(ctx, key, time.Hour, "user-1", "rest-1", testItems())
+	if err != nil || replayed {
+		t.Fatalf("first CreateOrderOnce = replayed %v, err %v", replayed, err)
+	}
+
+	again, replayed, err := svc.CreateOrderOnce(ctx, key, time.Hour, "user-1", "rest-1", testItems())
+	if err != nil || !replayed {
+		t.Fatalf("retry CreateOrderOnce = replayed %v, err %v", replayed, err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("retry returned order %s, want %s", again.ID, first.ID)
+	}
+
+	_, _, err = svc.CreateOrderOnce(ctx, repository.IdempotencyKey{Key: "key-1", RequestHash: "hash-2"}, time.Hour, "user-1", "rest-1", testItems())
+	if !errors.Is(err, repository.ErrIdempotencyKeyReused) {
+		t.Errorf("different request err = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	page, err := store.ListOrders(ctx, repository.OrderFilter{UserID: "user-1"}, nil, 10)
+	if err != nil {
+		t.Fatalf("ListOrders: %v", err)
+	}
+	if len(page.Orders) != 1 {
+		t.Errorf("%d orders stored, want 1", len(page.Orders))
+	}
+}
+
+func TestCreateOrderOnceReleasesKeyOnFailure(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+	key := repository.IdempotencyKey{Key: "key-1", RequestHash: "hash-1"}
+
+	if _, _, err := svc.CreateOrderOnce(ctx, key, time.Hour, "user-1", "rest-1", nil); err == nil {
+		t.Fatal("CreateOrderOnce without items succeeded")
+	}
+
+	if _, replayed, err := svc.CreateOrderOnce(ctx, key, time.Hour, "user-1", "rest-1", testItems()); err != nil || replayed {
+		t.Fatalf("CreateOrderOnce after failure = replayed %v, err %v", replayed, err)
+	}
+}
+
+func TestFulfilmentLifecycle(t *testing.T) {
+	svc, store := newTestService(t)
+	ctx := context.Background()
+	order := createConfirmedOrder(t, svc, store)
+
+	steps := []struct {
+		step  func(ctx context.Context, orderID string, expectedVersion int, actor domain.Actor) (*domain.Order, error)
+		actor domain.Actor
+		want  domain.OrderStatus
+	}{
+		{svc.StartPreparingOrder, restaurant, domain.OrderStatusPreparing},
+		{svc.MarkOrderReady, restaurant, domain.OrderStatusReady},
+		{svc.PickUpOrder, courier, domain.OrderStatusPickedUp},
+		{svc.SendOrderOutForDelivery, courier, domain.OrderStatusOutForDelivery},
+		{svc.DeliverOrder, courier, domain.OrderStatusDelivered},
+	}
+
+	order, err := svc.AcceptRestaurantOrder(ctx, "rest-1", order.ID, order.Version)
+	if err != nil || order.Status != domain.OrderStatusAccepted {
+		t.Fatalf("AcceptRestaurantOrder = %v, want the order ACCEPTED", err)
+	}
+	for _, s := range steps {
+		next, err := s.step(ctx, order.ID, order.Version, s.actor)
+		if err != nil {
+			t.Fatalf("moving to %s: %v", s.want, err)
+		}
+		if next.Status != s.want || next.Version != order.Version+1 {
+			t.Fatalf("order is %s at version %d, want %s at %d", next.Status, next.Version, s.want, order.Version+1)
+		}
+		order = next
+	}
+
+	stored, err := store.GetOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.AcceptedAt == nil || stored.DeliveredAt == nil {
+		t.Error("fulfilment timestamps were not recorded")
+	}
+
+	events, err := store.OrderEvents(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("OrderEvents: %v", err)
+	}
+	replayed, err := domain.ReplayOrder(events)
+	if err != nil {
+		t.Fatalf("ReplayOrder: %v", err)
+	}
+	if replayed.Status != domain.OrderStatusDelivered || replayed.Version != stored.Version {
+		t.Errorf("replayed order is %s at version %d, want DELIVERED at %d", replayed.Status, replayed.Version, stored.Version)
+	}
+}
+
+func TestAdvanceOrderErrors(t *testing.T) {
+	svc, store := newTestService(t)
+	ctx := context.Background()
+	order := createConfirmedOrder(t, svc, store)
+
+	if _, err := svc.DeliverOrder(ctx, order.ID, 0, courier); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("delivering a confirmed order: err = %v, want ErrInvalidTransition", err)
+	}
+
+	if _, err := svc.AcceptRestaurantOrder(ctx, "rest-1", order.ID, order.Version-1); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("accepting a stale version: err = %v, want ErrVersionConflict", err)
+	}
+
+	if _, err := svc.AcceptRestaurantOrder(ctx, "rest-1", "missing", 0); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("accepting a missing order: err = %v, want ErrOrderNotFound", err)
+	}
+
+	if _, err := svc.AcceptRestaurantOrder(ctx, "rest-2", order.ID, 0); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("accepting another restaurant's order: err = %v, want ErrOrderNotFound", err)
+	}
+
+	if _, err := svc.RejectOrder(ctx, "rest-2", order.ID, 0, "too busy"); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Errorf("rejecting another restaurant's order: err = %v, want ErrOrderNotFound", err)
+	}
+
+	stored, err := store.GetOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != domain.OrderStatusConfirmed || stored.Version != order.Version {
+		t.Errorf("failed updates changed the order to %s at version %d", stored.Status, stored.Version)
+	}
+}
+
+func TestAdvanceOrderPermissions(t *testing.T) {
+	svc, store := newTestService(t)
+	ctx := context.Background()
+	order := createConfirmedOrder(t, svc, store)
+
+	order, err := svc.AcceptRestaurantOrder(ctx, "rest-1", order.ID, order.Version)
+	if err != nil {
+		t.Fatalf("AcceptRestaurantOrder: %v", err)
+	}
+
+	for _, actor := range []domain.Actor{
+		{ID: "rest-2", Role: domain.ActorRestaurant},
+		{ID: "user-1", Role: domain.ActorCustomer},
+		courier,
+	} {
+		if _, err := svc.StartPreparingOrder(ctx, order.ID, order.Version, actor); !errors.Is(err, domain.ErrAdvanceNotPermitted) {
+			t.Errorf("preparing as %s %s: err = %v, want ErrAdvanceNotPermitted", actor.Role, actor.ID, err)
+		}
+	}
+
+	if order, err = svc.StartPreparingOrder(ctx, order.ID, order.Version, restaurant); err != nil {
+		t.Fatalf("StartPreparingOrder: %v", err)
+	}
+	if order, err = svc.MarkOrderReady(ctx, order.ID, order.Version, restaurant); err != nil {
+		t.Fatalf("MarkOrderReady: %v", err)
+	}
+	if _, err := svc.PickUpOrder(ctx, order.ID, order.Version, restaurant); !errors.Is(err, domain.ErrAdvanceNotPermitted) {
+		t.Errorf("picking up as the restaurant: err = %v, want ErrAdvanceNotPermitted", err)
+	}
+	if _, err := svc.PickUpOrder(ctx, order.ID, order.Version, domain.Actor{ID: "agent-1", Role: domain.ActorSupport}); err != nil {
+		t.Errorf("picking up as support: %v", err)
+	}
+}
+
+func TestConcurrentUpdatesOfOneVersion(t *testing.T) {
+	svc, store := newTestService(t)
+	ctx := context.Background()
+	order := createConfirmedOrder(t, svc, store)
+
+	const writers = 8
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.AcceptRestaurantOrder(ctx, "rest-1", order.ID, order.Version)
+			switch {
+			case err == nil:
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			case !errors.Is(err, repository.ErrVersionConflict):
+				t.Errorf("AcceptRestaurantOrder: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d writers succeeded, want 1", succeeded)
+	}
+}
+
+func TestCancelOrderPermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance int
+		actor   domain.Actor
+		wantErr error
+	}{
+		{"customer before acceptance", 0, domain.Actor{ID: "user-1", Role: domain.ActorCustomer}, nil},
+		{"other customer", 0, domain.Actor{ID: "user-2", Role: domain.ActorCustomer}, domain.ErrCancelNotPermitted},
+		{"customer after acceptance", 1, domain.Actor{ID: "user-1", Role: domain.ActorCustomer}, domain.ErrCancelNotPermitted},
+		{"restaurant after acceptance", 1, domain.Actor{ID: "rest-1", Role: domain.ActorRestaurant}, nil},
+		{"restaurant while preparing", 2, domain.Actor{ID: "rest-1", Role: domain.ActorRestaurant}, domain.ErrCancelNotPermitted},
+		{"support when ready", 3, domain.Actor{ID: "agent-1", Role: domain.ActorSupport}, nil},
+		{"support after pickup", 4, domain.Actor{ID: "agent-1", Role: domain.ActorSupport}, domain.ErrInvalidTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store := newTestService(t)
+			ctx := context.Background()
+			order := createConfirmedOrder(t, svc, store)
+
+			steps := []func(context.Context, string, int) (*domain.Order, error){
+				func(ctx context.Context, orderID string, version int) (*domain.Order, error) {
+					return svc.AcceptRestaurantOrder(ctx, "rest-1", orderID, version)
+				},
+				func(ctx context.Context, orderID string, version int) (*domain.Order, error) {
+					return svc.StartPreparingOrder(ctx, orderID, version, restaurant)
+				},
+				func(ctx context.Context, orderID string, version int) (*domain.Order, error) {
+					return svc.MarkOrderReady(ctx, orderID, version, restaurant)
+				},
+				func(ctx context.Context, orderID string, version int) (*domain.Order, error) {
+					return svc.PickUpOrder(ctx, orderID, version, courier)
+				},
+			}
+			for _, step := range steps[:tt.advance] {
+				var err error
+				if order, err = step(ctx, order.ID, order.Version); err != nil {
+					t.Fatalf("advancing order: %v", err)
+				}
+			}
+
+			cancelled, err := svc.CancelOrder(ctx, order.ID, order.Version, tt.actor, "changed my mind")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CancelOrder err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CancelOrder: %v", err)
+			}
+			if cancelled.Status != domain.OrderStatusCancelled {
+				t.Errorf("status = %s, want CANCELLED", cancelled.Status)
+			}
+
+			events, err := store.OrderEvents(ctx, order.ID)
+			if err != nil {
+				t.Fatalf("OrderEvents: %v", err)
+			}
+			last, ok := events[len(events)-1].Event.(domain.OrderCancelledEvent)
+			if !ok || last.CancelledBy != tt.actor.ID || last.Reason != "changed my mind" {
+				t.Errorf("last event = %+v, want an OrderCancelledEvent by %s", events[len(events)-1].Event, tt.actor.ID)
+			}
+		})
+	}
+}
+
+func TestListOrdersPaging(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	var created []string
+	for range 5 {
+		order, err := svc.CreateOrder(ctx, "user-1", "rest-1", testItems())
+		if err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+		created = append(created, order.ID)
+	}
+
+	filter := repository.OrderFilter{RestaurantID: "rest-1", OldestFirst: true}
+	first, err := svc.ListOrders(ctx, filter, nil, 2)
+	if err != nil {
+		t.Fatalf("ListOrders: %v", err)
+	}
+	if len(first.Orders) != 2 || first.Next == nil || first.Prev != nil {
+		t.Fatalf("first page has %d orders, next %v, prev %v", len(first.Orders), first.Next, first.Prev)
+	}
+
+	var seen []string
+	page := first
+	for {
+		for _, order := range page.Orders {
+			seen = append(seen, order.ID)
+		}
+		if page.Next == nil {
+			break
+		}
+		if page, err = svc.ListOrders(ctx, filter, page.Next, 2); err != nil {
+			t.Fatalf("ListOrders: %v", err)
+		}
+	}
+
+	if len(seen) != len(created) {
+		t.Fatalf("paged through %d orders, want %d", len(seen), len(created))
+	}
+	for i := range created {
+		if seen[i] != created[i] {
+			t.Fatalf("order %d is %s, want %s", i, seen[i], created[i])
+		}
+	}
+
+	prev, err := svc.ListOrders(ctx, filter, page.Prev, 2)
+	if err != nil {
+		t.Fatalf("ListOrders: %v", err)
+	}
+	if len(prev.Orders) != 2 || prev.Orders[0].ID != created[2] || prev.Orders[1].ID != created[3] {
+		t.Errorf("previous page = %v, want orders %s and %s", prev.Orders, created[2], created[3])
+	}
+}
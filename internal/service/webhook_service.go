@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidWebhook matches the errors of subscriptions that cannot be saved
+// as requested.
+var ErrInvalidWebhook = errors.New("invalid webhook subscription")
+
+// Bytes of randomness in a generated signing secret
+const webhookSecretBytes = 32
+
+// WebhookService manages the webhook subscriptions of restaurants. Every
+// method takes the restaurant acting; subscriptions of other restaurants are
+// reported as not found.
+//
+// Subscription URLs must use https unless AllowHTTP is set, for development.
+type WebhookService struct {
+	AllowHTTP bool
+
+	repo   repository.OrderStore
+	logger *logger.Logger
+}
+
+func NewWebhookService(repo repository.OrderStore, l *logger.Logger) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		logger: l,
+	}
+}
+
+// WebhookUpdate changes the fields of a subscription that are set.
+// Reactivating a subscription clears its failures.
+type WebhookUpdate struct {
+	URL        *string
+	EventTypes []domain.EventType
+	Active     *bool
+}
+
+// CreateSubscription subscribes rawURL to eventTypes, every one of
+// webhook.EventTypes when empty, with a generated signing secret. The secret
+// is only ever returned here.
+func (s *WebhookService) CreateSubscription(ctx context.Context, restaurantID, rawURL string, eventTypes []domain.EventType) (*repository.WebhookSubscription, error) {
+	if restaurantID == "" {
+		return nil, fmt.Errorf("%w: restaurant_id is required", ErrInvalidWebhook)
+	}
+	if err := s.validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	if len(eventTypes) == 0 {
+		eventTypes = webhook.EventTypes
+	}
+	eventTypes, err := validateWebhookEventTypes(eventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generate webhook secret: %w", err)
+	}
+
+	now := time.Now().UTC()
+	sub := &repository.WebhookSubscription{
+		ID:           uuid.New().String(),
+		RestaurantID: restaurantID,
+		URL:          rawURL,
+		Secret:       hex.EncodeToString(secret),
+		EventTypes:   eventTypes,
+		Active:       true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.repo.CreateWebhookSubscription(ctx, sub); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to create webhook subscription", map[string]any{
+			"error":         err,
+			"restaurant_id": restaurantID,
+		})
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("Webhook subscription created", map[string]any{
+		"subscription_id": sub.ID,
+		"restaurant_id":   restaurantID,
+		"event_types":     eventTypes,
+	})
+	return sub, nil
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, restaurantID, id string) (*repository.WebhookSubscription, error) {
+	return s.load(ctx, s.repo, restaurantID, id)
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context, restaurantID string) ([]repository.WebhookSubscription, error) {
+	subs, err := s.repo.ListWebhookSubscriptions(ctx, restaurantID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to list webhook subscriptions", map[string]any{
+			"error":         err,
+			"restaurant_id": restaurantID,
+		})
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (s *WebhookService) UpdateSubscription(ctx context.Context, restaurantID, id string, update WebhookUpdate) (*repository.WebhookSubscription, error) {
+	if update.URL != nil {
+		if err := s.validateURL(*update.URL); err != nil {
+			return nil, err
+		}
+	}
+	var eventTypes []domain.EventType
+	if update.EventTypes != nil {
+		var err error
+		if eventTypes, err = validateWebhookEventTypes(update.EventTypes); err != nil {
+			return nil, err
+		}
+	}
+
+	var sub *repository.WebhookSubscription
+	err := s.repo.RunInTx(ctx, func(tx repository.OrderStore) error {
+		var err error
+		if sub, err = s.load(ctx, tx, restaurantID, id); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		if update.URL != nil {
+			sub.URL = *update.URL
+		}
+		if eventTypes != nil {
+			sub.EventTypes = eventTypes
+		}
+		if update.Active != nil && *update.Active != sub.Active {
+			sub.Active = *update.Active
+			if sub.Active {
+				sub.ConsecutiveFailures, sub.DisabledAt, sub.DisabledReason = 0, nil, ""
+			} else {
+				sub.DisabledAt, sub.DisabledReason = &now, "disabled by the restaurant"
+			}
+		}
+		sub.UpdatedAt = now
+		return tx.UpdateWebhookSubscription(ctx, sub)
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrWebhookNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to update webhook subscription", map[string]any{
+				"error":           err,
+				"subscription_id": id,
+			})
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+// DeleteSubscription removes the subscription along with its delivery log.
+func (s *WebhookService) DeleteSubscription(ctx context.Context, restaurantID, id string) error {
+	err := s.repo.RunInTx(ctx, func(tx repository.OrderStore) error {
+		if _, err := s.load(ctx, tx, restaurantID, id); err != nil {
+			return err
+		}
+		return tx.DeleteWebhookSubscription(ctx, id)
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrWebhookNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to delete webhook subscription", map[string]any{
+				"error":           err,
+				"subscription_id": id,
+			})
+		}
+		return err
+	}
+
+	s.logger.WithContext(ctx).Info("Webhook subscription deleted", map[string]any{
+		"subscription_id": id,
+		"restaurant_id":   restaurantID,
+	})
+	return nil
+}
+
+// ListDeliveries returns the latest limit deliveries of the subscription,
+// newest first, only those in status unless it is empty.
+func (s *WebhookService) ListDeliveries(ctx context.Context, restaurantID, id string, status repository.WebhookDeliveryStatus, limit int) ([]repository.WebhookDelivery, error) {
+	if _, err := s.load(ctx, s.repo, restaurantID, id); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.ListWebhookDeliveries(ctx, id, status, limit)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to list webhook deliveries", map[string]any{
+			"error":           err,
+			"subscription_id": id,
+		})
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// load returns the subscription if it belongs to restaurantID.
+func (s *WebhookService) load(ctx context.Context, store repository.OrderStore, restaurantID, id string) (*repository.WebhookSubscription, error) {
+	// IDs are UUIDs; anything else cannot name a subscription
+	if uuid.Validate(id) != nil {
+		return nil, fmt.Errorf("%w: %s", repository.ErrWebhookNotFound, id)
+	}
+
+	sub, err := store.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.RestaurantID != restaurantID {
+		return nil, fmt.Errorf("restaurant %s: %w: %s", restaurantID, repository.ErrWebhookNotFound, id)
+	}
+	return sub, nil
+}
+
+func (s *WebhookService) validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if u.Scheme != "https" && !s.AllowHTTP {
+		return fmt.Errorf("%w: url must use https", ErrInvalidWebhook)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: url must not hold credentials", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+// validateWebhookEventTypes checks that every type may be subscribed to and
+// returns them without repeats, in the order of webhook.EventTypes.
+func validateWebhookEventTypes(eventTypes []domain.EventType) ([]domain.EventType, error) {
+	if len(eventTypes) == 0 {
+		return nil, fmt.Errorf("%w: event_types must not be empty", ErrInvalidWebhook)
+	}
+	for _, t := range eventTypes {
+		if !slices.Contains(webhook.EventTypes, t) {
+			return nil, fmt.Errorf("%w: cannot subscribe to %q, only to %v", ErrInvalidWebhook, t, webhook.EventTypes)
+		}
+	}
+
+	var out []domain.EventType
+	for _, t := range webhook.EventTypes {
+		if slices.Contains(eventTypes, t) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,165 @@
+// Package tracing sets up OpenTelemetry tracing and carries W3C trace context
+// across the hops the platform makes: HTTP, the outbox and Kafka.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+var Exporters = []string{ExporterNone, ExporterOTLP, ExporterStdout, ExporterFile}
+
+// TraceParentHeader is the W3C Trace Context header, also used as a Kafka
+// header name.
+const TraceParentHeader = "traceparent"
+
+type Config struct {
+	Exporter string `yaml:"exporter"`
+	// OTLP/HTTP endpoint URL such as "http://localhost:4318". When empty the
+	// OTEL_EXPORTER_OTLP_* environment variables apply.
+	Endpoint string `yaml:"endpoint"`
+	// Spans are appended to this file as JSON with the file exporter
+	File string `yaml:"file"`
+	// Fraction of new traces recorded; traces started upstream follow the
+	// caller's decision
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Exporter:    ExporterNone,
+		SampleRatio: 1,
+	}
+}
+
+// propagator reads and writes W3C traceparent and tracestate. It is used
+// directly rather than through the global, so context is carried even when
+// no exporter is configured.
+var propagator = propagation.TraceContext{}
+
+// Setup installs the global tracer provider for service. The returned
+// function flushes buffered spans and must be called before exit. With
+// ExporterNone spans are not recorded but trace context is still carried.
+func Setup(ctx context.Context, cfg Config, service string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	noop := func(context.Context) error { return nil }
+
+	var (
+		exporter sdktrace.SpanExporter
+		file     *os.File
+		err      error
+	)
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return noop, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return noop, fmt.Errorf("open trace file: %w", err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return noop, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return noop, fmt.Errorf("create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceName(service)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return noop, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// Tracer returns the named tracer of the global provider. Tracers obtained
+// before Setup start recording once it has run.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Inject writes the trace context of ctx to carrier.
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	propagator.Inject(ctx, carrier)
+}
+
+// Extract returns ctx carrying the remote trace context found in carrier, if
+// any.
+func Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return propagator.Extract(ctx, carrier)
+}
+
+// TraceParent returns the traceparent of the span in ctx, or "" if there is
+// none, for storing trace context alongside data that crosses a hop later.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get(TraceParentHeader)
+}
+
+// WithTraceParent returns ctx continuing the trace named by traceParent. An
+// empty or malformed traceParent leaves ctx unchanged.
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{TraceParentHeader: traceParent})
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,147 @@
+// Package webhook calls partner endpoints when an order of their restaurant
+// is created, confirmed, failed or cancelled. The webhook consumer of
+// order-processor queues a delivery of each event for every subscription that
+// wants it, and the Worker sends them signed with the subscription's secret.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/kafka"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+)
+
+// EventTypes are the events partners may subscribe to.
+var EventTypes = []domain.EventType{
+	domain.OrderCreatedEventType,
+	domain.OrderConfirmedEventType,
+	domain.OrderFailedEventType,
+	domain.OrderCancelledEventType,
+}
+
+// Headers of every delivery. EventIDHeader is the same on every attempt of a
+// delivery, so partners can drop repeats. SignatureHeader is "sha256=" and
+// the hex HMAC-SHA256, keyed with the subscription's secret, of the
+// TimestampHeader value, a dot and the body.
+const (
+	EventIDHeader   = "X-Webhook-ID"
+	EventTypeHeader = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Payload is the JSON body of a delivery. Data is the event as the platform
+// publishes it, at its latest schema version.
+type Payload struct {
+	ID           string           `json:"id"`
+	Type         domain.EventType `json:"type"`
+	OccurredAt   time.Time        `json:"occurred_at"`
+	OrderID      string           `json:"order_id"`
+	RestaurantID string           `json:"restaurant_id"`
+	Data         json.RawMessage  `json:"data"`
+}
+
+// Sign returns the SignatureHeader value of body sent at timestamp, in Unix
+// seconds.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery received at now, as partners
+// should. Deliveries signed more than tolerance before or after now are
+// rejected, so that a captured request cannot be replayed later.
+func Verify(secret string, header http.Header, body []byte, now time.Time, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed %s", ErrInvalidSignature, TimestampHeader)
+	}
+	if skew := now.Sub(time.Unix(timestamp, 0)); skew > tolerance || skew < -tolerance {
+		return fmt.Errorf("%w: signed %s away from now", ErrInvalidSignature, skew.Round(time.Second))
+	}
+	if !hmac.Equal([]byte(header.Get(SignatureHeader)), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Register adds handlers to d that queue each event of EventTypes for the
+// active subscriptions of the order's restaurant that filter it in. The
+// deliveries commit with the message's offset, so each is queued once.
+func Register(d *kafka.Dispatcher, l *logger.Logger) {
+	enqueue[domain.OrderCreatedEvent](d, l)
+	enqueue[domain.OrderConfirmedEvent](d, l)
+	enqueue[domain.OrderFailedEvent](d, l)
+	enqueue[domain.OrderCancelledEvent](d, l)
+}
+
+func enqueue[E domain.Event](d *kafka.Dispatcher, l *logger.Logger) {
+	kafka.On(d, func(ctx context.Context, delivery *kafka.Delivery, event E) error {
+		order, err := delivery.Tx.GetOrder(ctx, event.AggregateID())
+		if err != nil {
+			return fmt.Errorf("load order %s: %w", event.AggregateID(), err)
+		}
+
+		id := event.ID()
+		if id == "" {
+			// Events published before they had IDs are named by their message
+			msg := delivery.Message
+			id = fmt.Sprintf("%s-%d-%d", msg.Topic, msg.Partition, msg.Offset)
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			return kafka.Permanent(fmt.Errorf("marshal %s event: %w", event.EventType(), err))
+		}
+		payload, err := json.Marshal(Payload{
+			ID:           id,
+			Type:         event.EventType(),
+			OccurredAt:   event.Timestamp().UTC(),
+			OrderID:      order.ID,
+			RestaurantID: order.RestaurantID,
+			Data:         data,
+		})
+		if err != nil {
+			return kafka.Permanent(fmt.Errorf("marshal webhook payload: %w", err))
+		}
+
+		queued, err := delivery.Tx.EnqueueWebhookDeliveries(ctx, repository.WebhookEvent{
+			ID:           id,
+			Type:         event.EventType(),
+			OrderID:      order.ID,
+			RestaurantID: order.RestaurantID,
+			Payload:      payload,
+		})
+		if err != nil {
+			return err
+		}
+
+		if queued > 0 {
+			delivery.AfterCommit(func() {
+				l.WithContext(ctx).Debug("Webhooks queued", map[string]any{
+					"order_id":      order.ID,
+					"restaurant_id": order.RestaurantID,
+					"event_type":    event.EventType(),
+					"deliveries":    queued,
+				})
+			})
+		}
+		return nil
+	})
+}
@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/kafka"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/repository/memstore"
+	segmentio "github.com/segmentio/kafka-go"
+)
+
+func dispatch(t *testing.T, store *memstore.Store, event domain.Event) {
+	t.Helper()
+
+	d := kafka.NewDispatcher()
+	Register(d, logger.New("ERROR"))
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	delivery := &kafka.Delivery{Message: segmentio.Message{Topic: "order-status", Value: payload}, Tx: store}
+	if err := d.Dispatch(context.Background(), delivery, event.EventType()); err != nil {
+		t.Fatalf("Dispatch %s: %v", event.EventType(), err)
+	}
+}
+
+func newOrder(t *testing.T, store *memstore.Store) *domain.Order {
+	t.Helper()
+	order, err := domain.NewOrder("user-1", "rest-1", []domain.OrderItem{
+		{ID: "line-1", ItemID: "item-1", Name: "Thali", Price: domain.NewMoney(45000, "INR"), Quantity: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewOrder: %v", err)
+	}
+	if err := store.CreateOrder(context.Background(), order, domain.NewOrderCreatedEvent(order)); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	return order
+}
+
+func subscribe(t *testing.T, store *memstore.Store, id, restaurantID, url string, active bool, eventTypes ...domain.EventType) {
+	t.Helper()
+	if len(eventTypes) == 0 {
+		eventTypes = EventTypes
+	}
+	now := time.Now().UTC()
+	err := store.CreateWebhookSubscription(context.Background(), &repository.WebhookSubscription{
+		ID:           id,
+		RestaurantID: restaurantID,
+		URL:          url,
+		Secret:       "secret-" + id,
+		EventTypes:   eventTypes,
+		Active:       active,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhookSubscription %s: %v", id, err)
+	}
+}
+
+func deliveries(t *testing.T, store *memstore.Store, subscriptionID string) []repository.WebhookDelivery {
+	t.Helper()
+	list, err := store.ListWebhookDeliveries(context.Background(), subscriptionID, "", 100)
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries %s: %v", subscriptionID, err)
+	}
+	return list
+}
+
+func TestRegisterHandlesEveryEventType(t *testing.T) {
+	d := kafka.NewDispatcher()
+	Register(d, logger.New("ERROR"))
+
+	for _, eventType := range EventTypes {
+		if !d.Handles(eventType) {
+			t.Errorf("no handler for %s", eventType)
+		}
+	}
+}
+
+func TestEventsAreQueuedForMatchingSubscriptions(t *testing.T) {
+	store := memstore.New()
+	order := newOrder(t, store)
+	subscribe(t, store, "all", "rest-1", "https://partner.example/hook", true)
+	subscribe(t, store, "cancellations", "rest-1", "https://partner.example/cancelled", true, domain.OrderCancelledEventType)
+	subscribe(t, store, "inactive", "rest-1", "https://partner.example/off", false)
+	subscribe(t, store, "other", "rest-2", "https://other.example/hook", true)
+
+	event := domain.NewOrderConfirmedEvent(order.ID)
+	dispatch(t, store, event)
+	// A redelivered message must not queue the event twice
+	dispatch(t, store, event)
+
+	queued := deliveries(t, store, "all")
+	if len(queued) != 1 {
+		t.Fatalf("queued %d deliveries for the matching subscription, want 1", len(queued))
+	}
+	for _, id := range []string{"cancellations", "inactive", "other"} {
+		if n := len(deliveries(t, store, id)); n != 0 {
+			t.Errorf("queued %d deliveries for %s, want none", n, id)
+		}
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(queued[0].Payload, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload.ID != event.EventID || payload.Type != domain.OrderConfirmedEventType || payload.OrderID != order.ID || payload.RestaurantID != "rest-1" {
+		t.Errorf("payload = %+v, want the confirmation of %s", payload, order.ID)
+	}
+	var data domain.OrderConfirmedEvent
+	if err := json.Unmarshal(payload.Data, &data); err != nil || data.EventID != event.EventID {
+		t.Errorf("payload data = %s, want the event", payload.Data)
+	}
+}
+
+func TestVerifyChecksSignatureAndAge(t *testing.T) {
+	body := []byte(`{"id":"event-1"}`)
+	now := time.Now()
+
+	header := http.Header{}
+	header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	header.Set(SignatureHeader, Sign("secret", now.Unix(), body))
+
+	if err := Verify("secret", header, body, now, time.Minute); err != nil {
+		t.Errorf("Verify = %v, want the signature accepted", err)
+	}
+	if err := Verify("other", header, body, now, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify with another secret = %v, want ErrInvalidSignature", err)
+	}
+	if err := Verify("secret", header, []byte(`{"id":"event-2"}`), now, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify of another body = %v, want ErrInvalidSignature", err)
+	}
+	if err := Verify("secret", header, body, now.Add(10*time.Minute), time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify of a replayed delivery = %v, want ErrInvalidSignature", err)
+	}
+}
@@ -0,0 +1,263 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/metrics"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+)
+
+// UserAgent is sent with every delivery.
+const UserAgent = "order-management-platform-webhooks/1"
+
+// Response bodies are read up to this size so connections can be reused
+const maxResponseBody = 64 << 10
+
+// Policy controls how deliveries are sent. An attempt fails unless the
+// endpoint answers 2xx within Timeout; failed deliveries are retried after
+// delays doubling from InitialBackoff up to MaxBackoff, MaxAttempts times in
+// all. A subscription whose attempts fail DisableAfter times in a row, over
+// any of its deliveries, is disabled.
+type Policy struct {
+	Timeout        time.Duration `yaml:"timeout"`
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	DisableAfter   int           `yaml:"disable_after"`
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		Timeout:        10 * time.Second,
+		MaxAttempts:    8,
+		InitialBackoff: 30 * time.Second,
+		MaxBackoff:     time.Hour,
+		DisableAfter:   20,
+	}
+}
+
+func (p Policy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxBackoff)
+}
+
+// Worker polls the store for due deliveries and sends them. Workers may run
+// side by side, as each delivery is claimed by one at a time.
+//
+// Metrics defaults to unregistered collectors and Client to a client with
+// the policy's timeout that does not follow redirects or use a proxy and
+// refuses to connect to loopback, private, link-local and other non-public
+// addresses, checked when dialing so a subscription cannot reach them by
+// changing its DNS. Either may be changed before Start. AllowPrivateNetworks
+// lifts the address check of the default client, for development.
+type Worker struct {
+	Metrics              *metrics.Metrics
+	Client               *http.Client
+	AllowPrivateNetworks bool
+
+	repo      repository.OrderStore
+	logger    *logger.Logger
+	interval  time.Duration
+	batchSize int
+	policy    Policy
+}
+
+func NewWorker(repo repository.OrderStore, l *logger.Logger, interval time.Duration, batchSize int, policy Policy) *Worker {
+	w := &Worker{
+		Metrics:   metrics.New(),
+		repo:      repo,
+		logger:    l,
+		interval:  interval,
+		batchSize: batchSize,
+		policy:    policy,
+	}
+
+	dialer := &net.Dialer{Timeout: policy.Timeout, Control: w.checkAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	w.Client = &http.Client{
+		Timeout:   policy.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return w
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which netip does not
+// count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// checkAddress refuses connections to addresses that are not on the public
+// internet. The dialer calls it with the resolved address.
+func (w *Worker) checkAddress(network, address string, _ syscall.RawConn) error {
+	if w.AllowPrivateNetworks {
+		return nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("check webhook address %s: %w", address, err)
+	}
+	ip := addrPort.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("webhook address %s is not public", ip)
+	}
+	return nil
+}
+
+func (w *Worker) Start(ctx context.Context) error {
+	w.logger.Info("Webhook worker started", map[string]any{
+		"interval":   w.interval.String(),
+		"batch_size": w.batchSize,
+	})
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		w.drain(ctx)
+	}
+}
+
+// drain sends due deliveries batch by batch until none are left. The
+// deliveries of a batch are sent in parallel, so a claim lasting two timeouts
+// leaves time to record every attempt.
+func (w *Worker) drain(ctx context.Context) {
+	for {
+		claimed, err := w.repo.ClaimWebhookDeliveries(ctx, w.batchSize, 2*w.policy.Timeout)
+		if err != nil {
+			w.logger.Error("Failed to claim webhook deliveries", map[string]any{
+				"error": err,
+			})
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, p := range claimed {
+			wg.Go(func() { w.attempt(ctx, p) })
+		}
+		wg.Wait()
+
+		if len(claimed) < w.batchSize {
+			return
+		}
+	}
+}
+
+// attempt sends p once and records the outcome. An attempt cut short by
+// shutdown is not recorded; the delivery is claimed again once its claim
+// lapses.
+func (w *Worker) attempt(ctx context.Context, p repository.PendingWebhook) {
+	status, sendErr := w.send(ctx, p)
+	if ctx.Err() != nil {
+		return
+	}
+
+	attempt := repository.WebhookAttempt{
+		DeliveryID:     p.ID,
+		Status:         repository.WebhookDeliveryDelivered,
+		ResponseStatus: status,
+	}
+	outcome := "delivered"
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		if p.Attempts+1 < w.policy.MaxAttempts {
+			attempt.Status = repository.WebhookDeliveryPending
+			attempt.RetryIn = w.policy.backoff(p.Attempts + 1)
+			outcome = "retry"
+		} else {
+			attempt.Status = repository.WebhookDeliveryFailed
+			outcome = "failed"
+		}
+	}
+
+	disabled, err := w.repo.RecordWebhookAttempt(ctx, attempt, w.policy.DisableAfter)
+	if err != nil {
+		w.logger.Error("Failed to record webhook attempt", map[string]any{
+			"error":       err,
+			"delivery_id": p.ID,
+		})
+		return
+	}
+	w.Metrics.WebhookAttempts.WithLabelValues(string(p.EventType), outcome).Inc()
+
+	fields := map[string]any{
+		"delivery_id":     p.ID,
+		"subscription_id": p.SubscriptionID,
+		"event_type":      p.EventType,
+		"order_id":        p.OrderID,
+		"attempt":         p.Attempts + 1,
+	}
+	switch outcome {
+	case "delivered":
+		w.logger.Debug("Webhook delivered", fields)
+	case "retry":
+		fields["error"] = sendErr
+		fields["retry_in"] = attempt.RetryIn.String()
+		w.logger.Warn("Webhook attempt failed", fields)
+	default:
+		fields["error"] = sendErr
+		w.logger.Error("Webhook delivery failed", fields)
+	}
+
+	if disabled {
+		w.logger.Warn("Webhook subscription disabled", map[string]any{
+			"subscription_id": p.SubscriptionID,
+			"failures":        w.policy.DisableAfter,
+		})
+	}
+}
+
+// send POSTs the delivery signed with the subscription's secret and returns
+// the response status, 0 if there was no response.
+func (w *Worker) send(ctx context.Context, p repository.PendingWebhook) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(p.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set(EventIDHeader, p.EventID)
+	req.Header.Set(EventTypeHeader, string(p.EventType))
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(p.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(p.Secret, timestamp, p.Payload))
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
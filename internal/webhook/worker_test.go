@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmehra2102/order-management-platform/internal/domain"
+	"github.com/dmehra2102/order-management-platform/internal/logger"
+	"github.com/dmehra2102/order-management-platform/internal/repository"
+	"github.com/dmehra2102/order-management-platform/internal/repository/memstore"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// endpoint answers with status, counting the requests and checking each is
+// signed with secret.
+type endpoint struct {
+	t       *testing.T
+	secret  string
+	status  atomic.Int32
+	calls   atomic.Int32
+	headers atomic.Value
+}
+
+func newEndpoint(t *testing.T, secret string, status int) (*endpoint, *httptest.Server) {
+	e := &endpoint{t: t, secret: secret}
+	e.status.Store(int32(status))
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	return e, srv
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := Verify(e.secret, r.Header, body, time.Now(), time.Minute); err != nil {
+		e.t.Errorf("delivery not signed: %v", err)
+	}
+	e.headers.Store(r.Header.Clone())
+	e.calls.Add(1)
+	w.WriteHeader(int(e.status.Load()))
+}
+
+// newWorker returns a worker that may reach the test endpoints on loopback.
+func newWorker(store *memstore.Store, policy Policy) *Worker {
+	w := NewWorker(store, logger.New("ERROR"), time.Millisecond, 10, policy)
+	w.AllowPrivateNetworks = true
+	return w
+}
+
+func testPolicy() Policy {
+	return Policy{
+		Timeout:        time.Second,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		DisableAfter:   10,
+	}
+}
+
+// drainUntil runs the worker until done holds, waiting out retry delays.
+func drainUntil(t *testing.T, w *Worker, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("worker did not finish in time")
+		}
+		w.drain(context.Background())
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWorkerDeliversSignedEvent(t *testing.T) {
+	store := memstore.New()
+	order := newOrder(t, store)
+	e, srv := newEndpoint(t, "secret-hook", http.StatusNoContent)
+	subscribe(t, store, "hook", "rest-1", srv.URL, true)
+	event := domain.NewOrderConfirmedEvent(order.ID)
+	dispatch(t, store, event)
+
+	w := newWorker(store, testPolicy())
+	w.drain(context.Background())
+
+	if e.calls.Load() != 1 {
+		t.Fatalf("endpoint called %d times, want 1", e.calls.Load())
+	}
+	header := e.headers.Load().(http.Header)
+	if header.Get(EventIDHeader) != event.EventID || header.Get(EventTypeHeader) != string(domain.OrderConfirmedEventType) {
+		t.Errorf("headers = %v, want the event's ID and type", header)
+	}
+
+	d := deliveries(t, store, "hook")[0]
+	if d.Status != repository.WebhookDeliveryDelivered || d.Attempts != 1 || d.ResponseStatus != http.StatusNoContent {
+		t.Errorf("delivery = %+v, want DELIVERED on the first attempt", d)
+	}
+	if got := testutil.ToFloat64(w.Metrics.WebhookAttempts.WithLabelValues(string(domain.OrderConfirmedEventType), "delivered")); got != 1 {
+		t.Errorf("delivered attempts counted = %v, want 1", got)
+	}
+
+	// Delivered events are not sent again
+	w.drain(context.Background())
+	if e.calls.Load() != 1 {
+		t.Errorf("endpoint called %d times after a second drain, want 1", e.calls.Load())
+	}
+}
+
+func TestWorkerRetriesThenGivesUp(t *testing.T) {
+	store := memstore.New()
+	order := newOrder(t, store)
+	e, srv := newEndpoint(t, "secret-hook", http.StatusInternalServerError)
+	subscribe(t, store, "hook", "rest-1", srv.URL, true)
+	dispatch(t, store, domain.NewOrderConfirmedEvent(order.ID))
+
+	w := newWorker(store, testPolicy())
+	w.drain(context.Background())
+
+	d := deliveries(t, store, "hook")[0]
+	if d.Status != repository.WebhookDeliveryPending || d.Attempts != 1 || d.ResponseStatus != http.StatusInternalServerError || d.LastError == "" {
+		t.Fatalf("delivery after a failed attempt = %+v, want PENDING with the error", d)
+	}
+
+	drainUntil(t, w, func() bool {
+		return deliveries(t, store, "hook")[0].Status != repository.WebhookDeliveryPending
+	})
+
+	d = deliveries(t, store, "hook")[0]
+	if d.Status != repository.WebhookDeliveryFailed || d.Attempts != 3 || e.calls.Load() != 3 {
+		t.Errorf("delivery = %+v after %d calls, want FAILED after 3 attempts", d, e.calls.Load())
+	}
+	if got := testutil.ToFloat64(w.Metrics.WebhookAttempts.WithLabelValues(string(domain.OrderConfirmedEventType), "retry")); got != 2 {
+		t.Errorf("retried attempts counted = %v, want 2", got)
+	}
+}
+
+func TestWorkerDisablesFailingSubscription(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.New()
+	order := newOrder(t, store)
+	e, srv := newEndpoint(t, "secret-hook", http.StatusBadGateway)
+	subscribe(t, store, "hook", "rest-1", srv.URL, true)
+	dispatch(t, store, domain.NewOrderConfirmedEvent(order.ID))
+
+	policy := testPolicy()
+	policy.MaxAttempts, policy.DisableAfter = 5, 2
+	w := newWorker(store, policy)
+
+	drainUntil(t, w, func() bool {
+		sub, err := store.GetWebhookSubscription(ctx, "hook")
+		return err == nil && !sub.Active
+	})
+
+	sub, err := store.GetWebhookSubscription(ctx, "hook")
+	if err != nil {
+		t.Fatalf("GetWebhookSubscription: %v", err)
+	}
+	if sub.ConsecutiveFailures != 2 || sub.DisabledAt == nil || !strings.Contains(sub.DisabledReason, "502") {
+		t.Errorf("subscription = %+v, want it disabled after 2 failures", sub)
+	}
+
+	// Deliveries of a disabled subscription wait without being attempted
+	time.Sleep(5 * time.Millisecond)
+	w.drain(ctx)
+	if d := deliveries(t, store, "hook")[0]; d.Status != repository.WebhookDeliveryPending || d.Attempts != 2 || e.calls.Load() != 2 {
+		t.Fatalf("delivery = %+v after %d calls, want it left PENDING after 2 attempts", d, e.calls.Load())
+	}
+
+	// Once reactivated they are sent again
+	e.status.Store(http.StatusOK)
+	sub.Active, sub.ConsecutiveFailures, sub.DisabledAt, sub.DisabledReason = true, 0, nil, ""
+	if err := store.UpdateWebhookSubscription(ctx, sub); err != nil {
+		t.Fatalf("UpdateWebhookSubscription: %v", err)
+	}
+	w.drain(ctx)
+	if d := deliveries(t, store, "hook")[0]; d.Status != repository.WebhookDeliveryDelivered || d.Attempts != 3 {
+		t.Errorf("delivery = %+v, want DELIVERED on the third attempt", d)
+	}
+}
+
+func TestWorkerRefusesPrivateAddresses(t *testing.T) {
+	store := memstore.New()
+	order := newOrder(t, store)
+	e, srv := newEndpoint(t, "secret-hook", http.StatusNoContent)
+	subscribe(t, store, "hook", "rest-1", srv.URL, true)
+	dispatch(t, store, domain.NewOrderConfirmedEvent(order.ID))
+
+	w := NewWorker(store, logger.New("ERROR"), time.Millisecond, 10, testPolicy())
+	w.drain(context.Background())
+
+	d := deliveries(t, store, "hook")[0]
+	if e.calls.Load() != 0 || d.Status != repository.WebhookDeliveryPending || !strings.Contains(d.LastError, "not public") {
+		t.Errorf("delivery = %+v after %d calls, want the loopback endpoint refused", d, e.calls.Load())
+	}
+
+	for address, public := range map[string]bool{
+		"93.184.216.34:443":      true,
+		"[2606:4700::1111]:443":  true,
+		"10.0.0.8:443":           false,
+		"169.254.169.254:80":     false,
+		"100.64.0.1:443":         false,
+		"[::ffff:127.0.0.1]:443": false,
+		"[fd00::1]:443":          false,
+		"[fe80::1]:443":          false,
+		"0.0.0.0:443":            false,
+	} {
+		if err := w.checkAddress("tcp", address, nil); (err == nil) != public {
+			t.Errorf("checkAddress(%s) = %v, want public %t", address, err, public)
+		}
+	}
+}
+
+func TestPolicyBackoffDoublesUpToMax(t *testing.T) {
+	p := Policy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if got := p.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
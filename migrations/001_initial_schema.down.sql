@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS consumer_offsets;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
@@ -8,7 +8,7 @@ CREATE TABLE IF NOT EXISTS orders (
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     version INT DEFAULT 1
-)
+);
 
 -- Order Items table
 CREATE TABLE IF NOT EXISTS order_items (
@@ -18,8 +18,8 @@ CREATE TABLE IF NOT EXISTS order_items (
     name VARCHAR(255) NOT NULL,
     price DECIMAL(10,2) NOT NULL,
     quantity INT NOT NULL,
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-)
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
 
 -- Event Table
 CREATE TABLE IF NOT EXISTS events (
@@ -29,20 +29,20 @@ CREATE TABLE IF NOT EXISTS events (
     event_data JSONB NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     version INT NOT NULL
-)
+);
 
 -- Consumer offsets (for exactly-once processing)
 CREATE TABLE IF NOT EXISTS consumer_offsets (
     consumer_group VARCHAR(255) NOT NULL,
     partition INT NOT NULL,
-    offset BIGINT NOT NULL,
+    "offset" BIGINT NOT NULL,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (consumer_group, partition)
-)
+);
 
 -- Indexes
 CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
 CREATE INDEX IF NOT EXISTS idx_order_status ON orders(status);
 CREATE INDEX IF NOT EXISTS idx_order_created_at ON orders(created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_events_aggregate_id ON events(aggregate_id);
-CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
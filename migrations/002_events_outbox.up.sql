@@ -0,0 +1,4 @@
+-- Outbox: events are written with their order and relayed to Kafka afterwards
+ALTER TABLE events ADD COLUMN IF NOT EXISTS published_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_events_unpublished ON events(id) WHERE published_at IS NULL;
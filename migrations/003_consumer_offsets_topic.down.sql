@@ -0,0 +1,7 @@
+-- Offsets of topics other than orders cannot be kept under the old key
+DELETE FROM consumer_offsets WHERE topic <> 'orders';
+
+ALTER TABLE consumer_offsets DROP CONSTRAINT IF EXISTS consumer_offsets_pkey;
+ALTER TABLE consumer_offsets ADD PRIMARY KEY (consumer_group, partition);
+
+ALTER TABLE consumer_offsets DROP COLUMN IF EXISTS topic;
@@ -0,0 +1,5 @@
+-- Offsets are tracked per topic so one consumer group can read several topics
+ALTER TABLE consumer_offsets ADD COLUMN IF NOT EXISTS topic VARCHAR(255) NOT NULL DEFAULT 'orders';
+
+ALTER TABLE consumer_offsets DROP CONSTRAINT IF EXISTS consumer_offsets_pkey;
+ALTER TABLE consumer_offsets ADD PRIMARY KEY (consumer_group, topic, partition);
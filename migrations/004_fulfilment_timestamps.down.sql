@@ -0,0 +1,6 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS accepted_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS preparing_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS ready_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS picked_up_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS out_for_delivery_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS delivered_at;
@@ -0,0 +1,7 @@
+-- Timestamps for each step of the fulfilment lifecycle
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS accepted_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS preparing_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS ready_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS picked_up_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS out_for_delivery_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP;
@@ -0,0 +1,2 @@
+-- Currency of order totals and item prices (ISO 4217)
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'INR';
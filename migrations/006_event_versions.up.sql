@@ -0,0 +1,2 @@
+-- Each order version is produced by exactly one event
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_aggregate_version ON events(aggregate_id, version);
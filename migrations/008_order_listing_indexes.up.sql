@@ -0,0 +1,4 @@
+-- Keyset pagination over (created_at, id), overall and per user or restaurant
+CREATE INDEX IF NOT EXISTS idx_orders_created_at_id ON orders(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_user_created_at_id ON orders(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_restaurant_created_at_id ON orders(restaurant_id, created_at DESC, id DESC);
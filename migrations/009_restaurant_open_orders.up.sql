@@ -0,0 +1,2 @@
+-- Restaurant order queue and open order counts
+CREATE INDEX IF NOT EXISTS idx_orders_restaurant_status ON orders(restaurant_id, status);
@@ -0,0 +1,2 @@
+-- Request ID of the API call or message that produced each event
+ALTER TABLE events ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(128);
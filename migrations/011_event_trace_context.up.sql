@@ -0,0 +1,3 @@
+-- W3C traceparent of the span that produced each event, so the relay can
+-- continue the trace when it publishes the event
+ALTER TABLE events ADD COLUMN IF NOT EXISTS trace_parent VARCHAR(64);
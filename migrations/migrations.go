@@ -0,0 +1,8 @@
+// Package migrations holds the SQL schema migrations. Each version has a
+// NNN_name.up.sql file and a NNN_name.down.sql file that reverts it.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS